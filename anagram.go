@@ -0,0 +1,101 @@
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+// maxAnagramLength caps the keywords -anagrams permutes: an 8-letter word
+// already has 40320 orderings.
+const maxAnagramLength = 8
+
+// maxAnagramsPerKeyword is the most pronounceable anagrams kept per keyword.
+const maxAnagramsPerKeyword = 200
+
+// anagrams returns the distinct reorderings of the letters of word that look
+// pronounceable, in alphabetical order and without word itself. Words that
+// are too long or not plain ASCII letters have none.
+func anagrams(word string) []string {
+	word = strings.ToLower(word)
+	if len(word) > maxAnagramLength {
+		return nil
+	}
+	letters := []byte(word)
+	for _, c := range letters {
+		if c < 'a' || c > 'z' {
+			return nil
+		}
+	}
+	slices.Sort(letters)
+
+	var result []string
+	for {
+		if s := string(letters); s != word && looksPronounceable(s) {
+			result = append(result, s)
+			if len(result) == maxAnagramsPerKeyword {
+				break
+			}
+		}
+		if !nextPermutation(letters) {
+			break
+		}
+	}
+	return result
+}
+
+// nextPermutation rearranges letters into the next ordering in
+// lexicographic order, skipping repeats, and reports false after the last.
+func nextPermutation(letters []byte) bool {
+	i := len(letters) - 2
+	for i >= 0 && letters[i] >= letters[i+1] {
+		i--
+	}
+	if i < 0 {
+		return false
+	}
+	j := len(letters) - 1
+	for letters[j] <= letters[i] {
+		j--
+	}
+	letters[i], letters[j] = letters[j], letters[i]
+	slices.Reverse(letters[i+1:])
+	return true
+}
+
+// looksPronounceable rejects names without a vowel or with three consonants
+// in a row.
+func looksPronounceable(s string) bool {
+	run := 0
+	for i := 0; i < len(s); i++ {
+		if isVowel(s[i]) {
+			run = 0
+			continue
+		}
+		if run++; run >= 3 {
+			return false
+		}
+	}
+	return hasVowel(s)
+}
+
+// anagramNames returns the anagram variants of every keyword, skipping
+// names that are already in names.
+func anagramNames(keywords [][]string, names []Candidate) []Candidate {
+	seen := map[string]bool{}
+	for _, name := range names {
+		seen[name.BaseName] = true
+	}
+	var result []Candidate
+	for _, list := range keywords {
+		for _, keyword := range list {
+			for _, anagram := range anagrams(keyword) {
+				if seen[anagram] {
+					continue
+				}
+				seen[anagram] = true
+				result = append(result, Candidate{BaseName: anagram, Keywords: []string{keyword}, Variant: VariantAnagram})
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Attempt is one step taken towards the verdict of a domain: a whois query,
+// a DNS lookup, a cache hit. DomainResult.Attempts lists them in order, so
+// a verdict that looks wrong can be traced to the step that made it.
+type Attempt struct {
+	Method string `json:"method"`
+	Server string `json:"server,omitempty"`
+	// Outcome is the status the step led to, followed by its error if any.
+	Outcome string `json:"outcome"`
+	// Rule is what classified the reply, e.g. a -patterns-file rule.
+	Rule       string `json:"rule,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+	// Pass is the recheck pass the attempt belongs to, from 2; zero for the
+	// first check.
+	Pass int `json:"pass,omitempty"`
+}
+
+func newAttempt(method, server string, start time.Time, status Status, err error) Attempt {
+	return Attempt{
+		Method:     method,
+		Server:     server,
+		Outcome:    attemptOutcome(status, err),
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+}
+
+func attemptOutcome(status Status, err error) string {
+	if err != nil {
+		return fmt.Sprintf("%s: %v", status, err)
+	}
+	return string(status)
+}
+
+// cachedAttempts replaces the attempts of a cached verdict, which belong to
+// the run that checked it, with the cache hit.
+func cachedAttempts(result DomainResult) []Attempt {
+	return []Attempt{{
+		Method:  "cache",
+		Outcome: string(result.Status),
+		Rule:    "verdict of " + displayTime(result.CheckedAt, "2006-01-02 15:04 MST"),
+	}}
+}
+
+// writeAttempts prints the attempts of every result that has some, for
+// -explain.
+func writeAttempts(w io.Writer, results []DomainResult) {
+	fmt.Fprintln(w, "DECISION PATHS:")
+	for _, result := range results {
+		if len(result.Attempts) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "  %s: %s\n", result.Domain, result.Status)
+		for i, a := range result.Attempts {
+			step := a.Method
+			if a.Server != "" {
+				step += " " + a.Server
+			}
+			if a.Pass > 0 {
+				step = fmt.Sprintf("pass %d, %s", a.Pass, step)
+			}
+			line := fmt.Sprintf("    %d. %s %s %s", i+1, step, sym.Arrow, a.Outcome)
+			if a.Rule != "" {
+				line += " (" + a.Rule + ")"
+			}
+			fmt.Fprintf(w, "%s [%s]\n", line, time.Duration(a.DurationMs)*time.Millisecond)
+		}
+	}
+}
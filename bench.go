@@ -0,0 +1,189 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// BenchResult summarizes how a TLD's whois server behaved under a burst of
+// sequential queries.
+type BenchResult struct {
+	TLD     string
+	Server  string
+	Queries int
+	Errors  int
+	P50     time.Duration
+	P95     time.Duration
+	Elapsed time.Duration
+	// ThrottledAt is the 1-based index of the first query that was rate
+	// limited or failed, or 0 when none was.
+	ThrottledAt int
+	// ThrottledAfter is the time from the first query to ThrottledAt.
+	ThrottledAfter time.Duration
+}
+
+// Recommend returns the workers and per-server rate that should stay below
+// the point where the server started refusing queries. A rate of zero means
+// no limit was observed.
+func (b BenchResult) Recommend() (workers int, rate float64) {
+	if b.ThrottledAt > 1 && b.ThrottledAfter > 0 {
+		// Stay at half the rate that triggered throttling.
+		rate = 0.5 * float64(b.ThrottledAt-1) / b.ThrottledAfter.Seconds()
+	} else if b.ThrottledAt == 1 {
+		rate = 0.2
+	}
+	if rate == 0 {
+		return 10, 0
+	}
+	workers = int(math.Ceil(rate * b.P50.Seconds()))
+	return max(workers, 1), math.Round(rate*100) / 100
+}
+
+func runBench(args []string) int {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	tlds := fs.String("tlds", "com", "Comma-separated TLDs to benchmark; presets like @popular are expanded")
+	n := fs.Int("n", 20, "Number of queries per TLD")
+	output := fs.String("o", "", "Write the recommended flags to this file")
+	whoisFlags := addWhoisFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Domain Checker - Benchmark whois servers\n\n")
+		fmt.Fprintf(os.Stderr, "Usage:\n")
+		fmt.Fprintf(os.Stderr, "  %s bench [options]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Sends -n sequential queries per TLD, alternating a registered and a nonexistent\n")
+		fmt.Fprintf(os.Stderr, "test domain, and recommends -workers and -server-rate settings. Results are not\n")
+		fmt.Fprintf(os.Stderr, "written to the cache or history.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExample:\n")
+		fmt.Fprintf(os.Stderr, "  %s bench -tlds=com,io,de -n=20\n\n", os.Args[0])
+	}
+	fs.Parse(args)
+
+	tldList, tldWarnings, err := parseTLDs(tldOptions{List: *tlds})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	for _, warning := range tldWarnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	if *n < 1 {
+		fmt.Fprintf(os.Stderr, "Error: -n must be at least 1\n")
+		return 1
+	}
+	checker, err := whoisFlags.Checker()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if fake, err := whoisFlags.Fake(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	} else if fake != nil {
+		fmt.Fprintf(os.Stderr, "Error: bench measures whois servers and cannot use -backend=fake\n")
+		return 1
+	}
+
+	var results []BenchResult
+	for _, tld := range tldList {
+		fmt.Fprintf(os.Stderr, "Benchmarking .%s (%d queries)...\n", tld, *n)
+		results = append(results, benchTLD(checker, tld, *n))
+	}
+	fmt.Fprintln(os.Stderr)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TLD\tSERVER\tQUERIES\tERRORS\tP50\tP95\tTHROTTLED AT\tWORKERS\tRATE")
+	overallWorkers, overallRate := 0, 0.0
+	for _, r := range results {
+		workers, rate := r.Recommend()
+		throttled := "-"
+		if r.ThrottledAt > 0 {
+			throttled = fmt.Sprintf("#%d after %s", r.ThrottledAt, r.ThrottledAfter.Round(time.Millisecond))
+		}
+		rateText := "no limit"
+		if rate > 0 {
+			rateText = fmt.Sprintf("%g/s", rate)
+		}
+		fmt.Fprintf(tw, ".%s\t%s\t%d\t%d\t%s\t%s\t%s\t%d\t%s\n", r.TLD, r.Server, r.Queries, r.Errors,
+			r.P50.Round(time.Millisecond), r.P95.Round(time.Millisecond), throttled, workers, rateText)
+
+		if overallWorkers == 0 || workers < overallWorkers {
+			overallWorkers = workers
+		}
+		if rate > 0 && (overallRate == 0 || rate < overallRate) {
+			overallRate = rate
+		}
+	}
+	tw.Flush()
+
+	flags := []string{fmt.Sprintf("-workers=%d", overallWorkers)}
+	if overallRate > 0 {
+		flags = append(flags, fmt.Sprintf("-server-rate=%g", overallRate))
+	}
+	fmt.Printf("\nRecommended flags: %s\n", strings.Join(flags, " "))
+
+	if *output != "" {
+		if err := os.WriteFile(*output, []byte(strings.Join(flags, "\n")+"\n"), 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: writing %s: %v\n", *output, err)
+			return 1
+		}
+	}
+	return 0
+}
+
+// benchDomains returns a domain that exists under nearly every TLD (the
+// registry's own nic.<tld>) and one that cannot exist.
+func benchDomains(tld string) (registered, nonexistent string) {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return "nic." + tld, "domain-checker-bench-" + hex.EncodeToString(b) + "." + tld
+}
+
+func benchTLD(checker *WhoisChecker, tld string, n int) BenchResult {
+	registered, nonexistent := benchDomains(tld)
+	result := BenchResult{TLD: tld, Queries: n}
+
+	var latencies []time.Duration
+	start := time.Now()
+	for i := 0; i < n; i++ {
+		domain := registered
+		if i%2 == 1 {
+			domain = nonexistent
+		}
+		checked := checker.Check(domain)
+		if checked.Server != "" {
+			result.Server = checked.Server
+		}
+		latencies = append(latencies, checked.Duration)
+		if checked.Status == StatusError {
+			result.Errors++
+			if result.ThrottledAt == 0 {
+				result.ThrottledAt = i + 1
+				result.ThrottledAfter = time.Since(start)
+			}
+		}
+	}
+	result.Elapsed = time.Since(start)
+
+	slices.Sort(latencies)
+	result.P50 = percentile(latencies, 0.50)
+	result.P95 = percentile(latencies, 0.95)
+	return result
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[max(i, 0)]
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// VerdictCache keeps the last verdict of every checked domain between runs.
+type VerdictCache struct {
+	path string
+
+	mu      sync.Mutex
+	entries map[string]DomainResult
+}
+
+// cacheDir is where cached data is kept, or "" when the user has no cache
+// directory.
+func cacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "domain-checker")
+}
+
+func defaultCachePath() string {
+	dir := cacheDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "verdicts.json")
+}
+
+func OpenCache(path string) (*VerdictCache, error) {
+	cache := &VerdictCache{path: path, entries: map[string]DomainResult{}}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading cache file: %w", err)
+	}
+	if err := json.Unmarshal(data, &cache.entries); err != nil {
+		return nil, fmt.Errorf("parsing cache file %s: %w", path, err)
+	}
+	return cache, nil
+}
+
+func (c *VerdictCache) Lookup(domain string) (DomainResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result, ok := c.entries[domain]
+	return result, ok
+}
+
+// Store remembers a verdict. Errors are not verdicts and are never cached.
+func (c *VerdictCache) Store(result DomainResult) {
+	if !result.Status.IsVerdict() {
+		return
+	}
+	// Only keep what the check itself found, not run-specific annotations.
+	result.Suggested = false
+	result.Candidate = nil
+	result.Score = 0
+	result.Handles = nil
+	result.Links = nil
+	result.Price = nil
+	result.Cached = false
+	result.Attempts = nil
+	result.RawExcerpt = ""
+	result.CheckedAt = result.CheckedAt.UTC()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[result.Domain] = result
+}
+
+func (c *VerdictCache) Save() error {
+	c.mu.Lock()
+	data, err := json.Marshal(c.entries)
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return fmt.Errorf("writing cache file: %w", err)
+	}
+	if err := writeFileAtomic(c.path, data); err != nil {
+		return fmt.Errorf("writing cache file: %w", err)
+	}
+	return nil
+}
+
+// Recording wraps check so that every verdict it returns is cached.
+func (c *VerdictCache) Recording(check checkFunc) checkFunc {
+	return func(domain string) DomainResult {
+		result := check(domain)
+		c.Store(result)
+		return result
+	}
+}
+
+// cacheTTL says how long cached verdicts are reused. Availability is the
+// volatile direction: an available name can be registered within hours,
+// while a taken one rarely frees up, so the two age differently.
+type cacheTTL struct {
+	Available time.Duration
+	// Taken applies to every other verdict as well.
+	Taken time.Duration
+}
+
+func (t cacheTTL) of(status Status) time.Duration {
+	if status == StatusAvailable {
+		return t.Available
+	}
+	return t.Taken
+}
+
+// SkipRecent returns cached verdicts younger than the TTL of their status
+// instead of calling check again.
+func (c *VerdictCache) SkipRecent(check checkFunc, ttl cacheTTL) checkFunc {
+	return func(domain string) DomainResult {
+		if result, ok := c.Lookup(domain); ok && time.Since(result.CheckedAt) < ttl.of(result.Status) {
+			result.Cached = true
+			result.Attempts = cachedAttempts(result)
+			return result
+		}
+		return check(domain)
+	}
+}
+
+// Offline answers from the cache only; domains it has never seen are
+// reported as unchecked.
+func (c *VerdictCache) Offline(domain string) DomainResult {
+	if result, ok := c.Lookup(domain); ok {
+		result.Cached = true
+		result.Attempts = cachedAttempts(result)
+		return result
+	}
+	return DomainResult{Domain: domain, Status: StatusUnchecked}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+func runCache(args []string) int {
+	usage := func() {
+		fmt.Fprintf(os.Stderr, "Domain Checker - Move verdicts in and out of the cache\n\n")
+		fmt.Fprintf(os.Stderr, "Usage:\n")
+		fmt.Fprintf(os.Stderr, "  %s cache import [-cache=verdicts.json] [-history=checks.db] dump.ndjson\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s cache export [-cache=verdicts.json] [-o verdicts.ndjson]\n\n", os.Args[0])
+	}
+
+	if len(args) == 0 {
+		usage()
+		return 1
+	}
+
+	fs := flag.NewFlagSet("cache "+args[0], flag.ExitOnError)
+	cachePath := fs.String("cache", defaultCachePath(), "Verdict cache file, as -cache of a check run")
+	var output, historyPath, patternsFile *string
+	switch args[0] {
+	case "import":
+		historyPath = fs.String("history", "", "Also record the imported verdicts in this SQLite history database")
+		patternsFile = fs.String("patterns-file", "", "YAML file of rules tried on raw whois replies before the built-in patterns, as for a check run")
+	case "export":
+		output = fs.String("o", "", "Write the verdicts to this file instead of stdout")
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown cache command %q\n\n", args[0])
+		usage()
+		return 1
+	}
+	positional := parseInterspersed(fs, args[1:])
+	if *cachePath == "" || (args[0] == "import") != (len(positional) == 1) || len(positional) > 1 {
+		usage()
+		fs.PrintDefaults()
+		return 1
+	}
+
+	cache, err := OpenCache(*cachePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if args[0] == "export" {
+		return exportCache(cache, *output)
+	}
+
+	checker := &WhoisChecker{}
+	if *patternsFile != "" {
+		if checker.Rules, err = readPatternRules(*patternsFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+	var history *HistoryStore
+	if *historyPath != "" {
+		if history, err = OpenHistory(*historyPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		defer history.Close()
+	}
+
+	run := RunInfo{ID: newRunID(), StartedAt: time.Now().UTC()}
+	imported, skipped, err := importVerdicts(positional[0], cache, checker)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := cache.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if history != nil {
+		run.FinishedAt = time.Now().UTC()
+		if err := history.Record(run, imported); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Imported %d verdicts into %s\n", len(imported), *cachePath)
+	if len(skipped) > 0 {
+		fmt.Fprintf(os.Stderr, "Skipped %d records:\n", skipped.total())
+		for _, reason := range skipped.reasons() {
+			fmt.Fprintf(os.Stderr, "  %s (%d): %s\n", reason, len(skipped[reason]), formatRecordLines(skipped[reason]))
+		}
+	}
+	return 0
+}
+
+// skippedRecords holds the line numbers of the records an import skipped,
+// by reason.
+type skippedRecords map[string][]int
+
+func (s skippedRecords) total() int {
+	total := 0
+	for _, lines := range s {
+		total += len(lines)
+	}
+	return total
+}
+
+// reasons returns the reasons, most frequent first.
+func (s skippedRecords) reasons() []string {
+	reasons := make([]string, 0, len(s))
+	for reason := range s {
+		reasons = append(reasons, reason)
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if len(s[reasons[i]]) != len(s[reasons[j]]) {
+			return len(s[reasons[i]]) > len(s[reasons[j]])
+		}
+		return reasons[i] < reasons[j]
+	})
+	return reasons
+}
+
+// formatRecordLines names the first few lines of a reason.
+func formatRecordLines(lines []int) string {
+	const shown = 5
+	parts := make([]string, 0, shown)
+	for _, line := range lines[:min(len(lines), shown)] {
+		parts = append(parts, fmt.Sprint(line))
+	}
+	text := "line " + strings.Join(parts, ", ")
+	if len(lines) > 1 {
+		text = "lines " + strings.Join(parts, ", ")
+	}
+	if n := len(lines) - shown; n > 0 {
+		text += fmt.Sprintf(" and %d more", n)
+	}
+	return text
+}
+
+// importVerdicts reads ndjson records of a domain, its checkedAt time and
+// either a status or the raw whois reply, which is classified as a check
+// would. Each verdict is stored unless the cache holds a newer one for the
+// domain. Records that cannot be used are skipped by reason; only a file
+// that cannot be read fails the import.
+func importVerdicts(path string, cache *VerdictCache, checker *WhoisChecker) ([]DomainResult, skippedRecords, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading import file: %w", err)
+	}
+	defer file.Close()
+
+	var imported []DomainResult
+	skipped := skippedRecords{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := scanner.Bytes()
+		if len(strings.TrimSpace(string(data))) == 0 {
+			continue
+		}
+		result, reason := importRecord(data, checker)
+		if reason != "" {
+			skipped[reason] = append(skipped[reason], line)
+			continue
+		}
+		if cached, ok := cache.Lookup(result.Domain); ok && !cached.CheckedAt.Before(result.CheckedAt) {
+			skipped["the cache has a newer verdict"] = append(skipped["the cache has a newer verdict"], line)
+			continue
+		}
+		cache.Store(result)
+		imported = append(imported, result)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("reading import file: %w", err)
+	}
+	return imported, skipped, nil
+}
+
+// importRecord turns one record into a verdict, or returns why it cannot.
+func importRecord(data []byte, checker *WhoisChecker) (DomainResult, string) {
+	var result DomainResult
+	var raw struct {
+		Raw string `json:"raw"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return result, "not a JSON record with the expected field types"
+	}
+	json.Unmarshal(data, &raw)
+
+	result.Domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(result.Domain)), ".")
+	switch {
+	case result.Domain == "":
+		return result, "no domain"
+	case !strings.Contains(result.Domain, "."):
+		return result, "domain without a TLD"
+	case result.CheckedAt.IsZero():
+		return result, "no checkedAt time"
+	case result.CheckedAt.After(time.Now().Add(time.Hour)):
+		return result, "checkedAt in the future"
+	}
+	result.CheckedAt = result.CheckedAt.UTC()
+
+	switch {
+	case result.Status != "":
+	case raw.Raw != "":
+		reply := decodeWhois(domainTLD(result.Domain), raw.Raw)
+		result.Status, _ = checker.classify(result.Domain, reply)
+		if result.Status == StatusTaken {
+			fields := parseWhoisFields(reply)
+			result.Registrar, result.CreatedAt, result.ExpiresAt, result.EPPStatus = fields.Registrar, fields.CreatedAt, fields.ExpiresAt, fields.EPPStatus
+		}
+		if !result.Status.IsVerdict() {
+			return result, "raw reply not recognised as a verdict"
+		}
+	default:
+		return result, "neither status nor raw reply"
+	}
+	if !result.Status.IsVerdict() {
+		return result, fmt.Sprintf("status %s is not a verdict", result.Status)
+	}
+	if result.Method == "" {
+		result.Method = "import"
+	}
+	return result, ""
+}
+
+// exportCache writes every cached verdict as an ndjson record, in domain
+// order, in the form cache import reads.
+func exportCache(cache *VerdictCache, output string) int {
+	cache.mu.Lock()
+	results := make([]DomainResult, 0, len(cache.entries))
+	for _, result := range cache.entries {
+		results = append(results, result)
+	}
+	cache.mu.Unlock()
+	sort.Slice(results, func(i, j int) bool { return results[i].Domain < results[j].Domain })
+
+	out := os.Stdout
+	if output != "" {
+		var err error
+		if out, err = os.Create(output); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		defer out.Close()
+	}
+	w := bufio.NewWriter(out)
+	enc := json.NewEncoder(w)
+	var err error
+	for _, result := range results {
+		if err = enc.Encode(result); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil && output != "" {
+		err = out.Close()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: exporting cache: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d verdicts\n", len(results))
+	return 0
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+// Variants of a candidate name.
+const (
+	VariantPrefix     = "prefix"
+	VariantSuffix     = "suffix"
+	VariantExplicit   = "explicit"
+	VariantSuggestion = "suggestion"
+	VariantAnagram    = "anagram"
+	VariantRandom     = "random"
+)
+
+// Candidate is a domain to check along with where it came from, so reports
+// can group and label results by the keywords and variant that produced them.
+type Candidate struct {
+	FQDN     string   `json:"-"`
+	BaseName string   `json:"baseName"`
+	TLD      string   `json:"tld"`
+	Keywords []string `json:"keywords,omitempty"`
+	// Lists names the -lists list each keyword came from, in the same
+	// order; "" for unnamed lists. Empty when no list is named.
+	Lists []string `json:"lists,omitempty"`
+	// Variant is empty for a plain keyword combination.
+	Variant string `json:"variant,omitempty"`
+	// Affix is the prefix or suffix of those variants.
+	Affix    string `json:"affix,omitempty"`
+	Priority int    `json:"priority,omitempty"`
+	// Combinations lists every way the name was produced when there is more
+	// than one, e.g. super+fast and superfast.
+	Combinations [][]string `json:"combinations,omitempty"`
+	// Language is the language of the -translations keywords of the name,
+	// e.g. "de", or languages joined with + in keyword order; "" when no
+	// keyword is a translation.
+	Language string `json:"language,omitempty"`
+	// Metadata holds the other cells of the -domains-csv row the domain
+	// came from, with -csv-passthrough.
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// parts are the words joined into the name.
+func (c Candidate) parts() []string {
+	switch c.Variant {
+	case VariantPrefix:
+		return append([]string{c.Affix}, c.Keywords...)
+	case VariantSuffix:
+		return append(append([]string{}, c.Keywords...), c.Affix)
+	}
+	return c.Keywords
+}
+
+// slots describes the keywords by list, e.g. "prefix=get root=cloud".
+func (c Candidate) slots() string {
+	var parts []string
+	for i, label := range c.Lists {
+		if label != "" && i < len(c.Keywords) {
+			parts = append(parts, label+"="+c.Keywords[i])
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// dedupNames keeps the first candidate of each name and records the other
+// combinations that produced it. A single word joined with several
+// separators is the same combination and is not recorded twice.
+func dedupNames(names []Candidate) []Candidate {
+	index := map[string]int{}
+	var unique []Candidate
+	for _, name := range names {
+		i, seen := index[name.BaseName]
+		if !seen {
+			index[name.BaseName] = len(unique)
+			unique = append(unique, name)
+			continue
+		}
+		combinations := unique[i].Combinations
+		if len(combinations) == 0 {
+			combinations = [][]string{unique[i].parts()}
+		}
+		if slices.ContainsFunc(combinations, func(parts []string) bool { return slices.Equal(parts, name.parts()) }) {
+			continue
+		}
+		unique[i].Combinations = append(combinations, name.parts())
+	}
+	return unique
+}
+
+// newCandidate describes a domain given as-is.
+func newCandidate(domain, variant string) Candidate {
+	tld := domainTLD(domain)
+	return Candidate{
+		FQDN:     domain,
+		BaseName: strings.TrimSuffix(domain, "."+tld),
+		TLD:      tld,
+		Variant:  variant,
+	}
+}
+
+func explicitCandidates(domains []string, variant string) []Candidate {
+	candidates := make([]Candidate, len(domains))
+	for i, domain := range domains {
+		candidates[i] = newCandidate(domain, variant)
+	}
+	return candidates
+}
+
+func candidateDomains(candidates []Candidate) []string {
+	domains := make([]string, len(candidates))
+	for i, c := range candidates {
+		domains[i] = c.FQDN
+	}
+	return domains
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestGenerateDomainsOverlappingLists checks that a name several keyword
+// combinations produce is checked once, remembering each combination.
+func TestGenerateDomainsOverlappingLists(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		config Config
+		want   []string
+		// combinations are those recorded for superfast.com.
+		combinations [][]string
+	}{
+		{
+			name:         "one list with a prefix",
+			config:       Config{Keywords: [][]string{{"fast", "superfast"}}, Combinations: 1, Prefixes: []string{"super"}, TLDs: []string{"com"}},
+			want:         []string{"fast.com", "superfast.com", "supersuperfast.com"},
+			combinations: [][]string{{"super", "fast"}, {"superfast"}},
+		},
+		{
+			name:         "several separators",
+			config:       Config{Keywords: [][]string{{"fast", "superfast"}}, Combinations: 1, Prefixes: []string{"super"}, Separators: []string{"", "-"}, TLDs: []string{"com"}},
+			want:         []string{"fast.com", "superfast.com", "super-fast.com", "supersuperfast.com", "super-superfast.com"},
+			combinations: [][]string{{"super", "fast"}, {"superfast"}},
+		},
+		{
+			name:         "two lists",
+			config:       Config{Keywords: [][]string{{"super", "superf"}, {"fast", "ast"}}, TLDs: []string{"com"}},
+			want:         []string{"superfast.com", "superast.com", "superffast.com"},
+			combinations: [][]string{{"super", "fast"}, {"superf", "ast"}},
+		},
+		{
+			name:   "no overlap",
+			config: Config{Keywords: [][]string{{"super", "fast"}}, Combinations: 2, TLDs: []string{"com", "io"}},
+			want:   []string{"superfast.com", "superfast.io"},
+		},
+		{
+			name:   "single word under several separators",
+			config: Config{Keywords: [][]string{{"superfast"}}, Combinations: 1, Separators: []string{"", "-"}, TLDs: []string{"com"}},
+			want:   []string{"superfast.com"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			candidates := generateDomains(tt.config)
+			if got := candidateDomains(candidates); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("domains = %v, want %v", got, tt.want)
+			}
+			for _, c := range candidates {
+				if c.FQDN == "superfast.com" && !reflect.DeepEqual(c.Combinations, tt.combinations) {
+					t.Errorf("combinations of superfast.com = %v, want %v", c.Combinations, tt.combinations)
+				}
+			}
+		})
+	}
+}
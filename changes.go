@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// previousResults returns the results -changes-only compares against: the
+// -baseline results file when given, else the last verdict of every domain
+// in the history.
+func previousResults(history *HistoryStore, baseline string) ([]DomainResult, error) {
+	if baseline != "" {
+		report, err := readReportFile(baseline)
+		if err != nil {
+			return nil, err
+		}
+		return report.Results, nil
+	}
+	return history.LatestVerdicts()
+}
+
+// changedResults returns the changes of results against previous, in the
+// order of results, and how many results did not change. A domain previous
+// lacks counts as changed, with a zero Old.
+func changedResults(results, previous []DomainResult) ([]StatusChange, int) {
+	before := make(map[string]DomainResult, len(previous))
+	for _, result := range previous {
+		before[result.Domain] = result
+	}
+	var changes []StatusChange
+	unchanged := 0
+	for _, result := range results {
+		old, ok := before[result.Domain]
+		if ok && old.Status == result.Status {
+			unchanged++
+			continue
+		}
+		changes = append(changes, StatusChange{Domain: result.Domain, Old: old, New: result})
+	}
+	return changes, unchanged
+}
+
+// formatChangesMessage lists the changes of a -changes-only run for the
+// notifiers.
+func formatChangesMessage(changes []StatusChange, unchanged int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Domain check finished: %d changed, %d unchanged\n", len(changes), unchanged)
+	for i, change := range changes {
+		if i == maxMessageDomains {
+			fmt.Fprintf(&b, "… and %d more\n", len(changes)-i)
+			break
+		}
+		if change.Old.Status == "" {
+			fmt.Fprintf(&b, "• %s: %s (new)\n", change.Domain, change.New.Status)
+		} else {
+			fmt.Fprintf(&b, "• %s\n", change)
+		}
+	}
+	return b.String()
+}
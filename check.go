@@ -0,0 +1,172 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Exit codes of the check subcommand, which scripts branch on.
+const (
+	exitCheckAvailable = 0
+	exitCheckTaken     = 1
+	exitCheckUnknown   = 2
+)
+
+// runCheck checks a single domain and prints its verdict on one line.
+func runCheck(args []string) int {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	method := fs.String("method", "whois", "How the domain is checked: whois, or dns to only look for name servers (quick, but low confidence)")
+	timeout := fs.Duration("timeout", 0, "Maximum time one whois query may take (default 30s)")
+	cachePath := fs.String("cache", defaultCachePath(), "File where the last verdict of every domain is kept (empty disables the cache)")
+	ttlTaken := fs.Duration("cache-ttl-taken", 7*24*time.Hour, "Reuse a cached taken (or reserved) verdict younger than this instead of checking again (0 to always check)")
+	ttlAvailable := fs.Duration("cache-ttl-available", 2*time.Hour, "Reuse a cached available verdict younger than this instead of checking again (0 to always check)")
+	resolverAddr := fs.String("resolver", "", "DNS server (host or host:port) for -method=dns and subdomains (default: the system's)")
+	timezone := addTimezoneFlag(fs)
+	whoisFlags := addWhoisFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Domain Checker - Check a single domain\n\n")
+		fmt.Fprintf(os.Stderr, "Usage:\n")
+		fmt.Fprintf(os.Stderr, "  %s check [options] <domain>\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Prints the verdict on one line and exits with %d when the domain is available,\n", exitCheckAvailable)
+		fmt.Fprintf(os.Stderr, "%d when it is taken or reserved, and %d when no verdict was reached.\n\n", exitCheckTaken, exitCheckUnknown)
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExample:\n")
+		fmt.Fprintf(os.Stderr, "  %s check example.com && echo 'still free'\n\n", os.Args[0])
+	}
+	positional := parseInterspersed(fs, args)
+	if len(positional) != 1 {
+		fs.Usage()
+		return exitCheckUnknown
+	}
+	if err := setDisplayZone(*timezone); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitCheckUnknown
+	}
+
+	domain, _ := cleanInvisible(strings.ToLower(strings.TrimSpace(positional[0])))
+	domain = strings.TrimSuffix(domain, ".")
+	if fixed, duplicated := duplicatedSuffix(domain); duplicated {
+		fmt.Fprintf(os.Stderr, "Warning: treated '%s' as '%s'\n", domain, fixed)
+		domain = fixed
+	}
+	if !strings.Contains(domain, ".") {
+		fmt.Fprintf(os.Stderr, "Error: %q is not a domain; give the full name, e.g. %s.com\n", domain, domain)
+		return exitCheckUnknown
+	}
+	for _, label := range strings.Split(domain, ".") {
+		if problem := labelProblem(label); problem != "" {
+			fmt.Fprintf(os.Stderr, "Error: %s: %s\n", domain, problem)
+			return exitCheckUnknown
+		}
+	}
+
+	if *timeout < 0 {
+		fmt.Fprintf(os.Stderr, "Error: -timeout cannot be negative\n")
+		return exitCheckUnknown
+	}
+	if *ttlAvailable < 0 || *ttlTaken < 0 {
+		fmt.Fprintf(os.Stderr, "Error: -cache-ttl-taken and -cache-ttl-available cannot be negative\n")
+		return exitCheckUnknown
+	}
+	var err error
+	if resolver, err = parseResolver(*resolverAddr); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitCheckUnknown
+	}
+	whoisChecker, err := whoisFlags.Checker()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitCheckUnknown
+	}
+	whoisChecker.Timeout = *timeout
+	fake, err := whoisFlags.Fake()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitCheckUnknown
+	}
+
+	var check checkFunc
+	switch *method {
+	case "whois":
+		check = withSubdomains(whoisChecker.Check)
+	case "dns":
+		if fake != nil {
+			fmt.Fprintf(os.Stderr, "Error: -method=dns looks the domain up in DNS and cannot be used with -backend=fake\n")
+			return exitCheckUnknown
+		}
+		check = withSubdomains(checkDNS)
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown -method %q (use whois or dns)\n", *method)
+		return exitCheckUnknown
+	}
+	if fake != nil {
+		check = fake.Check
+		// As in a full run, made-up verdicts only go to a cache given
+		// explicitly.
+		cacheGiven := false
+		fs.Visit(func(f *flag.Flag) {
+			cacheGiven = cacheGiven || f.Name == "cache"
+		})
+		if !cacheGiven {
+			*cachePath = ""
+		}
+	}
+
+	var cache *VerdictCache
+	if *cachePath != "" {
+		if cache, err = OpenCache(*cachePath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitCheckUnknown
+		}
+		check = cache.SkipRecent(cache.Recording(check), cacheTTL{Available: *ttlAvailable, Taken: *ttlTaken})
+	}
+
+	result := check(domain)
+	if cache != nil && !result.Cached {
+		if err := cache.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+	fmt.Println(checkLine(result))
+
+	switch result.Status {
+	case StatusAvailable, StatusAbsent:
+		return exitCheckAvailable
+	case StatusTaken, StatusReserved, StatusExists:
+		return exitCheckTaken
+	default:
+		return exitCheckUnknown
+	}
+}
+
+// checkLine is the one-line verdict of the check subcommand, e.g.
+// "example.com: taken (whois, high confidence), registrar X, expires 2027-01-01".
+func checkLine(result DomainResult) string {
+	how := result.Method
+	if result.Status.IsVerdict() {
+		confidence := "high"
+		if result.Confidence != "" {
+			confidence = result.Confidence
+		}
+		how += fmt.Sprintf(", %s confidence", confidence)
+	}
+	if result.Cached {
+		how += ", cached " + displayTime(result.CheckedAt, "2006-01-02 15:04")
+	}
+	line := fmt.Sprintf("%s: %s (%s)", result.Domain, result.Status, how)
+	if result.Registrar != "" {
+		line += ", registrar " + result.Registrar
+	}
+	if !result.ExpiresAt.IsZero() {
+		line += ", expires " + result.ExpiresAt.Format("2006-01-02")
+	}
+	if result.Error != nil {
+		line += fmt.Sprintf(": %v", result.Error)
+	}
+	return line
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ExternalClassifier hands whois replies to a user command. The command gets
+// the domain as its only argument and the reply on stdin, and prints
+// available, taken, reserved, unknown or deferred. Runs are bounded by Timeout and by
+// a concurrency limit of their own, independent of -workers.
+type ExternalClassifier struct {
+	Command string
+	Timeout time.Duration
+	slots   chan struct{}
+}
+
+func newExternalClassifier(command string, timeout time.Duration, concurrency int) *ExternalClassifier {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &ExternalClassifier{Command: command, Timeout: timeout, slots: make(chan struct{}, concurrency)}
+}
+
+// Classify returns the verdict of the command, or an error when it failed,
+// timed out or printed anything else; callers then fall back to the built-in
+// patterns.
+func (c *ExternalClassifier) Classify(domain, reply string) (Status, error) {
+	c.slots <- struct{}{}
+	defer func() { <-c.slots }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, c.Command, domain)
+	cmd.Stdin = strings.NewReader(reply)
+	// Children of a killed script may hold its output open; do not wait
+	// for them.
+	cmd.WaitDelay = 100 * time.Millisecond
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("classifier %s: timed out after %s", c.Command, c.Timeout)
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", fmt.Errorf("classifier %s: %w", c.Command, err)
+	}
+
+	switch status := Status(strings.ToLower(strings.TrimSpace(string(out)))); status {
+	case StatusAvailable, StatusTaken, StatusReserved, StatusUnknown, StatusDeferred:
+		return status, nil
+	default:
+		return "", fmt.Errorf("classifier %s: unexpected output %q", c.Command, status)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testClassifier runs testdata/classifier.sh, which needs a POSIX shell.
+func testClassifier(t *testing.T, timeout time.Duration, concurrency int) *ExternalClassifier {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the classifier fixture is a shell script")
+	}
+	script, err := filepath.Abs(filepath.Join("testdata", "classifier.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newExternalClassifier(script, timeout, concurrency)
+}
+
+func TestExternalClassifier(t *testing.T) {
+	classifier := testClassifier(t, time.Second, 2)
+	for _, tt := range []struct {
+		domain, reply string
+		want          Status
+		// err is a part of the error, when the classifier fails.
+		err string
+	}{
+		{"free.com", "No match for \"FREE.COM\".\nNOT FOUND\n", StatusAvailable, ""},
+		{"taken.com", "Domain Name: TAKEN.COM\nRegistrar: Example\n", StatusTaken, ""},
+		{"fail.com", "", "", "registry unreachable"},
+		{"junk.com", "", "", `unexpected output "maybe"`},
+		{"slow.com", "", "", "timed out after 1s"},
+	} {
+		t.Run(tt.domain, func(t *testing.T) {
+			got, err := classifier.Classify(tt.domain, tt.reply)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Fatalf("Classify: %v", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Fatalf("Classify error = %v, want %q", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("Classify = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestExternalClassifierFallback checks that a failing command leaves the
+// verdict to the built-in patterns.
+func TestExternalClassifierFallback(t *testing.T) {
+	checker := &WhoisChecker{Classifier: testClassifier(t, time.Second, 1)}
+	reply := "No match for \"FAIL.COM\".\n>>> Last update of whois database: 2026-01-01T00:00:00Z <<<\n"
+	status, rule := checker.classify("fail.com", reply)
+	if status != StatusAvailable || rule == "-classifier" {
+		t.Errorf("classify = %s by %q, want available by the built-in patterns", status, rule)
+	}
+	status, rule = checker.classify("free.com", "NOT FOUND\n")
+	if status != StatusAvailable || rule != "-classifier" {
+		t.Errorf("classify = %s by %q, want available by -classifier", status, rule)
+	}
+}
+
+// TestExternalClassifierConcurrency checks that no more commands run at once
+// than the classifier's own limit.
+func TestExternalClassifierConcurrency(t *testing.T) {
+	classifier := testClassifier(t, 5*time.Second, 1)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := classifier.Classify("pause.com", "NOT FOUND"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if elapsed := time.Since(start); elapsed < 600*time.Millisecond {
+		t.Errorf("three 0.2s runs with a limit of one took %s", elapsed)
+	}
+}
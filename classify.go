@@ -0,0 +1,368 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+// whoisPatterns are lowercase substrings that identify a registry's reply.
+type whoisPatterns struct {
+	Available []string
+	Taken     []string
+	// Reserved identifies names the registry blocks from registration,
+	// such as registry-reserved words and ICANN name-collision labels.
+	Reserved []string
+	// Deferred identifies replies of a registry that is down for
+	// maintenance or refuses the kind of query, which say nothing about
+	// the domain and are worth asking again later.
+	Deferred []string
+	// Redacted identifies contact data withheld for privacy. Only a
+	// registration has contacts to withhold, so they mean taken even when
+	// the reply lacks the lines Taken looks for.
+	Redacted []string
+}
+
+var genericPatterns = whoisPatterns{
+	Available: []string{
+		"no match",
+		"not found",
+		"no entries found",
+		"no data found",
+		"available for registration",
+		"status: free",
+	},
+	Taken: []string{
+		"domain name:",
+		"registrar:",
+		"creation date:",
+		"expiration date:",
+		"updated date:",
+	},
+	Reserved: []string{
+		"reserved by the registry",
+		"reserved by registry",
+		"registry reserved",
+		"reserved name",
+		"name collision",
+		"this domain name is reserved",
+		"is not available for registration",
+	},
+	Deferred: []string{
+		"system maintenance",
+		"scheduled maintenance",
+		"under maintenance",
+		"maintenance mode",
+		"service temporarily unavailable",
+		"temporarily unavailable",
+		"whois service is not available",
+		"queries are not supported",
+		"query is not supported",
+		"not supported by this server",
+		"exceeded the daily quota",
+		"quota exceeded",
+	},
+	Redacted: []string{
+		"redacted for privacy",
+		"redacted for gdpr",
+		"data redacted",
+		"gdpr masked",
+		"gdpr redacted",
+		"withheld for privacy",
+		"statutory masking enabled",
+		"non-public data",
+	},
+}
+
+// googleRegistryPatterns cover the TLDs run by Charleston Road Registry,
+// which blocks many labels and says so in its whois replies. Only whole
+// phrases are matched: a bare "blocked" also turns up in status notes and
+// disclaimers of registered and free names.
+var googleRegistryPatterns = whoisPatterns{
+	Reserved: []string{
+		"this name is reserved",
+		"reserved by the registry operator",
+		"this name is blocked",
+		"name collision",
+	},
+}
+
+// tldPatterns are tried before the generic patterns. They cover registries
+// that answer partly or wholly in the local language.
+var tldPatterns = map[string]whoisPatterns{
+	"jp": {
+		Available: []string{"no match!!"},
+		Taken:     []string{"[domain name]", "[ドメイン名]", "[登録者名]", "[状態]"},
+		Deferred:  []string{"メンテナンス中", "サービスを停止"},
+	},
+	"kr": {
+		Available: []string{"등록되어 있지 않", "is not registered"},
+		Taken:     []string{"등록인", "도메인이름", "registrant:"},
+		Deferred:  []string{"시스템 점검", "서비스 점검"},
+	},
+	"ru": {
+		Available: []string{"no entries found", "нет данных", "не найден"},
+		Taken:     []string{"domain:", "nserver:", "state:", "домен:"},
+		Deferred:  []string{"технические работы", "временно недоступен"},
+	},
+	"su": {
+		Available: []string{"no entries found"},
+		Taken:     []string{"domain:", "nserver:", "state:"},
+	},
+	"br": {
+		Available: []string{"no match for", "não encontrado"},
+		Taken:     []string{"owner:", "ownerid:", "nic-hdl-br:", "titular:"},
+	},
+	"cn": {
+		Available: []string{"no matching record"},
+		Taken:     []string{"registrant:", "domain status:"},
+	},
+	"tw": {
+		Available: []string{"no found"},
+		Taken:     []string{"registrant:", "註冊"},
+	},
+	"de": {
+		Available: []string{"status: free"},
+		Taken:     []string{"status: connect"},
+	},
+	// EURid and AFNIC withhold the registrant of private persons with
+	// wording of their own.
+	"eu": {
+		Available: []string{"status: available"},
+		Redacted:  []string{"not disclosed!", "visit www.eurid.eu for webbased whois"},
+	},
+	"fr": {
+		Redacted: []string{"ano nymous", "restricted publication"},
+	},
+	"app":   googleRegistryPatterns,
+	"dev":   googleRegistryPatterns,
+	"page":  googleRegistryPatterns,
+	"how":   googleRegistryPatterns,
+	"new":   googleRegistryPatterns,
+	"foo":   googleRegistryPatterns,
+	"day":   googleRegistryPatterns,
+	"zip":   googleRegistryPatterns,
+	"mov":   googleRegistryPatterns,
+	"nexus": googleRegistryPatterns,
+	"soy":   googleRegistryPatterns,
+	"esq":   googleRegistryPatterns,
+	"rsvp":  googleRegistryPatterns,
+	"ing":   googleRegistryPatterns,
+	"meme":  googleRegistryPatterns,
+	"phd":   googleRegistryPatterns,
+	"prof":  googleRegistryPatterns,
+	"boo":   googleRegistryPatterns,
+	"dad":   googleRegistryPatterns,
+	"eat":   googleRegistryPatterns,
+	"fly":   googleRegistryPatterns,
+	"gle":   googleRegistryPatterns,
+}
+
+// classifyWhois decides the status of a domain from the lowercased reply of
+// its registry. Maintenance notices come first, as they may quote the query
+// in a "Domain Name:" line; reserved indicators next since they often
+// contain the wording of an available reply ("not available for
+// registration"). Redaction markers count as taken after the available
+// patterns. Replies none of the patterns recognise are unknown rather than
+// assumed taken.
+func classifyWhois(tld, reply string) Status {
+	if deferredIndicator(tld, reply) != "" {
+		return StatusDeferred
+	}
+	if patterns, ok := tldPatterns[tld]; ok {
+		if containsAny(reply, patterns.Reserved) {
+			return StatusReserved
+		}
+		if containsAny(reply, patterns.Available) {
+			return StatusAvailable
+		}
+		if containsAny(reply, patterns.Taken) || containsAny(reply, patterns.Redacted) {
+			return StatusTaken
+		}
+	}
+	if containsAny(reply, genericPatterns.Reserved) {
+		return StatusReserved
+	}
+	if containsAny(reply, genericPatterns.Available) {
+		return StatusAvailable
+	}
+	if containsAny(reply, genericPatterns.Taken) || redactionMarker(tld, reply) != "" {
+		return StatusTaken
+	}
+	return StatusUnknown
+}
+
+// redactionMarker returns the privacy redaction wording found in the
+// lowercased reply, or "" when nothing was withheld.
+func redactionMarker(tld, reply string) string {
+	for _, marker := range append(tldPatterns[tld].Redacted, genericPatterns.Redacted...) {
+		if strings.Contains(reply, marker) {
+			return marker
+		}
+	}
+	return ""
+}
+
+// deferredIndicator returns the maintenance or refusal wording found in the
+// lowercased reply, or "" when the registry answered the query.
+func deferredIndicator(tld, reply string) string {
+	for _, indicator := range append(tldPatterns[tld].Deferred, genericPatterns.Deferred...) {
+		if strings.Contains(reply, indicator) {
+			return indicator
+		}
+	}
+	return ""
+}
+
+// thinRegistries hold only the domain, its registrar, dates and name
+// servers; the registrar's own whois server has the rest.
+var thinRegistries = map[string]bool{"com": true, "net": true}
+
+// classifyThin reads thin registry replies strictly. Only a "no match" for
+// the exact domain means available, and a reply listing several records,
+// as for names that are also name server hosts, only means taken when one
+// of them is the domain itself. It reports false when the reply has none of
+// these shapes.
+func classifyThin(domain, lower string) (Status, bool) {
+	switch {
+	case strings.Contains(lower, `no match for "`+domain+`"`):
+		return StatusAvailable, true
+	case whoisValue(lower, "domain name") == domain:
+		return StatusTaken, true
+	case strings.Contains(lower, "no match for"), strings.Contains(lower, "to single out one record"):
+		return StatusUnknown, true
+	}
+	return "", false
+}
+
+// whoisValue returns the value of the first "key: value" line with the key.
+func whoisValue(reply, key string) string {
+	for _, line := range strings.Split(reply, "\n") {
+		k, v, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if ok && strings.EqualFold(strings.TrimSpace(k), key) {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+func containsAny(s string, substrings []string) bool {
+	for _, sub := range substrings {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// whoisCharsets are the legacy encodings registries use when they do not
+// answer in UTF-8, most likely first.
+var whoisCharsets = map[string][]encoding.Encoding{
+	"jp": {japanese.EUCJP, japanese.ShiftJIS},
+	"kr": {korean.EUCKR},
+	"ru": {charmap.KOI8R, charmap.Windows1251},
+	"su": {charmap.KOI8R, charmap.Windows1251},
+	"ua": {charmap.KOI8U, charmap.Windows1251},
+	"cn": {simplifiedchinese.GB18030},
+	"tw": {traditionalchinese.Big5},
+	"br": {charmap.ISO8859_1},
+}
+
+// sniffedCharsets are tried for multibyte replies from TLDs without a hint,
+// or when no hinted charset decodes the reply cleanly.
+var sniffedCharsets = []encoding.Encoding{
+	japanese.ShiftJIS,
+	japanese.EUCJP,
+	simplifiedchinese.GB18030,
+}
+
+// decodeWhois converts a raw reply to UTF-8 so patterns match regardless of
+// the charset the registry used.
+func decodeWhois(tld, raw string) string {
+	// JPRS switches to ISO-2022-JP with escape sequences, which are valid
+	// UTF-8 bytes and would otherwise go unnoticed.
+	if strings.Contains(raw, "\x1b$B") || strings.Contains(raw, "\x1b$@") {
+		if decoded, err := japanese.ISO2022JP.NewDecoder().String(raw); err == nil {
+			return decoded
+		}
+	}
+	if utf8.ValidString(raw) {
+		return raw
+	}
+
+	// Single-byte charsets decode any bytes, so the hinted ones are ranked
+	// by how the text reads: Cyrillic decoded with the wrong table has its
+	// case swapped, with capitals inside words.
+	best, bestFlips := "", -1
+	for _, enc := range whoisCharsets[tld] {
+		if decoded, ok := decodeCleanly(enc, raw); ok {
+			if flips := caseFlips(decoded); bestFlips < 0 || flips < bestFlips {
+				best, bestFlips = decoded, flips
+			}
+		}
+	}
+	if bestFlips >= 0 {
+		return best
+	}
+	if looksMultibyte(raw) {
+		for _, enc := range sniffedCharsets {
+			if decoded, ok := decodeCleanly(enc, raw); ok {
+				return decoded
+			}
+		}
+	}
+	// Windows-1252 maps every byte, and single-byte Western text is by far
+	// the most common remainder.
+	if decoded, err := charmap.Windows1252.NewDecoder().String(raw); err == nil {
+		return decoded
+	}
+	return strings.ToValidUTF8(raw, "�")
+}
+
+// looksMultibyte reports whether most non-ASCII bytes come in runs, as in
+// CJK encodings, rather than alone between ASCII letters as in Latin-1.
+func looksMultibyte(raw string) bool {
+	var paired, single int
+	for i := 0; i < len(raw); i++ {
+		if raw[i] < 0x80 {
+			continue
+		}
+		if (i > 0 && raw[i-1] >= 0x80) || (i+1 < len(raw) && raw[i+1] >= 0x80) {
+			paired++
+		} else {
+			single++
+		}
+	}
+	return paired > single
+}
+
+// caseFlips counts the non-ASCII capitals that follow a lowercase letter,
+// which real text has few of.
+func caseFlips(s string) int {
+	flips := 0
+	var prev rune
+	for _, r := range s {
+		if r > 0x7f && unicode.IsUpper(r) && unicode.IsLower(prev) {
+			flips++
+		}
+		prev = r
+	}
+	return flips
+}
+
+// decodeCleanly decodes raw and reports whether every byte sequence was
+// valid in enc.
+func decodeCleanly(enc encoding.Encoding, raw string) (string, bool) {
+	decoded, err := enc.NewDecoder().String(raw)
+	if err != nil || strings.ContainsRune(decoded, utf8.RuneError) {
+		return "", false
+	}
+	return decoded, true
+}
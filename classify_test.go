@@ -0,0 +1,143 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// readWhoisFixture returns a whois reply saved under testdata/whois.
+func readWhoisFixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "whois", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestClassifyFixtures(t *testing.T) {
+	tests := []struct {
+		fixture string
+		domain  string
+		want    Status
+	}{
+		{"app-blocked.txt", "blocked.app", StatusReserved},
+		{"app-taken-transfer-blocked.txt", "example.app", StatusTaken},
+		{"dev-free.txt", "free.dev", StatusAvailable},
+		{"jp-free.txt", "example-free.jp", StatusAvailable},
+		{"jp-taken.txt", "example.jp", StatusTaken},
+		{"jp-maintenance.txt", "example.jp", StatusDeferred},
+		{"kr-free.txt", "example-free.kr", StatusAvailable},
+		{"kr-taken.txt", "example.kr", StatusTaken},
+		{"ru-free.txt", "example-free.ru", StatusAvailable},
+		{"ru-taken.txt", "example.ru", StatusTaken},
+		{"ru-maintenance.txt", "example.ru", StatusDeferred},
+		{"kr-maintenance.txt", "example.kr", StatusDeferred},
+		{"query-not-supported.txt", "example.xyz", StatusDeferred},
+		{"quota-exceeded.txt", "example.xyz", StatusDeferred},
+		{"br-free.txt", "example-free.com.br", StatusAvailable},
+		{"br-taken.txt", "example.com.br", StatusTaken},
+		{"unmatched.txt", "example.xyz", StatusUnknown},
+		// Verisign's thin registry is read strictly: a reply about another
+		// name, or a list of matching name server hosts, is no verdict.
+		{"com-taken.txt", "example.com", StatusTaken},
+		{"com-free.txt", "example-free.com", StatusAvailable},
+		{"com-free.txt", "example.com", StatusUnknown},
+		{"net-no-match-other.txt", "example.net", StatusUnknown},
+		{"com-multimatch.txt", "example.com", StatusTaken},
+		{"com-multimatch-hosts.txt", "example-free.com", StatusUnknown},
+		// Contacts withheld for privacy only exist for registrations, even
+		// when the reply lacks the registrar and dates.
+		{"com-redacted.txt", "example-redacted.com", StatusTaken},
+		{"com-redacted-sparse.txt", "example-redacted.com", StatusTaken},
+		{"eu-redacted.txt", "example-redacted.eu", StatusTaken},
+		{"eu-redacted-sparse.txt", "example-redacted.eu", StatusTaken},
+		{"fr-redacted.txt", "example-redacted.fr", StatusTaken},
+		{"fr-redacted-sparse.txt", "example-redacted.fr", StatusTaken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture+"/"+tt.domain, func(t *testing.T) {
+			checker := &WhoisChecker{}
+			status, rule := checker.classify(tt.domain, readWhoisFixture(t, tt.fixture))
+			if status != tt.want {
+				t.Errorf("classify(%s) = %s (%s), want %s", tt.domain, status, rule, tt.want)
+			}
+		})
+	}
+}
+
+// TestDecodeWhoisFixtures decodes replies saved in the legacy charsets
+// registries use, checks a non-ASCII field came through, and classifies
+// the decoded text.
+func TestDecodeWhoisFixtures(t *testing.T) {
+	tests := []struct {
+		fixture string
+		domain  string
+		// text is a part of the reply outside ASCII.
+		text string
+		want Status
+	}{
+		{"jp-taken-shiftjis.txt", "example.jp", "[登録者名]                      株式会社例", StatusTaken},
+		{"jp-taken-eucjp.txt", "example.jp", "[登録年月日]", StatusTaken},
+		{"jp-taken-iso2022jp.txt", "example.jp", "[ドメイン情報]", StatusTaken},
+		{"ru-taken-koi8r.txt", "example.ru", `ООО "Пример"`, StatusTaken},
+		{"ru-taken-cp1251.txt", "example.ru", `ООО "Пример"`, StatusTaken},
+		{"br-taken-latin1.txt", "example.com.br", "Exemplo Comércio Ltda", StatusTaken},
+		{"jp-taken.txt", "example.jp", "株式会社例", StatusTaken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			raw := readWhoisFixture(t, tt.fixture)
+			decoded := decodeWhois(domainTLD(tt.domain), raw)
+			if !utf8.ValidString(decoded) || !strings.Contains(decoded, tt.text) {
+				t.Fatalf("decoded reply lacks %q:\n%s", tt.text, decoded)
+			}
+			checker := &WhoisChecker{}
+			if status, rule := checker.classify(tt.domain, decoded); status != tt.want {
+				t.Errorf("classify(%s) = %s (%s), want %s", tt.domain, status, rule, tt.want)
+			}
+		})
+	}
+}
+
+// TestSaveWhoisRaw checks that -save-whois writes the decoded reply, and
+// with -save-whois-raw the original bytes next to it.
+func TestSaveWhoisRaw(t *testing.T) {
+	dir := t.TempDir()
+	raw := readWhoisFixture(t, "jp-taken-shiftjis.txt")
+	checker := &WhoisChecker{SaveDir: dir, SaveRaw: true}
+	if err := checker.save("example.jp", decodeWhois("jp", raw), raw); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := os.ReadFile(filepath.Join(dir, "example.jp.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(decoded), "株式会社例") {
+		t.Errorf("saved reply is not decoded:\n%s", decoded)
+	}
+	original, err := os.ReadFile(filepath.Join(dir, "example.jp.raw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(original) != raw {
+		t.Error("saved raw reply differs from the bytes received")
+	}
+}
+
+// TestThinRegistryQuery checks that .com and .net are asked for domain
+// records only, so name server hosts sharing the name do not match.
+func TestThinRegistryQuery(t *testing.T) {
+	servers := defaultWhoisServers()
+	for _, tld := range []string{"com", "net"} {
+		if !thinRegistries[tld] {
+			t.Errorf(".%s is not a thin registry", tld)
+		}
+		if got := servers[tld][0].query("example." + tld); got != "domain example."+tld {
+			t.Errorf(".%s query = %q, want %q", tld, got, "domain example."+tld)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// clipboard copies text with the clipboard tool of a platform. Its
+// functions are those of the os and os/exec packages, replaced in tests.
+type clipboard struct {
+	GOOS     string
+	Getenv   func(string) string
+	LookPath func(string) (string, error)
+	// Run runs a command with stdin as its input.
+	Run func(command []string, stdin string) error
+}
+
+var systemClipboard = clipboard{
+	GOOS:     runtime.GOOS,
+	Getenv:   os.Getenv,
+	LookPath: exec.LookPath,
+	Run: func(command []string, stdin string) error {
+		cmd := exec.Command(command[0], command[1:]...)
+		cmd.Stdin = strings.NewReader(stdin)
+		return cmd.Run()
+	},
+}
+
+// command returns the command that reads stdin into the clipboard.
+func (c clipboard) command() ([]string, error) {
+	switch c.GOOS {
+	case "darwin":
+		return []string{"pbcopy"}, nil
+	case "windows":
+		return []string{"clip"}, nil
+	}
+
+	candidates := [][]string{
+		{"xclip", "-selection", "clipboard"},
+		{"xsel", "--clipboard", "--input"},
+	}
+	if c.Getenv("WAYLAND_DISPLAY") != "" {
+		candidates = append([][]string{{"wl-copy"}}, candidates...)
+	}
+	for _, candidate := range candidates {
+		if _, err := c.LookPath(candidate[0]); err == nil {
+			return candidate, nil
+		}
+	}
+	return nil, errors.New("no clipboard tool found (install wl-copy, xclip or xsel)")
+}
+
+func (c clipboard) Copy(text string) error {
+	command, err := c.command()
+	if err != nil {
+		return err
+	}
+	return c.Run(command, text)
+}
+
+// copyAvailableDomains puts the domains -quiet would print on the clipboard.
+// Failures only warn: the report has been printed already.
+func copyAvailableDomains(report *Report, opts RenderOptions) {
+	var domains []string
+	for _, result := range rankedAvailable(report.Results, opts) {
+		domains = append(domains, result.Domain)
+	}
+	if len(domains) == 0 {
+		fmt.Fprintln(os.Stderr, "Clipboard: no available domains to copy")
+		return
+	}
+	if err := copyToClipboard(strings.Join(domains, "\n") + "\n"); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: -copy: %v\n", err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Clipboard: copied %d available domains\n", len(domains))
+}
+
+func copyToClipboard(text string) error {
+	return systemClipboard.Copy(text)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestClipboardCommand(t *testing.T) {
+	tests := []struct {
+		name      string
+		goos      string
+		wayland   string
+		installed []string
+		want      []string
+		wantErr   bool
+	}{
+		{name: "darwin", goos: "darwin", want: []string{"pbcopy"}},
+		{name: "windows", goos: "windows", want: []string{"clip"}},
+		{name: "wayland", goos: "linux", wayland: "wayland-0", installed: []string{"wl-copy", "xclip"}, want: []string{"wl-copy"}},
+		{name: "wayland without wl-copy", goos: "linux", wayland: "wayland-0", installed: []string{"xsel"}, want: []string{"xsel", "--clipboard", "--input"}},
+		{name: "x11", goos: "linux", installed: []string{"wl-copy", "xclip", "xsel"}, want: []string{"xclip", "-selection", "clipboard"}},
+		{name: "x11 with xsel only", goos: "freebsd", installed: []string{"xsel"}, want: []string{"xsel", "--clipboard", "--input"}},
+		{name: "missing tool", goos: "linux", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ran []string
+			var input string
+			c := clipboard{
+				GOOS: tt.goos,
+				Getenv: func(key string) string {
+					if key == "WAYLAND_DISPLAY" {
+						return tt.wayland
+					}
+					return ""
+				},
+				LookPath: func(file string) (string, error) {
+					if slices.Contains(tt.installed, file) {
+						return "/usr/bin/" + file, nil
+					}
+					return "", exec.ErrNotFound
+				},
+				Run: func(command []string, stdin string) error {
+					ran, input = command, stdin
+					return nil
+				},
+			}
+
+			err := c.Copy("example.com\n")
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "no clipboard tool") {
+					t.Fatalf("Copy: err = %v, want no clipboard tool", err)
+				}
+				if ran != nil {
+					t.Errorf("ran %v without a clipboard tool", ran)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(ran, tt.want) {
+				t.Errorf("ran %v, want %v", ran, tt.want)
+			}
+			if input != "example.com\n" {
+				t.Errorf("stdin = %q", input)
+			}
+		})
+	}
+}
+
+func TestClipboardRunError(t *testing.T) {
+	failed := errors.New("exit status 1")
+	c := clipboard{
+		GOOS: "darwin",
+		Run:  func([]string, string) error { return failed },
+	}
+	if err := c.Copy("x"); !errors.Is(err, failed) {
+		t.Errorf("Copy: err = %v, want %v", err, failed)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"time"
+)
+
+// displayZone is the zone times are shown to people in: the report footer,
+// monitor logs and status, calendar events. Machine outputs (json, csv,
+// history, state files) always carry UTC. -timezone sets it; the default
+// is the local zone, which the TZ variable also sets.
+var displayZone = time.Local
+
+// addTimezoneFlag adds -timezone to a flag set; the value is applied with
+// setDisplayZone once the flags are parsed.
+func addTimezoneFlag(fs *flag.FlagSet) *string {
+	return fs.String("timezone", "", "Zone times are shown in, e.g. 'Europe/Berlin' or 'UTC' (default: the local zone); machine-readable outputs are always UTC")
+}
+
+func setDisplayZone(name string) error {
+	if name == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("-timezone: %w", err)
+	}
+	displayZone = loc
+	return nil
+}
+
+// displayTime formats t in displayZone.
+func displayTime(t time.Time, layout string) string {
+	return t.In(displayZone).Format(layout)
+}
+
+// zonedLogWriter stamps each log line with the time in displayZone, which
+// the log package cannot do for any zone but the local one and UTC.
+type zonedLogWriter struct {
+	w io.Writer
+}
+
+func (z zonedLogWriter) Write(p []byte) (int, error) {
+	line := append([]byte(displayTime(time.Now(), "2006/01/02 15:04:05 ")), p...)
+	if _, err := z.w.Write(line); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
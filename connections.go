@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// debugLog is switched to stderr by -debug.
+var debugLog = log.New(io.Discard, "debug: ", log.LstdFlags)
+
+// Kinds of connections counted against the budget.
+const (
+	connWhois = "whois"
+	connHTTP  = "http"
+	connDNS   = "dns"
+)
+
+// connections bounds the sockets open at once across whois, DNS and HTTP probes,
+// so -workers only sets how many domains are in flight. It is nil, meaning no
+// limit, unless -max-connections is set.
+var connections *connBudget
+
+type connBudget struct {
+	limit int
+	// reserved slots can only be taken by whois, so the other stages cannot
+	// starve the checks themselves.
+	reserved int
+
+	mu      sync.Mutex
+	inUse   map[string]int
+	total   int
+	changed chan struct{}
+}
+
+func newConnBudget(limit int) *connBudget {
+	if limit <= 0 {
+		return nil
+	}
+	reserved := (limit + 3) / 4
+	if reserved >= limit {
+		reserved = limit - 1
+	}
+	return &connBudget{limit: limit, reserved: reserved, inUse: map[string]int{}, changed: make(chan struct{})}
+}
+
+// acquire blocks until a connection of the given kind may be opened and
+// returns the function that gives the slot back. Calling it more than once
+// is harmless.
+func (b *connBudget) acquire(ctx context.Context, kind string) (func(), error) {
+	if b == nil {
+		return func() {}, nil
+	}
+
+	waiting := false
+	for {
+		b.mu.Lock()
+		if b.fits(kind) {
+			b.inUse[kind]++
+			b.total++
+			b.mu.Unlock()
+			var once sync.Once
+			return func() { once.Do(func() { b.release(kind) }) }, nil
+		}
+		if !waiting {
+			debugLog.Printf("%s connection waiting for a slot (%s)", kind, b.usage())
+			waiting = true
+		}
+		changed := b.changed
+		b.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (b *connBudget) fits(kind string) bool {
+	if b.total >= b.limit {
+		return false
+	}
+	if kind == connWhois {
+		return true
+	}
+	return b.total-b.inUse[connWhois] < b.limit-b.reserved
+}
+
+func (b *connBudget) release(kind string) {
+	b.mu.Lock()
+	b.inUse[kind]--
+	b.total--
+	close(b.changed)
+	b.changed = make(chan struct{})
+	b.mu.Unlock()
+}
+
+// usage describes the slots in use; b.mu must be held.
+func (b *connBudget) usage() string {
+	kinds := make([]string, 0, len(b.inUse))
+	for kind, n := range b.inUse {
+		if n > 0 {
+			kinds = append(kinds, fmt.Sprintf("%s %d", kind, n))
+		}
+	}
+	sort.Strings(kinds)
+	return fmt.Sprintf("%d of %d in use: %s", b.total, b.limit, strings.Join(kinds, ", "))
+}
+
+// budgetTransport holds a connection slot from the start of a request until
+// its body is closed.
+type budgetTransport struct {
+	base http.RoundTripper
+}
+
+func (t budgetTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	release, err := connections.acquire(req.Context(), connHTTP)
+	if err != nil {
+		return nil, err
+	}
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
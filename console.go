@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"unicode/utf8"
+)
+
+// console describes what a terminal can display.
+type console struct {
+	// ANSI is true when escape sequences are interpreted rather than printed,
+	// which the progress line and the interactive table rely on.
+	ANSI bool
+	// Unicode is true when symbols such as ✓ render correctly.
+	Unicode bool
+}
+
+// detectConsole reports the capabilities of f, switching on escape sequence
+// processing on Windows consoles that support it. Files and pipes are read by
+// other programs, so they get Unicode but no escape sequences.
+func detectConsole(f *os.File) console {
+	if !isTerminal(f) {
+		return console{Unicode: true}
+	}
+	return consoleCapabilities(os.Getenv("TERM"), enableVirtualTerminal(f), consoleUTF8(f))
+}
+
+// consoleCapabilities combines what the platform reported with TERM; a "dumb"
+// terminal never gets escape sequences.
+func consoleCapabilities(term string, virtualTerminal, utf8 bool) console {
+	return console{
+		ANSI:    virtualTerminal && term != "dumb",
+		Unicode: utf8,
+	}
+}
+
+// symbolSet holds the glyphs used by the console renderers.
+type symbolSet struct {
+	Check    string
+	Cross    string
+	Blocked  string
+	Warning  string
+	Idea     string
+	Arrow    string
+	Dash     string
+	Ellipsis string
+	UpDown   string
+}
+
+var (
+	unicodeSymbols = symbolSet{"✓", "✗", "⊘", "⚠", "💡", "→", "—", "…", "↑/↓"}
+	asciiSymbols   = symbolSet{"+", "x", "-", "!", "*", "->", "-", "...", "j/k"}
+)
+
+// sym is switched to asciiSymbols when stdout cannot display Unicode.
+var sym = unicodeSymbols
+
+// setupConsole picks the symbols for stdout.
+func setupConsole() {
+	if !detectConsole(os.Stdout).Unicode {
+		sym = asciiSymbols
+	}
+}
+
+// ellipsisWidth is the number of characters the ellipsis takes up.
+func ellipsisWidth() int {
+	return utf8.RuneCountInString(sym.Ellipsis)
+}
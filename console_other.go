@@ -0,0 +1,13 @@
+//go:build !windows
+
+package main
+
+import "os"
+
+func enableVirtualTerminal(*os.File) bool {
+	return true
+}
+
+func consoleUTF8(*os.File) bool {
+	return true
+}
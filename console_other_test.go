@@ -0,0 +1,16 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+// TestUnixConsole checks that terminals outside Windows are taken to
+// interpret escape sequences and display UTF-8.
+func TestUnixConsole(t *testing.T) {
+	if !enableVirtualTerminal(os.Stdout) || !consoleUTF8(os.Stdout) {
+		t.Error("a terminal outside Windows is reported without escape sequences or UTF-8")
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"unicode"
+)
+
+func TestConsoleCapabilities(t *testing.T) {
+	for _, tt := range []struct {
+		name            string
+		term            string
+		virtualTerminal bool
+		utf8            bool
+		want            console
+	}{
+		{"modern terminal", "xterm-256color", true, true, console{ANSI: true, Unicode: true}},
+		{"windows terminal", "", true, true, console{ANSI: true, Unicode: true}},
+		{"legacy code page", "", true, false, console{ANSI: true}},
+		{"old windows console", "", false, false, console{}},
+		{"old console with utf-8", "", false, true, console{Unicode: true}},
+		{"dumb terminal", "dumb", true, true, console{Unicode: true}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := consoleCapabilities(tt.term, tt.virtualTerminal, tt.utf8); got != tt.want {
+				t.Errorf("consoleCapabilities = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDetectConsoleFile checks that output redirected to a file keeps its
+// Unicode symbols but gets no escape sequences, on every platform.
+func TestDetectConsoleFile(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if got, want := detectConsole(f), (console{Unicode: true}); got != want {
+		t.Errorf("detectConsole(file) = %+v, want %+v", got, want)
+	}
+}
+
+func TestASCIISymbols(t *testing.T) {
+	v := reflect.ValueOf(asciiSymbols)
+	for i := 0; i < v.NumField(); i++ {
+		for _, r := range v.Field(i).String() {
+			if r > unicode.MaxASCII {
+				t.Errorf("asciiSymbols.%s = %q is not ASCII", v.Type().Field(i).Name, v.Field(i).String())
+			}
+		}
+	}
+}
@@ -0,0 +1,32 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+const cpUTF8 = 65001
+
+// enableVirtualTerminal turns on escape sequence processing, which Windows 10
+// and later consoles support but leave off for programs that do not ask.
+func enableVirtualTerminal(f *os.File) bool {
+	handle := windows.Handle(f.Fd())
+	var mode uint32
+	if err := windows.GetConsoleMode(handle, &mode); err != nil {
+		return false
+	}
+	if mode&windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING != 0 {
+		return true
+	}
+	return windows.SetConsoleMode(handle, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING) == nil
+}
+
+// consoleUTF8 reports whether the console code page is UTF-8; legacy code
+// pages such as 437 or 1252 garble anything outside ASCII.
+func consoleUTF8(*os.File) bool {
+	cp, err := windows.GetConsoleOutputCP()
+	return err == nil && cp == cpUTF8
+}
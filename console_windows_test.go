@@ -0,0 +1,22 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestEnableVirtualTerminalFile checks that a file, which has no console
+// mode, is not reported as taking escape sequences.
+func TestEnableVirtualTerminalFile(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if enableVirtualTerminal(f) {
+		t.Error("enableVirtualTerminal(file) = true")
+	}
+}
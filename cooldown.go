@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// A server that rate limits this many queries in a row, none answered in
+// between, is taken to have banned the client for the day, as some
+// registries do: it gets no queries for cooldownPeriod, in this run and
+// the following ones.
+const (
+	cooldownStrikes = 5
+	cooldownPeriod  = 24 * time.Hour
+)
+
+// serverCooldown is a server left alone until a time.
+type serverCooldown struct {
+	Until  time.Time `json:"until"`
+	Reason string    `json:"reason"`
+}
+
+// serverCooldowns remembers the servers in cooldown across runs, in a file
+// of the cache directory.
+type serverCooldowns struct {
+	path string
+
+	mu      sync.Mutex
+	servers map[string]serverCooldown
+	// strikes counts the rate-limited replies of each server since its last
+	// answer.
+	strikes map[string]int
+}
+
+func cooldownsPath() string {
+	dir := cacheDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "cooldowns.json")
+}
+
+// loadCooldowns reads the cooldowns still running from path; an empty path
+// keeps them in memory only.
+func loadCooldowns(path string) (*serverCooldowns, error) {
+	c := &serverCooldowns{path: path, servers: map[string]serverCooldown{}, strikes: map[string]int{}}
+	if path == "" {
+		return c, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading cooldowns: %w", err)
+	}
+	if err := json.Unmarshal(data, &c.servers); err != nil {
+		return nil, fmt.Errorf("parsing cooldowns %s: %w", path, err)
+	}
+	now := time.Now()
+	for host, cooldown := range c.servers {
+		if !cooldown.Until.After(now) {
+			delete(c.servers, host)
+		}
+	}
+	return c, nil
+}
+
+// active returns the cooldown host is in at t.
+func (c *serverCooldowns) active(host string, t time.Time) (serverCooldown, bool) {
+	if c == nil {
+		return serverCooldown{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cooldown, ok := c.servers[host]
+	return cooldown, ok && cooldown.Until.After(t)
+}
+
+// list returns the cooldowns running at t, by host.
+func (c *serverCooldowns) list(t time.Time) []string {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var hosts []string
+	for host, cooldown := range c.servers {
+		if cooldown.Until.After(t) {
+			hosts = append(hosts, host)
+		}
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// observe counts a rate-limited reply of host as a strike and any other
+// outcome as an answer. It returns the cooldown the strike started, and
+// saves it so the next runs skip the server too.
+func (c *serverCooldowns) observe(host string, err error) (serverCooldown, bool, error) {
+	if c == nil {
+		return serverCooldown{}, false, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if categorizeError(err) != ErrorRateLimited {
+		delete(c.strikes, host)
+		return serverCooldown{}, false, nil
+	}
+	c.strikes[host]++
+	if c.strikes[host] < cooldownStrikes {
+		return serverCooldown{}, false, nil
+	}
+	delete(c.strikes, host)
+	cooldown := serverCooldown{
+		Until:  time.Now().Add(cooldownPeriod).UTC(),
+		Reason: fmt.Sprintf("%d rate-limited replies in a row", cooldownStrikes),
+	}
+	c.servers[host] = cooldown
+	return cooldown, true, c.save()
+}
+
+func (c *serverCooldowns) save() error {
+	if c.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(c.servers, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return fmt.Errorf("writing cooldowns: %w", err)
+	}
+	if err := writeFileAtomic(c.path, data); err != nil {
+		return fmt.Errorf("writing cooldowns: %w", err)
+	}
+	return nil
+}
+
+// cooldownError defers a query to a server in cooldown without sending it.
+type cooldownError struct {
+	Host     string
+	Cooldown serverCooldown
+}
+
+func (e *cooldownError) Error() string {
+	return fmt.Sprintf("%v: cooldown of %s until %s, after %s",
+		errDeferred, e.Host, displayTime(e.Cooldown.Until, "2006-01-02 15:04"), e.Cooldown.Reason)
+}
+
+func (e *cooldownError) Unwrap() error {
+	return errDeferred
+}
+
+// cooldown returns the error deferring a query to host at t, or nil when
+// host may be queried.
+func (c *WhoisChecker) cooldown(host string, t time.Time) *cooldownError {
+	if c.IgnoreCooldowns {
+		return nil
+	}
+	if cooldown, ok := c.Cooldowns.active(host, t); ok {
+		return &cooldownError{Host: host, Cooldown: cooldown}
+	}
+	return nil
+}
+
+// writeCooldowns warns about the servers in cooldown before a run, whose
+// domains it defers.
+func writeCooldowns(w io.Writer, c *WhoisChecker) {
+	now := time.Now()
+	for _, host := range c.Cooldowns.list(now) {
+		cooldown, _ := c.Cooldowns.active(host, now)
+		until := displayTime(cooldown.Until, "2006-01-02 15:04")
+		if c.IgnoreCooldowns {
+			fmt.Fprintf(w, "%s COOLDOWN of %s until %s (%s) ignored with -ignore-cooldowns\n", sym.Warning, host, until, cooldown.Reason)
+			continue
+		}
+		fmt.Fprintf(w, "%s COOLDOWN: %s gets no queries until %s (%s); its domains are deferred. -ignore-cooldowns queries it anyway\n",
+			sym.Warning, host, until, cooldown.Reason)
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/net/publicsuffix"
+)
+
+// ConfidenceLow marks verdicts drawn from indirect evidence.
+const ConfidenceLow = "low"
+
+// withDNSFallback answers from DNS when whois has no server for the TLD of a
+// domain. Registered domains nearly always have name servers, so NS records
+// mean taken; a name that does not exist at all is probably available. Both
+// verdicts are marked low confidence.
+func withDNSFallback(check checkFunc) checkFunc {
+	return func(domain string) DomainResult {
+		result := check(domain)
+		if result.Status != StatusError || categorizeError(result.Error) != ErrorNoServer {
+			return result
+		}
+		fallback := checkDNS(domain)
+		fallback.Attempts = append(result.Attempts, fallback.Attempts...)
+		return fallback
+	}
+}
+
+// isSubdomain reports whether domain has labels left of its registrable
+// part according to the public suffix list, like api.example.com or
+// shop.example.co.uk.
+func isSubdomain(domain string) bool {
+	registrable, err := publicsuffix.EffectiveTLDPlusOne(domain)
+	return err == nil && registrable != domain
+}
+
+// withSubdomains checks subdomains in DNS rather than over whois, which only
+// knows registrable domains. They are reported as exists or absent.
+func withSubdomains(check checkFunc) checkFunc {
+	return func(domain string) DomainResult {
+		if !isSubdomain(domain) {
+			return check(domain)
+		}
+		return checkSubdomain(domain)
+	}
+}
+
+func checkSubdomain(domain string) DomainResult {
+	result := DomainResult{Domain: domain, Method: "dns", CheckedAt: time.Now()}
+
+	release, err := connections.acquire(context.Background(), connDNS)
+	if err != nil {
+		result.Status = StatusError
+		result.Error = err
+		return result
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	exists, err := resolver.Exists(ctx, domain)
+	release()
+	result.Duration = time.Since(result.CheckedAt)
+
+	switch {
+	case err != nil:
+		result.Status = StatusError
+		result.Error = err
+	case exists:
+		result.Status = StatusExists
+	default:
+		result.Status = StatusAbsent
+	}
+	result.Attempts = []Attempt{newAttempt("dns", resolver.describe(), result.CheckedAt, result.Status, result.Error)}
+	return result
+}
+
+func checkDNS(domain string) DomainResult {
+	result := DomainResult{Domain: domain, Method: "dns-fallback", CheckedAt: time.Now(), Confidence: ConfidenceLow}
+	delegated, err := hasNameServers(domain)
+	result.Duration = time.Since(result.CheckedAt)
+	switch {
+	case err != nil:
+		result.Status = StatusError
+		result.Error = err
+	case delegated:
+		result.Status = StatusTaken
+	default:
+		result.Status = StatusAvailable
+	}
+	attempt := newAttempt("dns-fallback", resolver.describe(), result.CheckedAt, result.Status, result.Error)
+	attempt.Rule = "name servers mean taken, none available"
+	result.Attempts = []Attempt{attempt}
+	return result
+}
+
+// withDNSPrecheck looks for name servers before spending a whois query:
+// a delegated domain is registered, so only the others go on to check.
+func withDNSPrecheck(check checkFunc) checkFunc {
+	return func(domain string) DomainResult {
+		start := time.Now()
+		delegated, err := hasNameServers(domain)
+		if err == nil && delegated {
+			attempt := newAttempt("dns-precheck", resolver.describe(), start, StatusTaken, nil)
+			attempt.Rule = "delegated"
+			return DomainResult{Domain: domain, Status: StatusTaken, Method: "dns", CheckedAt: start, Duration: time.Since(start), Attempts: []Attempt{attempt}}
+		}
+		attempt := newAttempt("dns-precheck", resolver.describe(), start, "", err)
+		if err == nil {
+			attempt.Outcome = "not delegated"
+		} else {
+			attempt.Outcome = fmt.Sprintf("failed: %v", err)
+		}
+		result := check(domain)
+		result.Attempts = append([]Attempt{attempt}, result.Attempts...)
+		return result
+	}
+}
+
+// hasNameServers reports whether domain is delegated. A name that does not
+// exist is not an error.
+func hasNameServers(domain string) (bool, error) {
+	release, err := connections.acquire(context.Background(), connDNS)
+	if err != nil {
+		return false, err
+	}
+	defer release()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ns, err := resolver.LookupNS(ctx, domain)
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return false, nil
+	}
+	return len(ns) > 0, err
+}
+
+// resolver answers every DNS lookup of a run; -resolver sets its server.
+var resolver = &dnsResolver{}
+
+// dnsResolver makes every lookup fully qualified, so search domains of the
+// system configuration are never appended to a name and cannot turn an
+// unregistered name into an existing one. Verdicts come from DNS only, never
+// from the hosts file.
+type dnsResolver struct {
+	// Server is the host:port queried; empty uses the system's.
+	Server string
+}
+
+// parseResolver reads -resolver, a host with an optional port.
+func parseResolver(value string) (*dnsResolver, error) {
+	if value == "" {
+		return &dnsResolver{}, nil
+	}
+	host, port, err := net.SplitHostPort(value)
+	if err != nil {
+		host, port = strings.Trim(value, "[]"), "53"
+	}
+	if host == "" {
+		return nil, fmt.Errorf("invalid -resolver %q (use host or host:port)", value)
+	}
+	return &dnsResolver{Server: net.JoinHostPort(host, port)}, nil
+}
+
+// fqdn adds the trailing dot that makes a name absolute.
+func fqdn(name string) string {
+	return strings.TrimSuffix(name, ".") + "."
+}
+
+// LookupNS returns the name servers of domain. NS lookups never consult the
+// hosts file.
+func (r *dnsResolver) LookupNS(ctx context.Context, domain string) ([]*net.NS, error) {
+	name := fqdn(domain)
+	ns, err := r.net().LookupNS(ctx, name)
+	debugLog.Printf("dns: NS %s via %s: %d records, err=%v", name, r.describe(), len(ns), err)
+	return ns, err
+}
+
+func (r *dnsResolver) net() *net.Resolver {
+	if r.Server == "" {
+		return net.DefaultResolver
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, r.Server)
+		},
+	}
+}
+
+func (r *dnsResolver) describe() string {
+	if r.Server == "" {
+		return "system resolver"
+	}
+	return r.Server
+}
+
+// Exists reports whether the name exists in DNS, with or without address
+// records. Host lookups of the standard library would answer from the hosts
+// file first, so the query is sent directly to the server.
+func (r *dnsResolver) Exists(ctx context.Context, domain string) (bool, error) {
+	name := fqdn(domain)
+	server, err := r.server()
+	if err != nil {
+		// Without a known server, as on Windows, only the system lookup
+		// is left.
+		debugLog.Printf("dns: %v; looking up %s through the system, hosts file included", err, name)
+		_, err := net.DefaultResolver.LookupHost(ctx, name)
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return false, nil
+		}
+		return err == nil, err
+	}
+	rcode, err := queryRCode(ctx, server, name)
+	debugLog.Printf("dns: A %s via %s: %v, err=%v", name, server, rcode, err)
+	switch {
+	case err != nil:
+		return false, &net.DNSError{Err: err.Error(), Name: name, Server: server, IsTimeout: errors.Is(err, os.ErrDeadlineExceeded)}
+	case rcode == dnsmessage.RCodeSuccess:
+		return true, nil
+	case rcode == dnsmessage.RCodeNameError:
+		return false, nil
+	}
+	return false, &net.DNSError{Err: "server answered " + rcode.String(), Name: name, Server: server, IsTemporary: true}
+}
+
+// server is -resolver, or the first name server of /etc/resolv.conf.
+func (r *dnsResolver) server() (string, error) {
+	if r.Server != "" {
+		return r.Server, nil
+	}
+	f, err := os.Open("/etc/resolv.conf")
+	if err != nil {
+		return "", fmt.Errorf("no DNS server known: %w", err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			return net.JoinHostPort(fields[1], "53"), nil
+		}
+	}
+	return "", errors.New("no nameserver in /etc/resolv.conf")
+}
+
+// queryRCode sends an A query for name over UDP and returns the response
+// code. A truncated reply still carries it.
+func queryRCode(ctx context.Context, server, name string) (dnsmessage.RCode, error) {
+	qname, err := dnsmessage.NewName(name)
+	if err != nil {
+		return 0, err
+	}
+	query := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: uint16(rand.Uint32()), RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: qname, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET}},
+	}
+	packed, err := query.Pack()
+	if err != nil {
+		return 0, err
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", server)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if _, err := conn.Write(packed); err != nil {
+		return 0, err
+	}
+	buf := make([]byte, 1232)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return 0, err
+		}
+		var parser dnsmessage.Parser
+		header, err := parser.Start(buf[:n])
+		if err != nil || header.ID != query.ID || !header.Response {
+			// Not the reply to this query; keep waiting for it.
+			continue
+		}
+		return header.RCode, nil
+	}
+}
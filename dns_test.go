@@ -0,0 +1,143 @@
+package main
+
+import (
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// searchDomain is what a corporate resolver configuration would append to
+// relative names; the stub answers for every name under it, as wildcard
+// records there do.
+const searchDomain = "corp.example.net."
+
+// stubDNS serves DNS over UDP on a local port: taken.test. has name servers,
+// names under searchDomain exist, and everything else does not. It returns
+// the resolver address and the names asked.
+func stubDNS(t *testing.T) (string, func() []string) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	var mu sync.Mutex
+	var asked []string
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var query dnsmessage.Message
+			if err := query.Unpack(buf[:n]); err != nil || len(query.Questions) == 0 {
+				continue
+			}
+			question := query.Questions[0]
+			name := strings.ToLower(question.Name.String())
+			mu.Lock()
+			asked = append(asked, name)
+			mu.Unlock()
+
+			reply := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: query.ID, Response: true, Authoritative: true, RCode: dnsmessage.RCodeNameError},
+				Questions: query.Questions,
+			}
+			if name == "taken.test." || strings.HasSuffix(name, "."+searchDomain) {
+				reply.RCode = dnsmessage.RCodeSuccess
+				if question.Type == dnsmessage.TypeNS {
+					ns := dnsmessage.MustNewName("ns1." + name)
+					reply.Answers = []dnsmessage.Resource{{
+						Header: dnsmessage.ResourceHeader{Name: question.Name, Type: dnsmessage.TypeNS, Class: dnsmessage.ClassINET, TTL: 60},
+						Body:   &dnsmessage.NSResource{NS: ns},
+					}}
+				}
+			}
+			packed, err := reply.Pack()
+			if err != nil {
+				t.Error(err)
+				continue
+			}
+			conn.WriteTo(packed, addr)
+		}
+	}()
+	return conn.LocalAddr().String(), func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(asked)
+	}
+}
+
+// TestDNSLookupsAreFullyQualified checks names against a stub resolver that
+// would report any name with the search domain appended as existing: the
+// verdicts must come from the names themselves.
+func TestDNSLookupsAreFullyQualified(t *testing.T) {
+	server, asked := stubDNS(t)
+	defer func(r *dnsResolver) { resolver = r }(resolver)
+	var err error
+	if resolver, err = parseResolver(server); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		domain    string
+		delegated bool
+	}{
+		{"taken.test", true},
+		{"free.test", false},
+		{"free.test.", false},
+	} {
+		delegated, err := hasNameServers(tt.domain)
+		if err != nil || delegated != tt.delegated {
+			t.Errorf("hasNameServers(%q) = %v, %v; want %v", tt.domain, delegated, err, tt.delegated)
+		}
+	}
+	for _, tt := range []struct {
+		domain string
+		status Status
+	}{
+		{"api.taken.test", StatusAbsent},
+		// Present in every hosts file, but not in DNS.
+		{"localhost.test", StatusAbsent},
+		{"www." + strings.TrimSuffix(searchDomain, "."), StatusExists},
+	} {
+		if result := checkSubdomain(tt.domain); result.Status != tt.status {
+			t.Errorf("checkSubdomain(%q) = %s (%v), want %s", tt.domain, result.Status, result.Error, tt.status)
+		}
+	}
+
+	for _, name := range asked() {
+		if strings.HasSuffix(name, "."+searchDomain) && !strings.HasPrefix(name, "www.") {
+			t.Errorf("%s was looked up with the search domain appended", name)
+		}
+	}
+	if !slices.Contains(asked(), "free.test.") {
+		t.Errorf("free.test. was never asked; asked %v", asked())
+	}
+}
+
+func TestParseResolver(t *testing.T) {
+	for _, tt := range []struct {
+		value, want string
+		err         bool
+	}{
+		{"", "", false},
+		{"192.0.2.53", "192.0.2.53:53", false},
+		{"192.0.2.53:5353", "192.0.2.53:5353", false},
+		{"2001:db8::53", "[2001:db8::53]:53", false},
+		{"[2001:db8::53]:5353", "[2001:db8::53]:5353", false},
+		{"dns.example.net", "dns.example.net:53", false},
+		{":53", "", true},
+	} {
+		r, err := parseResolver(tt.value)
+		if (err != nil) != tt.err || err == nil && r.Server != tt.want {
+			t.Errorf("parseResolver(%q) = %+v, %v; want %q", tt.value, r, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// csvDomain is a domain read from -domains-csv with the other cells of its
+// row, keyed by column name.
+type csvDomain struct {
+	Domain   string
+	Metadata map[string]string
+}
+
+// readDomainsCSV reads the domains in one column of a CSV file. column is
+// a 1-based column number or a header name, matched ignoring case; with a
+// name the first row must be the header, with a number it is taken as one
+// when its cell is not a domain. Rows whose cell is not a valid domain are
+// skipped with a warning naming their line. With passthrough, the other
+// cells of each row are kept, keyed by header name, or "column N" without
+// a header.
+func readDomainsCSV(path, column string, passthrough bool) (domains []csvDomain, warnings []string, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading domains CSV: %w", err)
+	}
+	defer file.Close()
+
+	// encoding/csv would read a UTF-8 byte order mark, as spreadsheet
+	// programs write, as part of the first cell.
+	br := bufio.NewReader(file)
+	if bom, _ := br.Peek(3); string(bom) == "\ufeff" {
+		br.Discard(3)
+	}
+	r := csv.NewReader(br)
+	r.FieldsPerRecord = -1
+
+	index, byNumber := -1, false
+	if n, err := strconv.Atoi(column); err == nil {
+		if n < 1 {
+			return nil, nil, fmt.Errorf("-csv-column must be 1 or more, or a header name")
+		}
+		index, byNumber = n-1, true
+	}
+
+	var header []string
+	seen := map[string]int{}
+	for row := 0; ; row++ {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading domains CSV %s: %w", path, err)
+		}
+		line, _ := r.FieldPos(0)
+
+		if row == 0 {
+			if !byNumber {
+				for i, name := range record {
+					if strings.EqualFold(strings.TrimSpace(name), column) {
+						index = i
+						break
+					}
+				}
+				if index < 0 {
+					return nil, nil, fmt.Errorf("domains CSV %s has no column %q (header: %s)", path, column, strings.Join(record, ", "))
+				}
+				header = record
+				continue
+			}
+			if index < len(record) && csvDomainProblem(normalizeCSVDomain(record[index])) != "" {
+				header = record
+				continue
+			}
+		}
+
+		if index >= len(record) || strings.TrimSpace(record[index]) == "" {
+			warnings = append(warnings, fmt.Sprintf("domains CSV %s line %d: no domain in column %s, skipped", path, line, column))
+			continue
+		}
+		domain := normalizeCSVDomain(record[index])
+		if clean, changed := cleanInvisible(domain); changed {
+			warnings = append(warnings, fmt.Sprintf("domains CSV %s line %d: domain %q contained invisible characters or quotes, cleaned to %q", path, line, domain, clean))
+			domain = clean
+		}
+		if problem := csvDomainProblem(domain); problem != "" {
+			warnings = append(warnings, fmt.Sprintf("domains CSV %s line %d: %q %s, skipped", path, line, record[index], problem))
+			continue
+		}
+		if first, ok := seen[domain]; ok {
+			warnings = append(warnings, fmt.Sprintf("domains CSV %s line %d: %s is already on line %d, skipped", path, line, domain, first))
+			continue
+		}
+		seen[domain] = line
+
+		d := csvDomain{Domain: domain}
+		if passthrough {
+			d.Metadata = map[string]string{}
+			for i, value := range record {
+				if i == index {
+					continue
+				}
+				name := fmt.Sprintf("column %d", i+1)
+				if i < len(header) && strings.TrimSpace(header[i]) != "" {
+					name = strings.TrimSpace(header[i])
+				}
+				d.Metadata[name] = value
+			}
+		}
+		domains = append(domains, d)
+	}
+	return domains, warnings, nil
+}
+
+func normalizeCSVDomain(cell string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(cell)), ".")
+}
+
+// csvDomainProblem says why a cell is not a domain, or "" when it is one.
+func csvDomainProblem(domain string) string {
+	if !strings.Contains(domain, ".") {
+		return "is not a domain"
+	}
+	for _, label := range strings.Split(domain, ".") {
+		if problem := labelProblem(label); problem != "" {
+			return "is not a valid domain: " + problem
+		}
+	}
+	return ""
+}
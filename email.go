@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type EmailFlags struct {
+	To       *string
+	From     *string
+	Host     *string
+	Port     *int
+	User     *string
+	Password *string
+	TLS      *string
+}
+
+func addEmailFlags(fs *flag.FlagSet) *EmailFlags {
+	port, _ := strconv.Atoi(os.Getenv("DOMAIN_CHECKER_SMTP_PORT"))
+	if port == 0 {
+		port = 587
+	}
+	return &EmailFlags{
+		To:       fs.String("email-to", os.Getenv("DOMAIN_CHECKER_EMAIL_TO"), "Comma-separated recipients of the email report (env DOMAIN_CHECKER_EMAIL_TO)"),
+		From:     fs.String("email-from", os.Getenv("DOMAIN_CHECKER_EMAIL_FROM"), "Sender address of the email report (env DOMAIN_CHECKER_EMAIL_FROM)"),
+		Host:     fs.String("smtp-host", os.Getenv("DOMAIN_CHECKER_SMTP_HOST"), "SMTP server host (env DOMAIN_CHECKER_SMTP_HOST)"),
+		Port:     fs.Int("smtp-port", port, "SMTP server port (env DOMAIN_CHECKER_SMTP_PORT)"),
+		User:     fs.String("smtp-user", os.Getenv("DOMAIN_CHECKER_SMTP_USER"), "SMTP username (env DOMAIN_CHECKER_SMTP_USER)"),
+		Password: fs.String("smtp-pass", "", "SMTP password (default: env DOMAIN_CHECKER_SMTP_PASS)"),
+		TLS:      fs.String("smtp-tls", "auto", "SMTP encryption: auto, starttls, tls or none (auto uses tls on port 465, starttls otherwise)"),
+	}
+}
+
+// Notifier returns nil when no recipients are configured.
+func (f *EmailFlags) Notifier() (*EmailNotifier, error) {
+	if *f.To == "" {
+		return nil, nil
+	}
+	if *f.From == "" || *f.Host == "" {
+		return nil, fmt.Errorf("-email-to requires -email-from and -smtp-host")
+	}
+
+	mode := *f.TLS
+	if mode == "auto" {
+		mode = "starttls"
+		if *f.Port == 465 {
+			mode = "tls"
+		}
+	}
+	if mode != "starttls" && mode != "tls" && mode != "none" {
+		return nil, fmt.Errorf("-smtp-tls must be auto, starttls, tls or none")
+	}
+
+	// The password is read from the environment only here, so that it never
+	// shows up as a flag default in -h output.
+	password := *f.Password
+	if password == "" {
+		password = os.Getenv("DOMAIN_CHECKER_SMTP_PASS")
+	}
+
+	return &EmailNotifier{
+		To:       parseKeywords(*f.To),
+		From:     *f.From,
+		Host:     *f.Host,
+		Port:     *f.Port,
+		User:     *f.User,
+		Password: password,
+		TLSMode:  mode,
+	}, nil
+}
+
+type EmailNotifier struct {
+	To       []string
+	From     string
+	Host     string
+	Port     int
+	User     string
+	Password string
+	TLSMode  string
+}
+
+// SendReport emails body as plain text with the results attached as CSV.
+func (e *EmailNotifier) SendReport(ctx context.Context, subject, body string, results []DomainResult) error {
+	var attachment bytes.Buffer
+	if err := writeCSV(&attachment, results); err != nil {
+		return err
+	}
+
+	msg, err := e.buildMessage(subject, body, attachment.Bytes())
+	if err != nil {
+		return err
+	}
+	return e.send(ctx, msg)
+}
+
+func (e *EmailNotifier) buildMessage(subject, body string, csvData []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	fmt.Fprintf(&buf, "From: %s\r\n", e.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(e.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", mw.Boundary())
+
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"8bit"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	part.Write([]byte(strings.ReplaceAll(body, "\n", "\r\n")))
+
+	part, err = mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/csv; charset=utf-8"},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Disposition":       {`attachment; filename="results.csv"`},
+	})
+	if err != nil {
+		return nil, err
+	}
+	encoded := base64.StdEncoding.EncodeToString(csvData)
+	for len(encoded) > 76 {
+		part.Write([]byte(encoded[:76] + "\r\n"))
+		encoded = encoded[76:]
+	}
+	part.Write([]byte(encoded + "\r\n"))
+
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (e *EmailNotifier) send(ctx context.Context, msg []byte) error {
+	addr := net.JoinHostPort(e.Host, strconv.Itoa(e.Port))
+	tlsConfig := &tls.Config{ServerName: e.Host}
+
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(2 * time.Minute))
+	if e.TLSMode == "tls" {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	client, err := smtp.NewClient(conn, e.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if e.TLSMode == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("%s does not support STARTTLS", addr)
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+
+	if e.User != "" {
+		if err := client.Auth(smtp.PlainAuth("", e.User, e.Password, e.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(e.From); err != nil {
+		return err
+	}
+	for _, to := range e.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
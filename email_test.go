@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// smtpStub accepts mail on a local port without authentication or TLS, and
+// sends the data of each message it receives on the returned channel.
+func smtpStub(t *testing.T) (string, int, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	messages := make(chan string, 1)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+				reply("220 stub")
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					switch verb := strings.ToUpper(strings.Fields(line + " ")[0]); verb {
+					case "DATA":
+						reply("354 go ahead")
+						var data strings.Builder
+						for {
+							line, err := r.ReadString('\n')
+							if err != nil || line == ".\r\n" {
+								break
+							}
+							data.WriteString(line)
+						}
+						messages <- data.String()
+						reply("250 queued")
+					case "QUIT":
+						reply("221 bye")
+						return
+					default:
+						reply("250 ok")
+					}
+				}
+			}()
+		}
+	}()
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	n, _ := strconv.Atoi(port)
+	return host, n, messages
+}
+
+// TestEmailChangesOnlyAttachesEveryResult checks that with -changes-only
+// the email body is the digest of changes while the attached CSV still
+// holds every result.
+func TestEmailChangesOnlyAttachesEveryResult(t *testing.T) {
+	dir := t.TempDir()
+	fixture := filepath.Join(dir, "fixture.txt")
+	if err := os.WriteFile(fixture, []byte("stable.com taken\nmoved.com available\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	baseline := filepath.Join(dir, "baseline.json")
+	previous := `{"results":[{"domain":"stable.com","status":"taken"},{"domain":"moved.com","status":"taken"}]}`
+	if err := os.WriteFile(baseline, []byte(previous), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	host, port, messages := smtpStub(t)
+
+	code, logged, _ := runCaptured(t, "-backend=fake", "-fake-fixture="+fixture, "-domains=stable.com,moved.com",
+		"-changes-only", "-baseline="+baseline,
+		"-email-to=me@example.com", "-email-from=checker@example.com",
+		"-smtp-host="+host, "-smtp-port="+strconv.Itoa(port), "-smtp-tls=none")
+	if code != exitOK {
+		t.Fatalf("exit code = %d\n%s", code, logged)
+	}
+	var message string
+	select {
+	case message = <-messages:
+	default:
+		t.Fatalf("no email sent\n%s", logged)
+	}
+
+	body, attachment, ok := strings.Cut(message, `filename="results.csv"`)
+	if !ok {
+		t.Fatalf("email lacks the CSV attachment:\n%s", message)
+	}
+	if !strings.Contains(body, "moved.com") || strings.Contains(body, "stable.com") {
+		t.Errorf("email body is not the digest of changes:\n%s", body)
+	}
+	_, encoded, _ := strings.Cut(attachment, "\r\n\r\n")
+	encoded, _, _ = strings.Cut(encoded, "\r\n--")
+	csv, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(encoded, "\r\n", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, domain := range []string{"stable.com", "moved.com"} {
+		if !strings.Contains(string(csv), domain) {
+			t.Errorf("attached CSV lacks %s:\n%s", domain, csv)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// ErrorCategory says why a check failed, independent of the wording of the
+// underlying error.
+type ErrorCategory string
+
+const (
+	ErrorTimeout     ErrorCategory = "timeout"
+	ErrorConnection  ErrorCategory = "connection"
+	ErrorDNS         ErrorCategory = "dns"
+	ErrorRateLimited ErrorCategory = "rate-limited"
+	ErrorNoServer    ErrorCategory = "no-server"
+	ErrorStalled     ErrorCategory = "stalled"
+	ErrorOther       ErrorCategory = "other"
+)
+
+var (
+	errRateLimited = errors.New("server is rate limiting queries")
+	errNoServer    = errors.New("no whois server known for this TLD")
+	errStalled     = errors.New("check abandoned, no result arrived in time")
+	errOutOfBudget = errors.New("not checked, -budget ran out")
+	errOutOfQuota  = errors.New("skipped: quota")
+	errEchoReply   = errors.New("short reply without registrar, dates or name servers, likely an echo of the query")
+	errDeferred    = errors.New("registry could not answer the query")
+	// errVerifyConflict marks an available verdict -verify-available found
+	// contradicted by DNS.
+	errVerifyConflict = errors.New("whois and DNS disagree")
+)
+
+func categorizeError(err error) ErrorCategory {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, errRateLimited):
+		return ErrorRateLimited
+	case errors.Is(err, errNoServer):
+		return ErrorNoServer
+	case errors.Is(err, errStalled):
+		return ErrorStalled
+	case errors.As(err, &dnsErr):
+		return ErrorDNS
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorTimeout
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH):
+		return ErrorConnection
+	default:
+		return ErrorOther
+	}
+}
+
+// Retriable reports whether another server may succeed where this one failed.
+func (c ErrorCategory) Retriable() bool {
+	switch c {
+	case ErrorTimeout, ErrorConnection, ErrorDNS, ErrorRateLimited:
+		return true
+	}
+	return false
+}
+
+// rateLimitIndicators are replies servers send instead of an answer when a
+// client queries too fast. A spent daily quota is no rate limit: its replies
+// are deferred by the classifier instead.
+var rateLimitIndicators = []string{
+	"limit exceeded",
+	"too many requests",
+	"query rate",
+	"exceeded the maximum allowable",
+	"please try again later",
+}
+
+func isRateLimitResponse(lower string) bool {
+	for _, indicator := range rateLimitIndicators {
+		if strings.Contains(lower, indicator) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Expectation is the status one domain of -expect-file should have.
+type Expectation struct {
+	Domain string
+	// Status is empty for a domain that is only watched.
+	Status Status
+	// Registrar, when set, must be a case-insensitive substring of the
+	// registrar of a taken domain, e.g. the one it was registered with.
+	Registrar string
+}
+
+// Mismatch is a domain of -expect-file whose result differs from its
+// expectation.
+type Mismatch struct {
+	Domain            string `json:"domain"`
+	Expected          Status `json:"expected"`
+	ExpectedRegistrar string `json:"expectedRegistrar,omitempty"`
+	Actual            Status `json:"actual"`
+	Registrar         string `json:"registrar,omitempty"`
+	Reason            string `json:"reason"`
+}
+
+// readExpectations reads an expectations file. Each line holds a domain
+// and what it should be:
+//
+//	mybrand.com     taken  MarkMonitor   registered, by a registrar matching MarkMonitor
+//	mybrand-app.io  available            deliberately left unregistered
+//	mybrand.net     watch                checked and listed, never a mismatch
+//
+// The registrar is the rest of the line, so it may contain spaces. Text
+// after # is a comment.
+func readExpectations(path string) ([]Expectation, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading expectations file: %w", err)
+	}
+	defer file.Close()
+
+	var expectations []Expectation
+	seen := map[string]int{}
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) == 1 {
+			return nil, fmt.Errorf("expectations file %s line %d: %s has no expected status (available, taken or watch)", path, line, fields[0])
+		}
+
+		e := Expectation{Domain: strings.ToLower(fields[0])}
+		switch Status(strings.ToLower(fields[1])) {
+		case StatusAvailable:
+			e.Status = StatusAvailable
+		case StatusTaken:
+			e.Status = StatusTaken
+			e.Registrar = strings.Join(fields[2:], " ")
+		case "watch", "any":
+		default:
+			return nil, fmt.Errorf("expectations file %s line %d: unknown status %q (want available, taken or watch)", path, line, fields[1])
+		}
+		if e.Status != StatusTaken && len(fields) > 2 {
+			return nil, fmt.Errorf("expectations file %s line %d: only taken domains take a registrar", path, line)
+		}
+		if first, ok := seen[e.Domain]; ok {
+			return nil, fmt.Errorf("expectations file %s line %d: %s is already listed on line %d", path, line, e.Domain, first)
+		}
+		seen[e.Domain] = line
+		expectations = append(expectations, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading expectations file: %w", err)
+	}
+	return expectations, nil
+}
+
+// mismatches compares the results with the expectations. As with -expect,
+// a domain that could not be checked is a mismatch, since its expectation
+// was not verified.
+func mismatches(results []DomainResult, expectations []Expectation) []Mismatch {
+	byDomain := map[string]DomainResult{}
+	for _, result := range results {
+		byDomain[result.Domain] = result
+	}
+
+	var found []Mismatch
+	for _, e := range expectations {
+		if e.Status == "" {
+			continue
+		}
+		result, ok := byDomain[e.Domain]
+		m := Mismatch{
+			Domain:            e.Domain,
+			Expected:          e.Status,
+			ExpectedRegistrar: e.Registrar,
+			Actual:            result.Status,
+			Registrar:         result.Registrar,
+		}
+		switch {
+		case !ok:
+			m.Actual = StatusUnchecked
+			m.Reason = "not checked"
+		case result.Status != e.Status:
+			m.Reason = fmt.Sprintf("expected %s, is %s", e.Status, result.Status)
+			if result.Error != nil {
+				m.Reason += fmt.Sprintf(" (%v)", result.Error)
+			}
+		case e.Registrar == "":
+			continue
+		case result.Registrar == "":
+			m.Reason = fmt.Sprintf("expected registrar %s, the whois reply names none", e.Registrar)
+		case !strings.Contains(strings.ToLower(result.Registrar), strings.ToLower(e.Registrar)):
+			m.Reason = fmt.Sprintf("expected registrar %s, is %s", e.Registrar, result.Registrar)
+		default:
+			continue
+		}
+		found = append(found, m)
+	}
+	return found
+}
+
+// writeMismatches prints the MISMATCHES section of the text output.
+func writeMismatches(w io.Writer, found []Mismatch) {
+	fmt.Fprintf(w, "%s MISMATCHES (%d):\n", sym.Warning, len(found))
+	for _, m := range found {
+		fmt.Fprintf(w, "  %s: %s\n", m.Domain, m.Reason)
+	}
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fakeChecker answers checks without any network traffic, for demos,
+// examples and tests of everything downstream of the check. A domain of the
+// fixture gets the status given there; any other one a status derived from
+// a hash of its name, so the same domain always gets the same verdict.
+type fakeChecker struct {
+	Fixture map[string]Status
+	// Latency is the average time a check takes; each domain takes between
+	// half and one and a half of it.
+	Latency time.Duration
+
+	mu sync.Mutex
+	// attempts counts the checks of each domain, so a simulated failure
+	// succeeds when it is retried.
+	attempts map[string]int
+}
+
+// fakeRegistrar is the registrar of every domain the fake backend reports
+// taken.
+const fakeRegistrar = "Example Registrar, Inc."
+
+func fakeHash(domain string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(domain))
+	return h.Sum32()
+}
+
+func (c *fakeChecker) Check(domain string) DomainResult {
+	checked := DomainResult{Domain: domain, Method: "fake", CheckedAt: time.Now()}
+	hash := fakeHash(domain)
+
+	c.mu.Lock()
+	if c.attempts == nil {
+		c.attempts = map[string]int{}
+	}
+	c.attempts[domain]++
+	attempt := c.attempts[domain]
+	c.mu.Unlock()
+
+	if c.Latency > 0 {
+		time.Sleep(c.Latency/2 + time.Duration(hash%1000)*c.Latency/1000)
+	}
+
+	status, ok := c.Fixture[domain]
+	rule := "fixture"
+	if !ok {
+		status, rule = fakeStatus(domain, hash, attempt), "hash of the name"
+	}
+	checked.Status = status
+	switch status {
+	case StatusError:
+		checked.Error = fmt.Errorf("fake backend: simulated %w", os.ErrDeadlineExceeded)
+	case StatusDeferred:
+		checked.Error = errDeferred
+	case StatusTaken:
+		checked.Registrar = fakeRegistrar
+		checked.CreatedAt = time.Date(2000+int(hash%24), time.Month(1+hash%12), 1, 0, 0, 0, 0, time.UTC)
+		checked.ExpiresAt = time.Date(checked.CheckedAt.Year()+1, checked.CreatedAt.Month(), 1, 0, 0, 0, 0, time.UTC)
+		checked.EPPStatus = []string{"clientTransferProhibited"}
+	}
+	checked.Duration = time.Since(checked.CheckedAt)
+	checked.Attempts = []Attempt{newAttempt("fake", "", checked.CheckedAt, checked.Status, checked.Error)}
+	checked.Attempts[0].Rule = rule
+	return checked
+}
+
+// fakeStatus derives a status from the hash of domain: about half the
+// names are taken, as real names mostly are, and a few reserved. One in
+// twenty fails its first attempt with a timeout, to exercise retries.
+func fakeStatus(domain string, hash uint32, attempt int) Status {
+	if isSubdomain(domain) {
+		if hash%2 == 0 {
+			return StatusExists
+		}
+		return StatusAbsent
+	}
+	bucket := hash % 100
+	if attempt == 1 && (hash/100)%20 == 0 {
+		return StatusError
+	}
+	switch {
+	case bucket < 50:
+		return StatusTaken
+	case bucket < 55:
+		return StatusReserved
+	default:
+		return StatusAvailable
+	}
+}
+
+// readFakeFixture reads a fixture for the fake backend, one domain and its
+// status per line:
+//
+//	example.com       taken
+//	example-app.io    available
+//	flaky.dev         error
+//
+// Text after # is a comment.
+func readFakeFixture(path string) (map[string]Status, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading fake fixture: %w", err)
+	}
+	defer file.Close()
+
+	fixture := map[string]Status{}
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("fake fixture %s line %d: want a domain and a status", path, line)
+		}
+		domain := strings.TrimSuffix(strings.ToLower(fields[0]), ".")
+		status := Status(strings.ToLower(fields[1]))
+		switch status {
+		case StatusAvailable, StatusTaken, StatusReserved, StatusUnknown, StatusDeferred, StatusError, StatusExists, StatusAbsent:
+		default:
+			return nil, fmt.Errorf("fake fixture %s line %d: unknown status %q (use available, taken, reserved, unknown, deferred, error, exists or absent)", path, line, fields[1])
+		}
+		if _, ok := fixture[domain]; ok {
+			return nil, fmt.Errorf("fake fixture %s line %d: %s is listed twice", path, line, domain)
+		}
+		fixture[domain] = status
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading fake fixture: %w", err)
+	}
+	return fixture, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+const (
+	consonants = "bcdfghjklmnprstvwz"
+	vowels     = "aeiou"
+)
+
+// maxRandomPatternLength keeps generated names short enough to be brandable.
+const maxRandomPatternLength = 12
+
+// profanity are substrings no generated name may contain. Random letters
+// stumble into these surprisingly often.
+var profanity = []string{
+	"anal", "anus", "arse", "butt", "cock", "coon", "crap", "cum", "cunt",
+	"dick", "dyke", "fag", "fuck", "gook", "homo", "jizz", "kike", "nazi",
+	"nig", "paki", "penis", "piss", "poo", "porn", "puss", "rape", "sex",
+	"shit", "slut", "spic", "tit", "twat", "vag", "wank", "whore",
+}
+
+// parseRandomPatterns reads -random: comma-separated patterns of c for a
+// consonant and v for a vowel, e.g. "cvcv,cvccv".
+func parseRandomPatterns(input string) ([]string, error) {
+	patterns := parseKeywords(strings.ToLower(input))
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("-random needs at least one pattern of c and v, e.g. cvcvc")
+	}
+	for _, pattern := range patterns {
+		if pattern == "" || strings.Trim(pattern, "cv") != "" {
+			return nil, fmt.Errorf("invalid -random pattern %q (use c for a consonant and v for a vowel, e.g. cvcvc)", pattern)
+		}
+		if len(pattern) > maxRandomPatternLength {
+			return nil, fmt.Errorf("-random pattern %q is longer than %d letters", pattern, maxRandomPatternLength)
+		}
+	}
+	return patterns, nil
+}
+
+// letterPools splits the letters of the keywords into consonants and
+// vowels. A pool the keywords leave empty falls back to the full alphabet.
+func letterPools(keywords []string) (string, string) {
+	var cons, vows strings.Builder
+	seen := map[rune]bool{}
+	for _, r := range strings.ToLower(strings.Join(keywords, "")) {
+		if seen[r] {
+			continue
+		}
+		seen[r] = true
+		switch {
+		case strings.ContainsRune(vowels, r):
+			vows.WriteRune(r)
+		case r >= 'a' && r <= 'z':
+			cons.WriteRune(r)
+		}
+	}
+	c, v := cons.String(), vows.String()
+	if c == "" {
+		c = consonants
+	}
+	if v == "" {
+		v = vowels
+	}
+	return c, v
+}
+
+// randomNames invents up to count distinct names following the patterns in
+// turn, with letters drawn from the pools. Names containing profanity are
+// dropped. Small patterns over small pools may yield fewer than count.
+func randomNames(patterns []string, count int, cons, vows string, rng *rand.Rand) []string {
+	seen := map[string]bool{}
+	var names []string
+	for attempt := 0; len(names) < count && attempt < count*20; attempt++ {
+		pattern := patterns[attempt%len(patterns)]
+		name := make([]byte, len(pattern))
+		for i := range pattern {
+			pool := cons
+			if pattern[i] == 'v' {
+				pool = vows
+			}
+			name[i] = pool[rng.Intn(len(pool))]
+		}
+		s := string(name)
+		if seen[s] || containsAny(s, profanity) {
+			continue
+		}
+		seen[s] = true
+		names = append(names, s)
+	}
+	return names
+}
+
+// randomCandidates wraps the invented names for TLD expansion.
+func randomCandidates(names []string) []Candidate {
+	candidates := make([]Candidate, len(names))
+	for i, name := range names {
+		candidates[i] = Candidate{BaseName: name, Variant: VariantRandom}
+	}
+	return candidates
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HandleStatus is the heuristic availability of a social media handle.
+type HandleStatus string
+
+const (
+	HandleFree    HandleStatus = "free"
+	HandleTaken   HandleStatus = "taken"
+	HandleUnknown HandleStatus = "unknown"
+	HandleInvalid HandleStatus = "invalid"
+)
+
+type handlePlatform struct {
+	URL   string
+	Valid *regexp.Regexp
+}
+
+var handlePlatforms = map[string]handlePlatform{
+	"github": {
+		URL:   "https://github.com/%s",
+		Valid: regexp.MustCompile(`^[a-z0-9](?:[a-z0-9]|-[a-z0-9]){0,38}$`),
+	},
+	"twitter": {
+		URL:   "https://x.com/%s",
+		Valid: regexp.MustCompile(`^[a-z0-9_]{1,15}$`),
+	},
+	"instagram": {
+		URL:   "https://www.instagram.com/%s/",
+		Valid: regexp.MustCompile(`^[a-z0-9._]{1,30}$`),
+	},
+}
+
+func parseHandlePlatforms(input string) ([]string, error) {
+	platforms := parseKeywords(strings.ToLower(input))
+	for _, p := range platforms {
+		if _, ok := handlePlatforms[p]; !ok {
+			return nil, fmt.Errorf("unknown handle platform %q (use github, twitter or instagram)", p)
+		}
+	}
+	return platforms, nil
+}
+
+// HandleChecker probes profile URLs; a 404 means the handle is likely free.
+// Requests to each platform are spaced by Interval, and no more than
+// MaxProbes are sent in total (0 for no limit).
+type HandleChecker struct {
+	Platforms []string
+	Interval  time.Duration
+	MaxProbes int
+	Client    *http.Client
+
+	mu     sync.Mutex
+	probes int
+}
+
+// newHandleClient is strict with sites that are not ours: a short timeout
+// and at most one redirect.
+func newHandleClient() *http.Client {
+	return &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: budgetTransport{},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > 1 {
+				return http.ErrUseLastResponse
+			}
+			return nil
+		},
+	}
+}
+
+// takeProbe reserves one of MaxProbes.
+func (h *HandleChecker) takeProbe() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.MaxProbes > 0 && h.probes >= h.MaxProbes {
+		return false
+	}
+	h.probes++
+	return true
+}
+
+// CheckResults annotates every available result with the status of its base
+// name on each platform. Each name is probed once per platform.
+func (h *HandleChecker) CheckResults(ctx context.Context, results []DomainResult) {
+	var names []string
+	seen := map[string]bool{}
+	for _, result := range results {
+		if result.Status != StatusAvailable {
+			continue
+		}
+		name := baseName(result.Domain)
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	statuses := map[string]map[string]HandleStatus{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, platform := range h.Platforms {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i, name := range names {
+				if i > 0 && h.Interval > 0 {
+					select {
+					case <-ctx.Done():
+						return
+					case <-time.After(h.Interval):
+					}
+				}
+				status, ok := h.check(ctx, platform, name)
+				if !ok {
+					continue
+				}
+				mu.Lock()
+				if statuses[name] == nil {
+					statuses[name] = map[string]HandleStatus{}
+				}
+				statuses[name][platform] = status
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i := range results {
+		if results[i].Status == StatusAvailable {
+			results[i].Handles = statuses[baseName(results[i].Domain)]
+		}
+	}
+}
+
+// check probes one handle. It reports false when no probe was made or the
+// probe failed, and the handle is then left out of the result.
+func (h *HandleChecker) check(ctx context.Context, platform, name string) (HandleStatus, bool) {
+	p := handlePlatforms[platform]
+	handle := strings.ToLower(name)
+	if !p.Valid.MatchString(handle) {
+		return HandleInvalid, true
+	}
+	if !h.takeProbe() {
+		debugLog.Printf("handles: %s on %s not probed, limit of %d probes reached", handle, platform, h.MaxProbes)
+		return "", false
+	}
+
+	url := fmt.Sprintf(p.URL, handle)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", false
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		debugLog.Printf("handles: GET %s: %v", url, err)
+		return "", false
+	}
+	resp.Body.Close()
+	debugLog.Printf("handles: GET %s: %s", url, resp.Status)
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return HandleFree, true
+	case resp.StatusCode == http.StatusOK:
+		return HandleTaken, true
+	default:
+		return HandleUnknown, true
+	}
+}
+
+// baseName is the domain without its TLD.
+func baseName(domain string) string {
+	name, _, _ := strings.Cut(domain, ".")
+	return name
+}
+
+func formatHandles(handles map[string]HandleStatus) string {
+	if len(handles) == 0 {
+		return ""
+	}
+	platforms := make([]string, 0, len(handles))
+	for p := range handles {
+		platforms = append(platforms, p)
+	}
+	sort.Strings(platforms)
+
+	parts := make([]string, len(platforms))
+	for i, p := range platforms {
+		parts[i] = fmt.Sprintf("%s %s", p, handles[p])
+	}
+	return "handles (heuristic): " + strings.Join(parts, ", ")
+}
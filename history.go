@@ -0,0 +1,643 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// historyMigrations are applied in order; the schema_version table records
+// how many have run. Never edit an existing entry, only append new ones.
+var historyMigrations = []string{
+	`CREATE TABLE checks (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		run_id      TEXT NOT NULL,
+		domain      TEXT NOT NULL,
+		status      TEXT NOT NULL,
+		epp_status  TEXT NOT NULL DEFAULT '',
+		method      TEXT NOT NULL DEFAULT '',
+		checked_at  TEXT NOT NULL,
+		duration_ms INTEGER NOT NULL DEFAULT 0,
+		error       TEXT NOT NULL DEFAULT ''
+	);
+	CREATE INDEX checks_domain_checked_at ON checks (domain, checked_at);
+	CREATE INDEX checks_checked_at ON checks (checked_at);`,
+
+	`CREATE TABLE monitor_state (
+		domain TEXT PRIMARY KEY,
+		state  TEXT NOT NULL
+	);`,
+
+	`CREATE TABLE runs (
+		run_id      TEXT PRIMARY KEY,
+		started_at  TEXT NOT NULL,
+		finished_at TEXT NOT NULL DEFAULT ''
+	);`,
+
+	`ALTER TABLE runs ADD COLUMN whois_queries INTEGER NOT NULL DEFAULT 0;`,
+
+	`ALTER TABLE checks ADD COLUMN registrar TEXT NOT NULL DEFAULT '';
+	ALTER TABLE checks ADD COLUMN created_at TEXT NOT NULL DEFAULT '';`,
+
+	`ALTER TABLE runs ADD COLUMN manifest TEXT NOT NULL DEFAULT '';`,
+}
+
+// HistoryStore keeps every check result in a SQLite database.
+type HistoryStore struct {
+	db *sql.DB
+}
+
+// HistoryEntry is a single stored check result.
+type HistoryEntry struct {
+	RunID     string
+	Domain    string
+	Status    Status
+	EPPStatus []string
+	Method    string
+	CheckedAt time.Time
+	Duration  time.Duration
+	Error     string
+	// Registrar and CreatedAt are parsed from whois replies of taken
+	// domains; empty for checks recorded before they were kept.
+	Registrar string
+	CreatedAt time.Time
+}
+
+func OpenHistory(path string) (*HistoryStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("opening history database: %w", err)
+	}
+	// SQLite allows a single writer; serialize access through one connection.
+	db.SetMaxOpenConns(1)
+
+	store := &HistoryStore{db: db}
+	if err := store.migrate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrating history database %s: %w", path, err)
+	}
+	return store, nil
+}
+
+func (h *HistoryStore) Close() error {
+	return h.db.Close()
+}
+
+func (h *HistoryStore) migrate() error {
+	version, err := h.schemaVersion()
+	if err != nil {
+		return err
+	}
+	if version > len(historyMigrations) {
+		return fmt.Errorf("database schema version %d is newer than this binary supports (%d); upgrade domain-checker to use it", version, len(historyMigrations))
+	}
+
+	for i := version; i < len(historyMigrations); i++ {
+		tx, err := h.db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(historyMigrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(`UPDATE schema_version SET version = ?`, i+1); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		// Builds from before schema_version read user_version; keeping it
+		// current lets them refuse a newer database rather than migrate it
+		// again.
+		if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, i+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// schemaVersion returns how many migrations have run, creating the
+// schema_version table on first use. Databases written before the table
+// existed kept the count in PRAGMA user_version, which seeds it.
+func (h *HistoryStore) schemaVersion() (int, error) {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`CREATE TABLE IF NOT EXISTS schema_version (version INTEGER NOT NULL)`); err != nil {
+		return 0, err
+	}
+	var version int
+	err = tx.QueryRow(`SELECT version FROM schema_version`).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		if err := tx.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+			return 0, err
+		}
+		_, err = tx.Exec(`INSERT INTO schema_version (version) VALUES (?)`, version)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return version, tx.Commit()
+}
+
+// Record stores a run and its results in one transaction, so a crash never
+// leaves a run half written.
+func (h *HistoryStore) Record(run RunInfo, results []DomainResult) error {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return fmt.Errorf("recording history: %w", err)
+	}
+	defer tx.Rollback()
+
+	finishedAt := ""
+	if !run.FinishedAt.IsZero() {
+		finishedAt = run.FinishedAt.UTC().Format(time.RFC3339Nano)
+	}
+	manifest := ""
+	if run.Manifest != nil {
+		data, err := json.Marshal(run.Manifest)
+		if err != nil {
+			return fmt.Errorf("recording history: %w", err)
+		}
+		manifest = string(data)
+	}
+	_, err = tx.Exec(
+		`INSERT INTO runs (run_id, started_at, finished_at, manifest) VALUES (?, ?, ?, ?)
+		 ON CONFLICT (run_id) DO UPDATE SET finished_at = excluded.finished_at`,
+		run.ID, run.StartedAt.UTC().Format(time.RFC3339Nano), finishedAt, manifest)
+	if err != nil {
+		return fmt.Errorf("recording history: %w", err)
+	}
+
+	for _, result := range results {
+		errText := ""
+		if result.Error != nil {
+			errText = result.Error.Error()
+		}
+		createdAt := ""
+		if !result.CreatedAt.IsZero() {
+			createdAt = result.CreatedAt.UTC().Format(time.RFC3339Nano)
+		}
+		_, err := tx.Exec(
+			`INSERT INTO checks (run_id, domain, status, epp_status, method, checked_at, duration_ms, error, registrar, created_at)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			run.ID,
+			result.Domain,
+			string(result.Status),
+			strings.Join(result.EPPStatus, " "),
+			result.Method,
+			result.CheckedAt.UTC().Format(time.RFC3339Nano),
+			result.Duration.Milliseconds(),
+			errText,
+			result.Registrar,
+			createdAt,
+		)
+		if err != nil {
+			return fmt.Errorf("recording history: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("recording history: %w", err)
+	}
+	return nil
+}
+
+// Run returns a stored run with its manifest, which is nil for runs
+// recorded without one, such as monitor sweeps.
+func (h *HistoryStore) Run(runID string) (RunInfo, error) {
+	var startedAt, finishedAt, manifest string
+	err := h.db.QueryRow(`SELECT started_at, finished_at, manifest FROM runs WHERE run_id = ?`, runID).
+		Scan(&startedAt, &finishedAt, &manifest)
+	if errors.Is(err, sql.ErrNoRows) {
+		return RunInfo{}, fmt.Errorf("no run %s in the history", runID)
+	}
+	if err != nil {
+		return RunInfo{}, fmt.Errorf("reading history: %w", err)
+	}
+
+	run := RunInfo{ID: runID}
+	run.StartedAt, _ = time.Parse(time.RFC3339Nano, startedAt)
+	run.FinishedAt, _ = time.Parse(time.RFC3339Nano, finishedAt)
+	if manifest != "" {
+		run.Manifest = &Manifest{}
+		if err := json.Unmarshal([]byte(manifest), run.Manifest); err != nil {
+			return RunInfo{}, fmt.Errorf("reading history: run %s: %w", runID, err)
+		}
+	}
+	return run, nil
+}
+
+// SetWhoisQueries records how many whois queries a run sent.
+func (h *HistoryStore) SetWhoisQueries(runID string, queries int) error {
+	_, err := h.db.Exec(`UPDATE runs SET whois_queries = ? WHERE run_id = ?`, queries, runID)
+	if err != nil {
+		return fmt.Errorf("recording history: %w", err)
+	}
+	return nil
+}
+
+// WhoisQueriesSince sums the whois queries of runs started at or after since.
+func (h *HistoryStore) WhoisQueriesSince(since time.Time) (int, error) {
+	var queries int
+	err := h.db.QueryRow(`SELECT COALESCE(SUM(whois_queries), 0) FROM runs WHERE started_at >= ?`,
+		since.UTC().Format(time.RFC3339Nano)).Scan(&queries)
+	if err != nil {
+		return 0, fmt.Errorf("reading history: %w", err)
+	}
+	return queries, nil
+}
+
+// Timeline returns all stored checks of a domain, oldest first.
+func (h *HistoryStore) Timeline(domain string) ([]HistoryEntry, error) {
+	rows, err := h.db.Query(
+		`SELECT run_id, domain, status, epp_status, method, checked_at, duration_ms, error, registrar, created_at
+		 FROM checks WHERE domain = ? ORDER BY checked_at, id`, domain)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []HistoryEntry
+	for rows.Next() {
+		e, err := scanHistoryEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// Export calls fn with every stored check, oldest first.
+func (h *HistoryStore) Export(fn func(HistoryEntry) error) error {
+	rows, err := h.db.Query(
+		`SELECT run_id, domain, status, epp_status, method, checked_at, duration_ms, error, registrar, created_at
+		 FROM checks ORDER BY checked_at, id`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e, err := scanHistoryEntry(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// HistoryRun is a stored run, as written by history export.
+type HistoryRun struct {
+	ID           string    `json:"runId"`
+	StartedAt    time.Time `json:"startedAt"`
+	FinishedAt   time.Time `json:"finishedAt"`
+	WhoisQueries int       `json:"whoisQueries"`
+	Manifest     *Manifest `json:"manifest,omitempty"`
+}
+
+// ExportRuns calls fn with every stored run, oldest first.
+func (h *HistoryStore) ExportRuns(fn func(HistoryRun) error) error {
+	rows, err := h.db.Query(
+		`SELECT run_id, started_at, finished_at, whois_queries, manifest FROM runs ORDER BY started_at, run_id`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var run HistoryRun
+		var startedAt, finishedAt, manifest string
+		if err := rows.Scan(&run.ID, &startedAt, &finishedAt, &run.WhoisQueries, &manifest); err != nil {
+			return err
+		}
+		run.StartedAt, _ = time.Parse(time.RFC3339Nano, startedAt)
+		run.FinishedAt, _ = time.Parse(time.RFC3339Nano, finishedAt)
+		if manifest != "" {
+			run.Manifest = &Manifest{}
+			if err := json.Unmarshal([]byte(manifest), run.Manifest); err != nil {
+				return fmt.Errorf("run %s: %w", run.ID, err)
+			}
+		}
+		if err := fn(run); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func scanHistoryEntry(rows *sql.Rows) (HistoryEntry, error) {
+	var e HistoryEntry
+	var eppStatus, checkedAt, createdAt string
+	var durationMs int64
+	if err := rows.Scan(&e.RunID, &e.Domain, &e.Status, &eppStatus, &e.Method, &checkedAt, &durationMs, &e.Error, &e.Registrar, &createdAt); err != nil {
+		return e, err
+	}
+	e.EPPStatus = strings.Fields(eppStatus)
+	e.CheckedAt, _ = time.Parse(time.RFC3339Nano, checkedAt)
+	e.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdAt)
+	e.Duration = time.Duration(durationMs) * time.Millisecond
+	return e, nil
+}
+
+// Result converts the entry back to a check result, as in results files.
+func (e HistoryEntry) Result() DomainResult {
+	result := DomainResult{
+		Domain:    e.Domain,
+		Status:    e.Status,
+		EPPStatus: e.EPPStatus,
+		Method:    e.Method,
+		CheckedAt: e.CheckedAt,
+		Duration:  e.Duration,
+		RunID:     e.RunID,
+		Registrar: e.Registrar,
+		CreatedAt: e.CreatedAt,
+	}
+	if e.Error != "" {
+		result.Error = errors.New(e.Error)
+	}
+	return result
+}
+
+// LatestVerdicts returns the latest check of every domain that had a
+// verdict, skipping later checks that failed.
+func (h *HistoryStore) LatestVerdicts() ([]DomainResult, error) {
+	rows, err := h.db.Query(
+		`SELECT run_id, domain, status, epp_status, method, checked_at, duration_ms, error, registrar, created_at
+		 FROM checks ORDER BY domain, checked_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("reading history: %w", err)
+	}
+	defer rows.Close()
+
+	var results []DomainResult
+	for rows.Next() {
+		e, err := scanHistoryEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("reading history: %w", err)
+		}
+		if !e.Status.IsVerdict() {
+			continue
+		}
+		if n := len(results); n > 0 && results[n-1].Domain == e.Domain {
+			results[n-1] = e.Result()
+		} else {
+			results = append(results, e.Result())
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading history: %w", err)
+	}
+	return results, nil
+}
+
+// Registration is a domain that was seen available and then taken.
+type Registration struct {
+	Domain string
+	// Available is the last check that found the domain available, Taken
+	// the first that found it taken.
+	Available HistoryEntry
+	Taken     HistoryEntry
+	// Registrar and CreatedAt come from the latest check that parsed them.
+	Registrar string
+	CreatedAt time.Time
+}
+
+// Registrations returns the domains that went from available to taken at
+// or after since, in the order they were found taken. Checks without a
+// verdict in between are skipped.
+func (h *HistoryStore) Registrations(since time.Time) ([]Registration, error) {
+	rows, err := h.db.Query(
+		`SELECT run_id, domain, status, epp_status, method, checked_at, duration_ms, error, registrar, created_at
+		 FROM checks ORDER BY domain, checked_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("reading history: %w", err)
+	}
+	defer rows.Close()
+
+	var registrations []Registration
+	var last HistoryEntry
+	current := -1
+	for rows.Next() {
+		e, err := scanHistoryEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("reading history: %w", err)
+		}
+		if e.Domain != last.Domain {
+			last, current = HistoryEntry{Domain: e.Domain}, -1
+		}
+		if !e.Status.IsVerdict() {
+			continue
+		}
+		switch {
+		case last.Status == StatusAvailable && e.Status == StatusTaken && !e.CheckedAt.Before(since):
+			registrations = append(registrations, Registration{Domain: e.Domain, Available: last, Taken: e})
+			current = len(registrations) - 1
+		case e.Status != StatusTaken:
+			current = -1
+		}
+		if current >= 0 {
+			if e.Registrar != "" {
+				registrations[current].Registrar = e.Registrar
+			}
+			if !e.CreatedAt.IsZero() {
+				registrations[current].CreatedAt = e.CreatedAt
+			}
+		}
+		last = e
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading history: %w", err)
+	}
+	sort.SliceStable(registrations, func(i, j int) bool {
+		return registrations[i].Taken.CheckedAt.Before(registrations[j].Taken.CheckedAt)
+	})
+	return registrations, nil
+}
+
+// Vacuum checks the database for corruption and rebuilds it to reclaim the
+// space of deleted rows.
+func (h *HistoryStore) Vacuum() error {
+	var integrity string
+	if err := h.db.QueryRow(`PRAGMA integrity_check`).Scan(&integrity); err != nil {
+		return fmt.Errorf("checking history database: %w", err)
+	}
+	if integrity != "ok" {
+		return fmt.Errorf("history database is damaged: %s; export what is readable with 'history export'", integrity)
+	}
+	if _, err := h.db.Exec(`VACUUM`); err != nil {
+		return fmt.Errorf("vacuuming history database: %w", err)
+	}
+	return nil
+}
+
+func (h *HistoryStore) LoadMonitorState() (*MonitorState, error) {
+	state := &MonitorState{Domains: map[string]DomainState{}}
+
+	rows, err := h.db.Query(`SELECT domain, state FROM monitor_state`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var domain, data string
+		if err := rows.Scan(&domain, &data); err != nil {
+			return nil, err
+		}
+		var ds DomainState
+		if err := json.Unmarshal([]byte(data), &ds); err != nil {
+			return nil, fmt.Errorf("parsing monitor state of %s: %w", domain, err)
+		}
+		state.Domains[domain] = ds
+	}
+	return state, rows.Err()
+}
+
+func (h *HistoryStore) SaveMonitorState(state *MonitorState) error {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return err
+	}
+	for domain, ds := range state.Domains {
+		data, err := json.Marshal(ds)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		_, err = tx.Exec(
+			`INSERT INTO monitor_state (domain, state) VALUES (?, ?)
+			 ON CONFLICT (domain) DO UPDATE SET state = excluded.state`,
+			domain, string(data))
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("saving monitor state: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+func runHistory(args []string) int {
+	usage := func() {
+		fmt.Fprintf(os.Stderr, "Domain Checker - Maintain the history database\n\n")
+		fmt.Fprintf(os.Stderr, "Usage:\n")
+		fmt.Fprintf(os.Stderr, "  %s history export -history=checks.db [-o archive.ndjson] [-runs runs.ndjson]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s history vacuum -history=checks.db\n\n", os.Args[0])
+	}
+
+	if len(args) == 0 {
+		usage()
+		return 1
+	}
+
+	fs := flag.NewFlagSet("history "+args[0], flag.ExitOnError)
+	historyPath := fs.String("history", "", "SQLite history database written by -history (required)")
+	var output, runsOutput *string
+	switch args[0] {
+	case "export":
+		output = fs.String("o", "", "Write the checks to this file instead of stdout")
+		runsOutput = fs.String("runs", "", "Write the runs (times, whois queries, manifest) to this file as ndjson (default with -o: the -o file with .runs.ndjson for its extension)")
+	case "vacuum":
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown history command %q\n\n", args[0])
+		usage()
+		return 1
+	}
+	fs.Parse(args[1:])
+	if *historyPath == "" || fs.NArg() > 0 {
+		usage()
+		fs.PrintDefaults()
+		return 1
+	}
+
+	store, err := OpenHistory(*historyPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer store.Close()
+
+	if args[0] == "vacuum" {
+		if err := store.Vacuum(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "%s is intact and vacuumed\n", *historyPath)
+		return 0
+	}
+
+	exported := 0
+	err = exportNDJSON(*output, func(enc *json.Encoder) error {
+		return store.Export(func(e HistoryEntry) error {
+			exported++
+			return enc.Encode(e.Result())
+		})
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: exporting history: %v\n", err)
+		return 1
+	}
+	// The runs go to a file of their own, so the checks stay readable by
+	// the results commands.
+	if *runsOutput == "" && *output != "" {
+		*runsOutput = strings.TrimSuffix(*output, filepath.Ext(*output)) + ".runs.ndjson"
+	}
+	runs := 0
+	if *runsOutput != "" {
+		err = exportNDJSON(*runsOutput, func(enc *json.Encoder) error {
+			return store.ExportRuns(func(run HistoryRun) error {
+				runs++
+				return enc.Encode(run)
+			})
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: exporting history: %v\n", err)
+			return 1
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d checks and %d runs\n", exported, runs)
+	return 0
+}
+
+// exportNDJSON runs fn with an encoder writing to path, or to stdout when
+// path is empty.
+func exportNDJSON(path string, fn func(*json.Encoder) error) error {
+	out := os.Stdout
+	if path != "" {
+		var err error
+		if out, err = os.Create(path); err != nil {
+			return err
+		}
+		defer out.Close()
+	}
+	w := bufio.NewWriter(out)
+	if err := fn(json.NewEncoder(w)); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if path != "" {
+		return out.Close()
+	}
+	return nil
+}
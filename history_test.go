@@ -0,0 +1,107 @@
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// createHistoryV1 writes a database as the first schema left it: only the
+// checks table, with one check in it.
+func createHistoryV1(t *testing.T, path string, versionTable bool) {
+	t.Helper()
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	statements := []string{historyMigrations[0]}
+	if versionTable {
+		statements = append(statements, `CREATE TABLE schema_version (version INTEGER NOT NULL); INSERT INTO schema_version (version) VALUES (1)`)
+	} else {
+		statements = append(statements, `PRAGMA user_version = 1`)
+	}
+	statements = append(statements,
+		`INSERT INTO checks (run_id, domain, status, method, checked_at) VALUES ('old', 'example.com', 'taken', 'whois', '2024-01-02T03:04:05Z')`)
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestHistoryMigratesV1(t *testing.T) {
+	for _, tt := range []struct {
+		name         string
+		versionTable bool
+	}{
+		{"schema_version", true},
+		{"user_version", false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "checks.db")
+			createHistoryV1(t, path, tt.versionTable)
+
+			store, err := OpenHistory(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer store.Close()
+
+			version, err := store.schemaVersion()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if version != len(historyMigrations) {
+				t.Errorf("schema version = %d, want %d", version, len(historyMigrations))
+			}
+
+			// The check written by v1 survives, and the columns and tables
+			// of later versions are usable.
+			entries, err := store.Timeline("example.com")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 || entries[0].Status != StatusTaken || entries[0].Registrar != "" {
+				t.Fatalf("timeline after migration = %+v", entries)
+			}
+			run := RunInfo{ID: "new", StartedAt: time.Now(), FinishedAt: time.Now()}
+			if err := store.Record(run, []DomainResult{{Domain: "example.com", Status: StatusTaken, Registrar: "Example Registrar", CheckedAt: time.Now()}}); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.SetWhoisQueries("new", 3); err != nil {
+				t.Fatal(err)
+			}
+			var runs []HistoryRun
+			if err := store.ExportRuns(func(r HistoryRun) error {
+				runs = append(runs, r)
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if len(runs) != 1 || runs[0].ID != "new" || runs[0].WhoisQueries != 3 {
+				t.Errorf("runs = %+v", runs)
+			}
+		})
+	}
+}
+
+func TestHistoryRefusesNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checks.db")
+	store, err := OpenHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.db.Exec(`UPDATE schema_version SET version = ?`, len(historyMigrations)+1); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	_, err = OpenHistory(path)
+	if err == nil || !strings.Contains(err.Error(), "newer than this binary supports") {
+		t.Errorf("OpenHistory of a newer schema: err = %v", err)
+	}
+}
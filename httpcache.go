@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// httpCacheMaxBytes caps the responses the HTTP cache keeps on disk; the
+// least recently used go first once it is reached.
+const httpCacheMaxBytes = 64 << 20
+
+// httpCache is a disk-backed cache of the RDAP client's GET responses, kept
+// apart from the verdict cache. It honours Cache-Control and Expires, and
+// revalidates stale responses with their ETag or Last-Modified.
+type httpCache struct {
+	dir      string
+	maxBytes int64
+	next     http.RoundTripper
+
+	mu sync.Mutex
+	// Hits were answered from disk, Revalidated by a 304 and Fetched by a
+	// full response.
+	Hits, Revalidated, Fetched int
+}
+
+// httpCacheEntry is a stored response.
+type httpCacheEntry struct {
+	URL      string      `json:"url"`
+	Status   int         `json:"status"`
+	Header   http.Header `json:"header"`
+	Body     []byte      `json:"body"`
+	StoredAt time.Time   `json:"storedAt"`
+}
+
+func httpCacheDir() string {
+	dir := cacheDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "http")
+}
+
+func newHTTPCache(dir string, next http.RoundTripper) *httpCache {
+	return &httpCache{dir: dir, maxBytes: httpCacheMaxBytes, next: next}
+}
+
+func (c *httpCache) path(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (c *httpCache) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Authorization") != "" {
+		return c.next.RoundTrip(req)
+	}
+	url := req.URL.String()
+	entry, _ := c.load(url)
+	if entry != nil && entry.fresh(time.Now()) {
+		c.count(&c.Hits)
+		debugLog.Printf("http cache: hit %s", url)
+		return entry.response(req), nil
+	}
+
+	// The caller's own validators win; otherwise those of the stored
+	// response make the request conditional.
+	conditional := req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != ""
+	if entry != nil && !conditional {
+		req = req.Clone(req.Context())
+		if etag := entry.Header.Get("ETag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if modified := entry.Header.Get("Last-Modified"); modified != "" {
+			req.Header.Set("If-Modified-Since", modified)
+		}
+	}
+	resp, err := c.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && entry != nil:
+		c.count(&c.Revalidated)
+		debugLog.Printf("http cache: revalidated %s", url)
+		for _, name := range []string{"Cache-Control", "Expires", "Date", "ETag", "Last-Modified"} {
+			if value := resp.Header.Get(name); value != "" {
+				entry.Header.Set(name, value)
+			}
+		}
+		entry.StoredAt = time.Now()
+		c.store(entry)
+		if conditional {
+			return resp, nil
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return entry.response(req), nil
+	case resp.StatusCode == http.StatusOK && cacheable(resp.Header):
+		c.count(&c.Fetched)
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		c.store(&httpCacheEntry{URL: url, Status: resp.StatusCode, Header: resp.Header, Body: body, StoredAt: time.Now()})
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		return resp, nil
+	}
+	return resp, nil
+}
+
+func (c *httpCache) count(n *int) {
+	c.mu.Lock()
+	*n++
+	c.mu.Unlock()
+}
+
+// Summary gives the counters for the debug log.
+func (c *httpCache) Summary() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return fmt.Sprintf("http cache: %d hits, %d revalidated, %d fetched", c.Hits, c.Revalidated, c.Fetched)
+}
+
+func (c *httpCache) load(url string) (*httpCacheEntry, error) {
+	path := c.path(url)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entry httpCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.URL != url {
+		return nil, fmt.Errorf("http cache entry %s is damaged", path)
+	}
+	// The modification time orders entries for eviction.
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return &entry, nil
+}
+
+// store writes the entry and evicts the least recently used ones over the
+// size cap. Failures only cost the cache.
+func (c *httpCache) store(entry *httpCacheEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil || int64(len(data)) > c.maxBytes {
+		return
+	}
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		debugLog.Printf("http cache: %v", err)
+		return
+	}
+	if err := writeFileAtomic(c.path(entry.URL), data); err != nil {
+		debugLog.Printf("http cache: %v", err)
+		return
+	}
+	c.evict()
+}
+
+func (c *httpCache) evict() {
+	dirEntries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	type file struct {
+		path    string
+		size    int64
+		touched time.Time
+	}
+	var files []file
+	var total int64
+	for _, e := range dirEntries {
+		info, err := e.Info()
+		if err != nil || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		files = append(files, file{filepath.Join(c.dir, e.Name()), info.Size(), info.ModTime()})
+		total += info.Size()
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].touched.Before(files[j].touched) })
+	for _, f := range files {
+		if total <= c.maxBytes {
+			break
+		}
+		if os.Remove(f.path) == nil {
+			total -= f.size
+			debugLog.Printf("http cache: evicted %s", filepath.Base(f.path))
+		}
+	}
+}
+
+// cacheable reports whether a response may be stored.
+func cacheable(header http.Header) bool {
+	directives := cacheControl(header)
+	_, noStore := directives["no-store"]
+	_, private := directives["private"]
+	return !noStore && !private
+}
+
+// fresh reports whether the entry may be served without asking the server.
+func (e *httpCacheEntry) fresh(now time.Time) bool {
+	directives := cacheControl(e.Header)
+	if _, ok := directives["no-cache"]; ok {
+		return false
+	}
+	if maxAge, ok := directives["max-age"]; ok {
+		seconds, err := strconv.Atoi(maxAge)
+		return err == nil && now.Sub(e.StoredAt) < time.Duration(seconds)*time.Second
+	}
+	if expires, err := http.ParseTime(e.Header.Get("Expires")); err == nil {
+		return now.Before(expires)
+	}
+	return false
+}
+
+func (e *httpCacheEntry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.Status, http.StatusText(e.Status)),
+		StatusCode:    e.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// cacheControl parses the Cache-Control header into its directives, with
+// lowercase names and unquoted values.
+func cacheControl(header http.Header) map[string]string {
+	directives := map[string]string{}
+	for _, value := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if name != "" {
+				directives[strings.ToLower(name)] = strings.Trim(arg, `"`)
+			}
+		}
+	}
+	return directives
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestHTTPCache(t *testing.T) {
+	var requests, notModified atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch r.URL.Path {
+		case "/fresh":
+			w.Header().Set("Cache-Control", "public, max-age=3600")
+		case "/stale":
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				notModified.Add(1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		case "/private":
+			w.Header().Set("Cache-Control", "no-store")
+		}
+		io.WriteString(w, "body of "+r.URL.Path)
+	}))
+	defer server.Close()
+
+	cache := newHTTPCache(t.TempDir(), http.DefaultTransport)
+	client := &http.Client{Transport: cache}
+	get := func(path string) string {
+		t.Helper()
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s: %s", path, resp.Status)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	for _, path := range []string{"/fresh", "/stale", "/private"} {
+		for i := 0; i < 2; i++ {
+			if body := get(path); body != "body of "+path {
+				t.Errorf("GET %s #%d = %q", path, i+1, body)
+			}
+		}
+	}
+	// /fresh is asked once, /stale twice with a 304 the second time, and
+	// /private every time.
+	if n := requests.Load(); n != 5 {
+		t.Errorf("server got %d requests, want 5", n)
+	}
+	if n := notModified.Load(); n != 1 {
+		t.Errorf("server answered %d revalidations, want 1", n)
+	}
+	if cache.Hits != 1 || cache.Revalidated != 1 {
+		t.Errorf("%s", cache.Summary())
+	}
+}
+
+func TestHTTPCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=3600")
+		w.Write(make([]byte, 400))
+	}))
+	defer server.Close()
+
+	cache := newHTTPCache(t.TempDir(), http.DefaultTransport)
+	// Room for two entries of about 800 bytes each.
+	cache.maxBytes = 2000
+	client := &http.Client{Transport: cache}
+	for _, path := range []string{"/a", "/b", "/a", "/c"} {
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	// /b was used least recently when /c arrived.
+	for path, want := range map[string]bool{"/a": true, "/b": false, "/c": true} {
+		entry, _ := cache.load(server.URL + path)
+		if (entry != nil) != want {
+			t.Errorf("%s cached = %v, want %v", path, entry != nil, want)
+		}
+	}
+}
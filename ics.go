@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// writeICS writes a calendar with an all-day event at the expiry date of
+// every taken domain and, when dropDays > 0, another one dropDays later when
+// the domain is likely to be released. It returns how many taken domains had
+// no known expiry date.
+func writeICS(w io.Writer, results []DomainResult, dropDays int) (int, error) {
+	var b strings.Builder
+	b.WriteString("BEGIN:VCALENDAR\r\n")
+	b.WriteString("VERSION:2.0\r\n")
+	b.WriteString("PRODID:-//botsman//domain-checker//EN\r\n")
+	b.WriteString("CALSCALE:GREGORIAN\r\n")
+
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	skipped := 0
+	for _, result := range results {
+		if result.Status != StatusTaken {
+			continue
+		}
+		if result.ExpiresAt.IsZero() {
+			skipped++
+			continue
+		}
+
+		description := icsDescription(result)
+		writeICSEvent(&b, "expiry-"+result.Domain, stamp, result.ExpiresAt,
+			result.Domain+" expires", description)
+		if dropDays > 0 {
+			writeICSEvent(&b, "drop-"+result.Domain, stamp, result.ExpiresAt.AddDate(0, 0, dropDays),
+				result.Domain+" may drop", description)
+		}
+	}
+
+	b.WriteString("END:VCALENDAR\r\n")
+	_, err := io.WriteString(w, b.String())
+	return skipped, err
+}
+
+func icsDescription(result DomainResult) string {
+	registrar := result.Registrar
+	if registrar == "" {
+		registrar = "unknown"
+	}
+	epp := strings.Join(result.EPPStatus, ", ")
+	if epp == "" {
+		epp = "unknown"
+	}
+	return fmt.Sprintf("Registrar: %s\nEPP status: %s\nExpires: %s",
+		registrar, epp, displayTime(result.ExpiresAt, "2006-01-02"))
+}
+
+func writeICSEvent(b *strings.Builder, uid, stamp string, day time.Time, summary, description string) {
+	b.WriteString("BEGIN:VEVENT\r\n")
+	writeICSLine(b, "UID:"+uid+"@domain-checker")
+	writeICSLine(b, "DTSTAMP:"+stamp)
+	// All-day events fall on the date of the -timezone zone.
+	day = day.In(displayZone)
+	writeICSLine(b, "DTSTART;VALUE=DATE:"+day.Format("20060102"))
+	writeICSLine(b, "DTEND;VALUE=DATE:"+day.AddDate(0, 0, 1).Format("20060102"))
+	writeICSLine(b, "SUMMARY:"+icsEscape(summary))
+	writeICSLine(b, "DESCRIPTION:"+icsEscape(description))
+	b.WriteString("END:VEVENT\r\n")
+}
+
+// writeICSLine folds lines longer than 75 octets as RFC 5545 requires,
+// without splitting UTF-8 sequences.
+func writeICSLine(b *strings.Builder, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = 74 // continuation lines start with a space
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+func icsEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+func writeICSFile(path string, results []DomainResult, dropDays int) (int, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, fmt.Errorf("writing calendar file: %w", err)
+	}
+	skipped, err := writeICS(file, results, dropDays)
+	if err != nil {
+		file.Close()
+		return 0, fmt.Errorf("writing calendar file: %w", err)
+	}
+	return skipped, file.Close()
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit reports each domain as a test case that passes when the domain
+// has the expected status (available unless -expect says otherwise).
+func writeJUnit(w io.Writer, report *Report, opts RenderOptions) error {
+	expected := opts.Expect
+	if expected == "" {
+		expected = StatusAvailable
+	}
+
+	name := "domain-checker"
+	if opts.Title != "" {
+		name += " " + opts.Title
+	}
+	suite := junitTestSuite{
+		Name:  name,
+		Tests: len(report.Results),
+		Time:  fmt.Sprintf("%.3f", report.FinishedAt.Sub(report.StartedAt).Seconds()),
+	}
+	if !report.StartedAt.IsZero() {
+		suite.Timestamp = report.StartedAt.UTC().Format("2006-01-02T15:04:05")
+	}
+
+	for _, result := range report.Results {
+		tc := junitTestCase{
+			Name:      result.Domain,
+			ClassName: "domain-checker." + domainTLD(result.Domain),
+			Time:      fmt.Sprintf("%.3f", result.Duration.Seconds()),
+		}
+		switch {
+		case result.Status == StatusError:
+			suite.Errors++
+			tc.Error = &junitMessage{
+				Message: fmt.Sprintf("check failed: %v", result.Error),
+				Type:    string(categorizeError(result.Error)),
+				Text:    fmt.Sprint(result.Error),
+			}
+		case result.Status == StatusUnchecked:
+			suite.Skipped++
+			tc.Skipped = &junitMessage{Message: "not in cache", Type: string(result.Status)}
+		case result.Status != expected:
+			suite.Failures++
+			reason := describeResult(result)
+			tc.Failure = &junitMessage{
+				Message: fmt.Sprintf("expected %s, got %s", expected, reason),
+				Type:    string(result.Status),
+				Text:    reason,
+			}
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// describeResult is the status of a result with whatever registration
+// details were found.
+func describeResult(result DomainResult) string {
+	var details []string
+	if result.Registrar != "" {
+		details = append(details, "registrar "+result.Registrar)
+	}
+	if !result.CreatedAt.IsZero() {
+		details = append(details, "created "+result.CreatedAt.Format("2006-01-02"))
+	}
+	if len(result.EPPStatus) > 0 {
+		details = append(details, "status "+strings.Join(result.EPPStatus, " "))
+	}
+	if result.Server != "" {
+		details = append(details, "server "+result.Server)
+	}
+	if len(details) == 0 {
+		return string(result.Status)
+	}
+	return fmt.Sprintf("%s (%s)", result.Status, strings.Join(details, ", "))
+}
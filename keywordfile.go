@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// keywordEntry is a keyword read from a keyword file with its annotations.
+type keywordEntry struct {
+	Word     string
+	Priority int
+	// Lists names the lists of -keywords-file the keyword belongs to;
+	// empty for every list.
+	Lists []string
+}
+
+// readKeywordFile reads a keyword file. Each line holds one keyword, which
+// may be followed by annotations:
+//
+//	cloud                       a plain keyword
+//	stack  # maybe too generic  text after # is a comment
+//	!fast                       disabled, skipped until the ! is removed
+//	get @priority=2 @lists=prefix
+//
+// An unknown annotation is ignored with a warning naming its line.
+func readKeywordFile(path string) (entries []keywordEntry, warnings []string, err error) {
+	file, err := openInput(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading keyword file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(text)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "!") {
+			continue
+		}
+
+		entry := keywordEntry{Word: fields[0]}
+		for _, field := range fields[1:] {
+			key, value, ok := strings.Cut(strings.TrimPrefix(field, "@"), "=")
+			if !strings.HasPrefix(field, "@") || !ok {
+				return nil, nil, fmt.Errorf("keyword file %s line %d: %q is not an annotation (one keyword per line, annotations as @name=value)", path, line, field)
+			}
+			switch key {
+			case "priority":
+				n, err := strconv.Atoi(value)
+				if err != nil {
+					return nil, nil, fmt.Errorf("keyword file %s line %d: @priority must be a whole number, got %q", path, line, value)
+				}
+				entry.Priority = n
+			case "lists":
+				for _, list := range parseKeywords(value) {
+					if !isListLabel(list) {
+						return nil, nil, fmt.Errorf("keyword file %s line %d: invalid list name %q", path, line, list)
+					}
+					entry.Lists = append(entry.Lists, list)
+				}
+			default:
+				warnings = append(warnings, fmt.Sprintf("keyword file %s line %d: unknown annotation @%s ignored (use @priority or @lists)", path, line, key))
+			}
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("reading keyword file: %w", err)
+	}
+	return entries, warnings, nil
+}
+
+// keywordFileLists arranges the entries of -keywords-file as keyword
+// lists. Without @lists they form a single list, as -keywords does; with
+// it, every list named becomes a list of -lists in order of first mention,
+// and keywords without @lists join all of them.
+func keywordFileLists(entries []keywordEntry) (lists [][]string, labels []string) {
+	for _, entry := range entries {
+		for _, list := range entry.Lists {
+			if !slices.Contains(labels, list) {
+				labels = append(labels, list)
+			}
+		}
+	}
+	if len(labels) == 0 {
+		var keywords []string
+		for _, entry := range entries {
+			keywords = append(keywords, entry.Word)
+		}
+		return [][]string{keywords}, nil
+	}
+
+	lists = make([][]string, len(labels))
+	for _, entry := range entries {
+		for i, label := range labels {
+			if len(entry.Lists) == 0 || slices.Contains(entry.Lists, label) {
+				lists[i] = append(lists[i], entry.Word)
+			}
+		}
+	}
+	return lists, labels
+}
+
+// listFileLabel names the list of a -lists-files file after the file,
+// e.g. prefix for lists/prefix.txt.
+func listFileLabel(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if !isListLabel(name) {
+		return ""
+	}
+	return name
+}
+
+// readListFiles reads -lists-files: one keyword list per file, named after
+// the file. @lists only means something in -keywords-file and is ignored
+// here with a warning.
+func readListFiles(paths []string) (lists [][]string, labels []string, entries []keywordEntry, warnings []string, err error) {
+	named := false
+	for _, path := range paths {
+		fileEntries, fileWarnings, err := readKeywordFile(path)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		warnings = append(warnings, fileWarnings...)
+		var keywords []string
+		for _, entry := range fileEntries {
+			if len(entry.Lists) > 0 {
+				warnings = append(warnings, fmt.Sprintf("keyword file %s: @lists of %s ignored, the file is a list of its own", path, entry.Word))
+			}
+			keywords = append(keywords, entry.Word)
+		}
+		if len(keywords) == 0 {
+			continue
+		}
+		label := listFileLabel(path)
+		named = named || label != ""
+		lists = append(lists, keywords)
+		labels = append(labels, label)
+		entries = append(entries, fileEntries...)
+	}
+	if !named {
+		labels = nil
+	}
+	return lists, labels, entries, warnings, nil
+}
+
+// keywordPriorities maps the keywords given an @priority to it.
+func keywordPriorities(entries []keywordEntry) map[string]int {
+	priorities := map[string]int{}
+	for _, entry := range entries {
+		if entry.Priority != 0 {
+			priorities[entry.Word] = entry.Priority
+		}
+	}
+	return priorities
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeKeywordFile writes content to name in a temporary directory and
+// returns its path.
+func writeKeywordFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadKeywordFile(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		content  string
+		entries  []keywordEntry
+		warnings []string
+	}{
+		{
+			name:    "plain",
+			content: "cloud\nstack\nfast\n",
+			entries: []keywordEntry{{Word: "cloud"}, {Word: "stack"}, {Word: "fast"}},
+		},
+		{
+			name:    "plain without final newline",
+			content: "cloud\nstack",
+			entries: []keywordEntry{{Word: "cloud"}, {Word: "stack"}},
+		},
+		{
+			name:    "crlf and blank lines",
+			content: "cloud\r\n\r\n   \r\n\tstack  \r\n",
+			entries: []keywordEntry{{Word: "cloud"}, {Word: "stack"}},
+		},
+		{
+			name:    "empty",
+			content: "",
+		},
+		{
+			name:    "comments",
+			content: "# keywords for the launch\ncloud  # maybe too generic\nstack# no space\n   # indented comment\n#fast\n",
+			entries: []keywordEntry{{Word: "cloud"}, {Word: "stack"}},
+		},
+		{
+			name:    "disabled",
+			content: "!cloud\nstack\n!fast @priority=3\n! get\n",
+			entries: []keywordEntry{{Word: "stack"}},
+		},
+		{
+			name:    "annotations",
+			content: "get @priority=2 @lists=prefix\ncloud @lists=prefix,suffix\nstack @priority=-1  # last\nfast\t@priority=0\n",
+			entries: []keywordEntry{
+				{Word: "get", Priority: 2, Lists: []string{"prefix"}},
+				{Word: "cloud", Lists: []string{"prefix", "suffix"}},
+				{Word: "stack", Priority: -1},
+				{Word: "fast"},
+			},
+		},
+		{
+			name:    "unknown annotations",
+			content: "cloud\nstack @weight=3\nfast @priority=1 @Lists=prefix\n",
+			entries: []keywordEntry{{Word: "cloud"}, {Word: "stack"}, {Word: "fast", Priority: 1}},
+			warnings: []string{
+				"line 2: unknown annotation @weight ignored",
+				"line 3: unknown annotation @Lists ignored",
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeKeywordFile(t, "keywords.txt", tt.content)
+			entries, warnings, err := readKeywordFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(entries, tt.entries) {
+				t.Errorf("entries = %+v, want %+v", entries, tt.entries)
+			}
+			if len(warnings) != len(tt.warnings) {
+				t.Fatalf("warnings = %q, want %q", warnings, tt.warnings)
+			}
+			for i, want := range tt.warnings {
+				if !strings.Contains(warnings[i], path+" "+want) {
+					t.Errorf("warning %d = %q, want it to contain %q", i, warnings[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestReadKeywordFileErrors(t *testing.T) {
+	for _, tt := range []struct{ content, err string }{
+		{"cloud stack\n", `line 1: "stack" is not an annotation`},
+		{"cloud\nget @priority\n", `line 2: "@priority" is not an annotation`},
+		{"cloud priority=2\n", `line 1: "priority=2" is not an annotation`},
+		{"cloud\n\nget @priority=high\n", `line 3: @priority must be a whole number, got "high"`},
+		{"get @priority=1.5\n", `line 1: @priority must be a whole number, got "1.5"`},
+		{"get @lists=pre.fix\n", `line 1: invalid list name "pre.fix"`},
+	} {
+		path := writeKeywordFile(t, "keywords.txt", tt.content)
+		_, _, err := readKeywordFile(path)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("readKeywordFile(%q): err = %v, want %q", tt.content, err, tt.err)
+		}
+	}
+
+	if _, _, err := readKeywordFile(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("readKeywordFile of a missing file: no error")
+	}
+}
+
+func TestKeywordFileLists(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		entries []keywordEntry
+		lists   [][]string
+		labels  []string
+	}{
+		{
+			name:    "no lists",
+			entries: []keywordEntry{{Word: "cloud"}, {Word: "stack", Priority: 2}},
+			lists:   [][]string{{"cloud", "stack"}},
+		},
+		{
+			name: "named lists",
+			entries: []keywordEntry{
+				{Word: "get", Lists: []string{"prefix"}},
+				{Word: "cloud"},
+				{Word: "hq", Lists: []string{"suffix"}},
+				{Word: "my", Lists: []string{"prefix", "suffix"}},
+			},
+			lists:  [][]string{{"get", "cloud", "my"}, {"cloud", "hq", "my"}},
+			labels: []string{"prefix", "suffix"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			lists, labels := keywordFileLists(tt.entries)
+			if !reflect.DeepEqual(lists, tt.lists) || !reflect.DeepEqual(labels, tt.labels) {
+				t.Errorf("keywordFileLists = %q, %q; want %q, %q", lists, labels, tt.lists, tt.labels)
+			}
+		})
+	}
+}
+
+func TestReadListFiles(t *testing.T) {
+	prefix := writeKeywordFile(t, "prefix.txt", "get\ntry @priority=2\n")
+	suffix := writeKeywordFile(t, "suffix.txt", "hq @lists=other\n# nothing else\n")
+	empty := writeKeywordFile(t, "empty.txt", "!unused\n")
+
+	lists, labels, entries, warnings, err := readListFiles([]string{prefix, empty, suffix})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]string{{"get", "try"}, {"hq"}}; !reflect.DeepEqual(lists, want) {
+		t.Errorf("lists = %q, want %q", lists, want)
+	}
+	if want := []string{"prefix", "suffix"}; !reflect.DeepEqual(labels, want) {
+		t.Errorf("labels = %q, want %q", labels, want)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "@lists of hq ignored") {
+		t.Errorf("warnings = %q", warnings)
+	}
+	if want := map[string]int{"try": 2}; !reflect.DeepEqual(keywordPriorities(entries), want) {
+		t.Errorf("priorities = %v, want %v", keywordPriorities(entries), want)
+	}
+
+	// Files without a usable name give unnamed lists.
+	unnamed := writeKeywordFile(t, "my words.txt", "cloud\n")
+	_, labels, _, _, err = readListFiles([]string{unnamed})
+	if err != nil || labels != nil {
+		t.Errorf("labels of %s = %q, %v; want none", unnamed, labels, err)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// KeywordStat tells how often names built from one keyword were available.
+// Percent is of the checked names, so errors do not drag it down.
+type KeywordStat struct {
+	// List is the name of the -lists list the keyword came from; the same
+	// word in two named lists is counted once per list.
+	List      string  `json:"list,omitempty"`
+	Keyword   string  `json:"keyword"`
+	Checked   int     `json:"checked"`
+	Available int     `json:"available"`
+	Percent   float64 `json:"percent"`
+}
+
+// keywordStats ranks the keywords of generated names by the share of their
+// names that came back available, best first. Keywords of named lists are
+// grouped by list, in the order the lists were given. Explicit domains and
+// suggestions carry no keywords and are left out.
+func keywordStats(results []DomainResult) []KeywordStat {
+	type key struct{ list, keyword string }
+	byKeyword := map[key]*KeywordStat{}
+	listOrder := map[string]int{}
+	for _, result := range results {
+		if result.Candidate == nil || !result.Status.IsVerdict() {
+			continue
+		}
+		seen := map[key]bool{}
+		for i, keyword := range result.Candidate.Keywords {
+			k := key{keyword: keyword}
+			if i < len(result.Candidate.Lists) {
+				k.list = result.Candidate.Lists[i]
+				if _, ok := listOrder[k.list]; !ok {
+					listOrder[k.list] = i
+				}
+			}
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			s, ok := byKeyword[k]
+			if !ok {
+				s = &KeywordStat{List: k.list, Keyword: keyword}
+				byKeyword[k] = s
+			}
+			s.Checked++
+			if result.Status == StatusAvailable {
+				s.Available++
+			}
+		}
+	}
+
+	stats := make([]KeywordStat, 0, len(byKeyword))
+	for _, s := range byKeyword {
+		s.Percent = float64(s.Available) * 100 / float64(s.Checked)
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].List != stats[j].List {
+			return listOrder[stats[i].List] < listOrder[stats[j].List]
+		}
+		if stats[i].Percent != stats[j].Percent {
+			return stats[i].Percent > stats[j].Percent
+		}
+		if stats[i].Available != stats[j].Available {
+			return stats[i].Available > stats[j].Available
+		}
+		return stats[i].Keyword < stats[j].Keyword
+	})
+	return stats
+}
+
+// writeKeywordStats prints one section per named list, or a single one.
+func writeKeywordStats(w io.Writer, stats []KeywordStat) {
+	width := 0
+	for _, s := range stats {
+		width = max(width, len(s.Keyword))
+	}
+	for start := 0; start < len(stats); {
+		end := start + 1
+		for end < len(stats) && stats[end].List == stats[start].List {
+			end++
+		}
+		if list := stats[start].List; list != "" {
+			fmt.Fprintf(w, "KEYWORDS OF %s BY AVAILABILITY (%d):\n", strings.ToUpper(list), end-start)
+		} else {
+			fmt.Fprintf(w, "KEYWORDS BY AVAILABILITY (%d):\n", end-start)
+		}
+		for _, s := range stats[start:end] {
+			fmt.Fprintf(w, "  %-*s  %3.0f%%  %d/%d available\n", width, s.Keyword, s.Percent, s.Available, s.Checked)
+		}
+		fmt.Fprintln(w)
+		start = end
+	}
+}
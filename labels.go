@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
+)
+
+// maxLabelLength is the DNS limit for a single label (RFC 1035).
+const maxLabelLength = 63
+
+// InvalidName is a generated domain dropped because its name can never be
+// registered, with the rule it breaks.
+type InvalidName struct {
+	Domain string
+	Rule   string
+}
+
+// validateSeparator refuses separators that make every joined name invalid.
+// Only letters, digits and hyphens may appear in a host name label.
+func validateSeparator(separator string) error {
+	for _, r := range separator {
+		if r != '-' && !isLDH(r) {
+			return fmt.Errorf("separator %q cannot appear in a domain name: labels may only contain letters, digits and hyphens", separator)
+		}
+	}
+	return nil
+}
+
+// parseSeparators reads -separator: comma-separated strings to join the
+// words of a name with, where "none" joins them directly and "both" stands
+// for none and a dash. Separators are validated and deduplicated.
+func parseSeparators(input string) ([]string, error) {
+	var separators []string
+	add := func(separator string) {
+		if !slices.Contains(separators, separator) {
+			separators = append(separators, separator)
+		}
+	}
+	for _, separator := range parseKeywords(strings.ToLower(input)) {
+		switch separator {
+		case "", "none":
+			add("")
+		case "both":
+			add("")
+			add("-")
+		default:
+			if err := validateSeparator(separator); err != nil {
+				return nil, err
+			}
+			add(separator)
+		}
+	}
+	if len(separators) == 0 {
+		separators = []string{""}
+	}
+	return separators, nil
+}
+
+func isLDH(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r > 0x7f
+}
+
+// labelProblem returns the rule label breaks, or "" for a valid label.
+// Non-ASCII labels are checked in their encoded xn-- form.
+func labelProblem(label string) string {
+	if label == "" {
+		return "empty label"
+	}
+	ascii := label
+	if strings.IndexFunc(label, func(r rune) bool { return r > 0x7f }) >= 0 {
+		encoded, err := idna.Registration.ToASCII(label)
+		if err != nil {
+			if long, _ := idna.Punycode.ToASCII(label); len(long) > maxLabelLength {
+				return fmt.Sprintf("longer than %d characters once encoded as %s", maxLabelLength, long)
+			}
+			return fmt.Sprintf("not a valid internationalized name (%v)", err)
+		}
+		ascii = encoded
+	}
+	switch {
+	case len(ascii) > maxLabelLength:
+		return fmt.Sprintf("longer than %d characters", maxLabelLength)
+	case strings.HasPrefix(ascii, "-"):
+		return "starts with a hyphen"
+	case strings.HasSuffix(ascii, "-"):
+		return "ends with a hyphen"
+	}
+	for _, r := range ascii {
+		if r == '_' {
+			return "contains an underscore, which host names do not allow"
+		}
+		if r != '-' && !isLDH(r) {
+			return fmt.Sprintf("contains %q; only letters, digits and hyphens are allowed", r)
+		}
+	}
+	if len(ascii) >= 4 && ascii[2:4] == "--" {
+		if !strings.HasPrefix(strings.ToLower(ascii), "xn--") {
+			return "hyphens in the third and fourth positions are reserved for encoded names"
+		}
+		if ascii == label {
+			// A keyword that happens to start with xn-- must still decode,
+			// or the registry sees a broken punycode name.
+			if _, err := idna.Registration.ToUnicode(label); err != nil {
+				return "starts with the punycode prefix xn-- but is not a valid encoded name"
+			}
+		}
+	}
+	return ""
+}
+
+// dropInvalidNames removes the candidates whose name breaks a label rule.
+func dropInvalidNames(candidates []Candidate) (valid []Candidate, dropped []InvalidName) {
+	problems := map[string]string{}
+	for _, candidate := range candidates {
+		problem, seen := problems[candidate.BaseName]
+		if !seen {
+			problem = labelProblem(candidate.BaseName)
+			problems[candidate.BaseName] = problem
+		}
+		if problem != "" {
+			dropped = append(dropped, InvalidName{Domain: candidate.FQDN, Rule: problem})
+			continue
+		}
+		valid = append(valid, candidate)
+	}
+	return valid, dropped
+}
+
+// keywordName strips what makes a keyword a domain rather than a name:
+// myapp.com and www.myapp.co.uk become myapp, and the dots of a keyword
+// without a known suffix, like node.js, are dropped. It returns "" when no
+// name is left, as for .com.
+func keywordName(keyword string) string {
+	if !strings.Contains(keyword, ".") {
+		return keyword
+	}
+	name := strings.Trim(keyword, ".")
+	lower := strings.ToLower(name)
+	suffix, icann := publicsuffix.PublicSuffix(lower)
+	if icann && suffix == lower {
+		// A bare suffix such as co.uk names nothing.
+		return ""
+	}
+	if icann {
+		name = name[:len(name)-len(suffix)-1]
+		// Only the label left of the suffix names the site.
+		return name[strings.LastIndexByte(name, '.')+1:]
+	}
+	return strings.ReplaceAll(name, ".", "")
+}
+
+// duplicatedSuffix returns domain without its last public suffix when what
+// is left already ends in one, as in myapp.com.com or myapp.io.com.
+func duplicatedSuffix(domain string) (string, bool) {
+	suffix, icann := publicsuffix.PublicSuffix(domain)
+	if !icann || suffix == domain {
+		return domain, false
+	}
+	rest := strings.TrimSuffix(domain, "."+suffix)
+	if inner, icann := publicsuffix.PublicSuffix(rest); !icann || inner == rest {
+		return domain, false
+	}
+	return rest, true
+}
+
+// isInvisible reports whether r is left in text pasted from documents
+// without being part of a name: a Unicode space such as the no-break space,
+// a format character such as the zero-width joiner or the byte order mark,
+// or a quote, typographic or plain. A plain space is visible, and left for
+// the label rules to refuse.
+func isInvisible(r rune) bool {
+	if r == ' ' {
+		return false
+	}
+	return unicode.IsSpace(r) || unicode.In(r, unicode.Zs, unicode.Cf, unicode.Pi, unicode.Pf) ||
+		r == '"' || r == '\''
+}
+
+// cleanInvisible removes the characters isInvisible matches from s.
+func cleanInvisible(s string) (string, bool) {
+	if strings.IndexFunc(s, isInvisible) < 0 {
+		return s, false
+	}
+	return strings.Map(func(r rune) rune {
+		if isInvisible(r) {
+			return -1
+		}
+		return r
+	}, s), true
+}
+
+// inputFile is a text input opened by openInput.
+type inputFile struct {
+	*bufio.Reader
+	io.Closer
+}
+
+// openInput opens a file of keywords or domains, or stdin for "-". A UTF-8
+// byte order mark, as Windows editors write, is skipped so that it does not
+// hide a comment or a disabled keyword on the first line.
+func openInput(path string) (io.ReadCloser, error) {
+	file := io.NopCloser(os.Stdin)
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		file = f
+	}
+	br := bufio.NewReader(file)
+	if bom, _ := br.Peek(3); string(bom) == "\ufeff" {
+		br.Discard(3)
+	}
+	return inputFile{br, file}, nil
+}
+
+// cleanInputs cleans the keywords or domains of one input, with a warning
+// for each one changed, or refuses them with strict. Inputs made only of
+// invisible characters are dropped.
+func cleanInputs(kind string, inputs []string, strict bool) (cleaned, warnings []string, err error) {
+	for _, input := range inputs {
+		clean, changed := cleanInvisible(input)
+		switch {
+		case !changed:
+		case strict:
+			return nil, nil, fmt.Errorf("%s %q contains invisible characters or quotes; remove them, or drop -strict to have them cleaned", kind, input)
+		case clean == "":
+			warnings = append(warnings, fmt.Sprintf("%s %q contained only invisible characters, skipped", kind, input))
+			continue
+		default:
+			warnings = append(warnings, fmt.Sprintf("%s %q contained invisible characters or quotes, cleaned to %q", kind, input, clean))
+		}
+		cleaned = append(cleaned, clean)
+	}
+	return cleaned, warnings, nil
+}
+
+// joinTemplate joins the words of combinations of one size, as given to
+// -join-templates: {0}{1}-{2} joins the first two words directly and puts
+// a dash before the third. Each word is used exactly once.
+type joinTemplate struct {
+	spec string
+	// literals holds the text around the placeholders: literals[i] comes
+	// before word order[i], and the last one after the last word.
+	literals []string
+	order    []int
+}
+
+// parseJoinTemplate reads a template. The words it joins are counted from
+// its placeholders, so {0}{1}-{2} is a template for three-word names.
+func parseJoinTemplate(spec string) (joinTemplate, error) {
+	t := joinTemplate{spec: spec}
+	rest := spec
+	for {
+		literal, after, found := strings.Cut(rest, "{")
+		t.literals = append(t.literals, literal)
+		if err := validateSeparator(literal); err != nil {
+			return t, fmt.Errorf("join template %q: %q cannot appear in a domain name: labels may only contain letters, digits and hyphens", spec, literal)
+		}
+		if !found {
+			break
+		}
+		index, after, closed := strings.Cut(after, "}")
+		n, err := strconv.Atoi(index)
+		if !closed || err != nil || n < 0 {
+			return t, fmt.Errorf("join template %q: placeholders are {0}, {1}, ... for the first, second, ... word", spec)
+		}
+		t.order = append(t.order, n)
+		rest = after
+	}
+
+	switch {
+	case len(t.order) < 2:
+		return t, fmt.Errorf("join template %q joins fewer than two words", spec)
+	case strings.HasPrefix(spec, "-") || strings.HasSuffix(spec, "-"):
+		return t, fmt.Errorf("join template %q would make every name start or end with a hyphen", spec)
+	}
+	used := make([]bool, len(t.order))
+	for _, n := range t.order {
+		if n >= len(t.order) || used[n] {
+			return t, fmt.Errorf("join template %q must use each of {0} to {%d} once", spec, len(t.order)-1)
+		}
+		used[n] = true
+	}
+	return t, nil
+}
+
+// size is the number of words the template joins.
+func (t joinTemplate) size() int {
+	return len(t.order)
+}
+
+func (t joinTemplate) join(words []string) string {
+	var b strings.Builder
+	for i, n := range t.order {
+		b.WriteString(t.literals[i])
+		b.WriteString(words[n])
+	}
+	b.WriteString(t.literals[len(t.order)])
+	return b.String()
+}
+
+// parseJoinTemplates reads -join-templates, comma-separated templates
+// grouped by the number of words they join.
+func parseJoinTemplates(input string) (map[int][]joinTemplate, error) {
+	templates := map[int][]joinTemplate{}
+	for _, spec := range parseKeywords(strings.ToLower(input)) {
+		if spec == "" {
+			continue
+		}
+		t, err := parseJoinTemplate(spec)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.ContainsFunc(templates[t.size()], func(other joinTemplate) bool { return other.spec == t.spec }) {
+			templates[t.size()] = append(templates[t.size()], t)
+		}
+	}
+	return templates, nil
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+// ldhLabel is a random label of letters, digits and inner hyphens that no
+// label rule refuses.
+type ldhLabel string
+
+func (ldhLabel) Generate(r *rand.Rand, size int) reflect.Value {
+	const letters = "abcdefghijklmnopqrstuvwxyz0123456789"
+	b := make([]byte, 1+r.Intn(maxLabelLength))
+	for i := range b {
+		b[i] = letters[r.Intn(len(letters))]
+		if i > 0 && i < len(b)-1 && i != 2 && i != 3 && r.Intn(5) == 0 {
+			b[i] = '-'
+		}
+	}
+	return reflect.ValueOf(ldhLabel(b))
+}
+
+// pasted is random text mixing letters with the characters documents leave
+// behind: no-break and zero-width spaces, joiners, byte order marks and
+// quotes.
+type pasted string
+
+func (pasted) Generate(r *rand.Rand, size int) reflect.Value {
+	runes := []rune("abcxyz09-. \u00a0\u2007\u200b\u200c\u200d\u2060\ufeff\u201c\u201d\u2018\u2019\"'\t\u00e9")
+	var b strings.Builder
+	for i := r.Intn(size + 1); i > 0; i-- {
+		b.WriteRune(runes[r.Intn(len(runes))])
+	}
+	return reflect.ValueOf(pasted(b.String()))
+}
+
+func TestValidLabelsPass(t *testing.T) {
+	property := func(label ldhLabel) bool {
+		return labelProblem(string(label)) == ""
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBrokenLabelsFail(t *testing.T) {
+	property := func(label ldhLabel) bool {
+		s := string(label)
+		return labelProblem("-"+s) != "" &&
+			labelProblem(s+"-") != "" &&
+			labelProblem(s+"_x") != "" &&
+			labelProblem(s+strings.Repeat("a", maxLabelLength+1-len(s))) != ""
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCleanInvisible(t *testing.T) {
+	property := func(input pasted) bool {
+		s := string(input)
+		clean, changed := cleanInvisible(s)
+		again, changedAgain := cleanInvisible(clean)
+		return strings.IndexFunc(clean, isInvisible) < 0 &&
+			changed == (clean != s) &&
+			again == clean && !changedAgain &&
+			// Only characters are removed, the rest keeps its order.
+			len(clean) <= len(s) && strings.Count(clean, "a") == strings.Count(s, "a")
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestDropInvalidNamesPartitions checks that every candidate is either kept
+// or dropped with the rule labelProblem gives.
+func TestDropInvalidNamesPartitions(t *testing.T) {
+	property := func(names []pasted) bool {
+		var candidates []Candidate
+		for _, name := range names {
+			candidates = append(candidates, Candidate{FQDN: string(name) + ".com", BaseName: string(name), TLD: "com"})
+		}
+		valid, dropped := dropInvalidNames(candidates)
+		if len(valid)+len(dropped) != len(candidates) {
+			return false
+		}
+		for _, c := range valid {
+			if labelProblem(c.BaseName) != "" {
+				return false
+			}
+		}
+		for _, d := range dropped {
+			if d.Rule != labelProblem(strings.TrimSuffix(d.Domain, ".com")) || d.Rule == "" {
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestLabelProblem enumerates the names separators and keywords can join
+// into that break a label rule.
+func TestLabelProblem(t *testing.T) {
+	for _, tt := range []struct {
+		label string
+		// want is a part of the rule broken; "" for a valid label.
+		want string
+	}{
+		{"superfast", ""},
+		{"super-fast", ""},
+		{"super--fast", ""},
+		{"a", ""},
+		{"123", ""},
+		{"münchen", ""},
+		{strings.Repeat("a", maxLabelLength), ""},
+		{"", "empty label"},
+		{"-superfast", "starts with a hyphen"},
+		{"superfast-", "ends with a hyphen"},
+		{"-", "starts with a hyphen"},
+		{"super_fast", "contains an underscore"},
+		{"super.fast", `contains '.'`},
+		{"super fast", `contains ' '`},
+		{"super+fast", `contains '+'`},
+		{strings.Repeat("a", maxLabelLength+1), "longer than 63 characters"},
+		// Internationalized labels are measured in their encoded form.
+		{"ü" + strings.Repeat("a", 60), "longer than 63 characters once encoded as xn--"},
+		// Hyphens in the third and fourth places are kept for encodings.
+		{"ab--cd", "hyphens in the third and fourth positions are reserved"},
+		{"xn--mnchen-3ya", ""},
+		{"xn--zz", "starts with the punycode prefix xn-- but is not a valid encoded name"},
+		{"xn--super-fast", "starts with the punycode prefix xn-- but is not a valid encoded name"},
+		{"xn--", "ends with a hyphen"},
+		{"xn--zz-", "ends with a hyphen"},
+	} {
+		got := labelProblem(tt.label)
+		if tt.want == "" && got != "" || tt.want != "" && !strings.Contains(got, tt.want) {
+			t.Errorf("labelProblem(%q) = %q, want %q", tt.label, got, tt.want)
+		}
+	}
+}
+
+func TestParseSeparators(t *testing.T) {
+	for _, tt := range []struct {
+		input string
+		want  []string
+		err   bool
+	}{
+		{"", []string{""}, false},
+		{"none", []string{""}, false},
+		{"both", []string{"", "-"}, false},
+		{"-,none,-", []string{"-", ""}, false},
+		{"--", []string{"--"}, false},
+		{"x", []string{"x"}, false},
+		{"_", nil, true},
+		{".", nil, true},
+		{"-,+", nil, true},
+	} {
+		got, err := parseSeparators(tt.input)
+		if (err != nil) != tt.err || !tt.err && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseSeparators(%q) = %q, %v; want %q, error %v", tt.input, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+// TestGenerateDropsInvalidLabels joins keywords that only break the rules
+// once joined with a dash, as a keyword ending with a hyphen does.
+func TestGenerateDropsInvalidLabels(t *testing.T) {
+	candidates := generateDomains(Config{Keywords: [][]string{{"ab", "-go", "ok"}}, Combinations: 2, Separators: []string{"-"}, TLDs: []string{"com"}})
+	valid, dropped := dropInvalidNames(candidates)
+	if got, want := candidateDomains(valid), []string{"ab-ok.com"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("valid = %v, want %v", got, want)
+	}
+	want := []InvalidName{
+		{"ab--go.com", "hyphens in the third and fourth positions are reserved for encoded names"},
+		{"-go-ok.com", "starts with a hyphen"},
+	}
+	if !reflect.DeepEqual(dropped, want) {
+		t.Errorf("dropped = %+v, want %+v", dropped, want)
+	}
+}
+
+func TestKeywordName(t *testing.T) {
+	for _, tt := range []struct{ keyword, want string }{
+		{"myapp", "myapp"},
+		{"myapp.com", "myapp"},
+		{"MyApp.com", "MyApp"},
+		{"myapp.co.uk", "myapp"},
+		{"myapp.org.uk", "myapp"},
+		{"myapp.com.au", "myapp"},
+		{"blog.myapp.co.uk", "myapp"},
+		{"myapp.co.uk.", "myapp"},
+		{".myapp.com", "myapp"},
+		{"co.uk", ""},
+		{".co.uk", ""},
+		{".com", ""},
+		// A word that is also a TLD is still a name.
+		{"cloud", "cloud"},
+		// Not ending in an ICANN suffix: the dots are dropped.
+		{"my.app.notatld", "myappnotatld"},
+	} {
+		if got := keywordName(tt.keyword); got != tt.want {
+			t.Errorf("keywordName(%q) = %q, want %q", tt.keyword, got, tt.want)
+		}
+	}
+}
+
+func TestDuplicatedSuffix(t *testing.T) {
+	for _, tt := range []struct {
+		domain, want string
+		duplicated   bool
+	}{
+		{"myapp.com", "myapp.com", false},
+		{"myapp.co.uk", "myapp.co.uk", false},
+		{"myapp.com.com", "myapp.com", true},
+		{"myapp.io.com", "myapp.io", true},
+		{"myapp.co.uk.io", "myapp.co.uk", true},
+		{"myapp.com.co.uk", "myapp.com", true},
+		{"myapp.co.uk.co.uk", "myapp.co.uk", true},
+		{"blog.myapp.com", "blog.myapp.com", false},
+		{"com.com", "com.com", false},
+	} {
+		got, duplicated := duplicatedSuffix(tt.domain)
+		if got != tt.want || duplicated != tt.duplicated {
+			t.Errorf("duplicatedSuffix(%q) = %q, %v; want %q, %v", tt.domain, got, duplicated, tt.want, tt.duplicated)
+		}
+	}
+}
+
+// TestPastedInputs runs the inputs of testdata/pasted, copied out of
+// documents, chat messages and spreadsheets, through file and stdin input:
+// whatever they carry along is cleaned away with a warning before a name is
+// checked.
+func TestPastedInputs(t *testing.T) {
+	for _, tt := range []struct {
+		fixture string
+		flag    string
+		args    []string
+		domains []string
+		logged  []string
+	}{
+		{
+			fixture: "gdocs-keywords.txt",
+			flag:    "-keywords-file",
+			args:    []string{"-tlds=io"},
+			domains: []string{"cloudfast.io", "cloudstack.io", "cloudsupernova.io", "fastsupernova.io", "stackfast.io", "stacksupernova.io"},
+			logged: []string{
+				`keyword "stack\u200b" contained invisible characters or quotes, cleaned to "stack"`,
+				`keyword "“fast”" contained invisible characters or quotes, cleaned to "fast"`,
+				`keyword "super\u200dnova" contained invisible characters or quotes, cleaned to "supernova"`,
+				`keyword "\u200b" contained only invisible characters, skipped`,
+			},
+		},
+		{
+			fixture: "word-domains.txt",
+			flag:    "-domains-file",
+			domains: []string{"superfast.com", "superfast.dev", "superfast.io"},
+			logged: []string{
+				`domain "‘superfast.com’" contained invisible characters or quotes, cleaned to "superfast.com"`,
+				`domain "superfast.io\u200b" contained invisible characters or quotes, cleaned to "superfast.io"`,
+			},
+		},
+		{
+			fixture: "chat-domains.txt",
+			flag:    "-domains-file",
+			domains: []string{"superfast.app", "superfast.net", "superfast.org"},
+			logged: []string{
+				`domain "superfast.net.\u200b" contained invisible characters or quotes, cleaned to "superfast.net."`,
+				`domain "\"superfast.app\"" contained invisible characters or quotes, cleaned to "superfast.app"`,
+				`domain "\u2060" contained only invisible characters, skipped`,
+			},
+		},
+	} {
+		path := filepath.Join("testdata", "pasted", tt.fixture)
+		for _, source := range []string{"file", "stdin"} {
+			t.Run(tt.fixture+"/"+source, func(t *testing.T) {
+				input := path
+				if source == "stdin" {
+					file, err := os.Open(path)
+					if err != nil {
+						t.Fatal(err)
+					}
+					defer file.Close()
+					defer func(stdin *os.File) { os.Stdin = stdin }(os.Stdin)
+					os.Stdin, input = file, "-"
+				}
+				args := append([]string{"-backend=fake", tt.flag + "=" + input}, tt.args...)
+				code, logged, domains := runCaptured(t, args...)
+				if code != exitOK {
+					t.Fatalf("exit code = %d\n%s", code, logged)
+				}
+				if !slices.Equal(domains, tt.domains) {
+					t.Errorf("checked %q, want %q", domains, tt.domains)
+				}
+				for _, want := range tt.logged {
+					if !strings.Contains(logged, want) {
+						t.Errorf("stderr does not mention %s:\n%s", want, logged)
+					}
+				}
+				if strings.Contains(logged, "brand variants") {
+					t.Errorf("the comment after the byte order mark was read as a domain:\n%s", logged)
+				}
+			})
+		}
+	}
+
+	// -strict refuses the same inputs instead.
+	code, logged, _ := runCaptured(t, "-backend=fake", "-strict", "-domains-file="+filepath.Join("testdata", "pasted", "word-domains.txt"))
+	if code != exitFailure || !strings.Contains(logged, `domain "‘superfast.com’" contains invisible characters or quotes`) {
+		t.Errorf("-strict: exit code = %d\n%s", code, logged)
+	}
+}
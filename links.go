@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// PurchaseLink is a registrar page where an available domain can be bought.
+type PurchaseLink struct {
+	Registrar string `json:"registrar"`
+	URL       string `json:"url"`
+}
+
+var registrarLinks = map[string]string{
+	"namecheap":  "https://www.namecheap.com/domains/registration/results/?domain={domain}",
+	"porkbun":    "https://porkbun.com/checkout/search?q={domain}",
+	"cloudflare": "https://dash.cloudflare.com/?to=/:account/domains/register/{domain}",
+}
+
+type linkTemplate struct {
+	Registrar string
+	Template  string
+}
+
+// parseLinkTemplates accepts registrar names and URL templates containing
+// {domain}; a template is labelled with its host name.
+func parseLinkTemplates(input string) ([]linkTemplate, error) {
+	var templates []linkTemplate
+	for _, item := range parseKeywords(input) {
+		if tmpl, ok := registrarLinks[strings.ToLower(item)]; ok {
+			templates = append(templates, linkTemplate{Registrar: strings.ToLower(item), Template: tmpl})
+			continue
+		}
+		u, err := url.Parse(item)
+		if err != nil || u.Host == "" || !strings.Contains(item, "{domain}") {
+			return nil, fmt.Errorf("unknown -links entry %q (use namecheap, porkbun, cloudflare or a URL containing {domain})", item)
+		}
+		templates = append(templates, linkTemplate{Registrar: u.Hostname(), Template: item})
+	}
+	return templates, nil
+}
+
+func purchaseLinks(domain string, templates []linkTemplate) []PurchaseLink {
+	links := make([]PurchaseLink, len(templates))
+	for i, t := range templates {
+		links[i] = PurchaseLink{
+			Registrar: t.Registrar,
+			URL:       strings.ReplaceAll(t.Template, "{domain}", url.QueryEscape(domain)),
+		}
+	}
+	return links
+}
+
+// writeMarkdownAvailable lists available domains, as a table with one link
+// column per registrar when purchase links are present.
+func writeMarkdownAvailable(w io.Writer, rows []DomainResult, opts RenderOptions) {
+	var registrars []string
+	for _, result := range rows {
+		if len(result.Links) > 0 {
+			for _, link := range result.Links {
+				registrars = append(registrars, link.Registrar)
+			}
+			break
+		}
+	}
+
+	if len(registrars) == 0 {
+		for _, result := range rows {
+			fmt.Fprintf(w, "- `%s`%s\n", result.Domain, availableDetails(result, opts))
+		}
+		return
+	}
+
+	fmt.Fprintf(w, "| Domain | %s |\n", strings.Join(registrars, " | "))
+	fmt.Fprintf(w, "|---%s|\n", strings.Repeat("|---", len(registrars)))
+	for _, result := range rows {
+		cells := make([]string, len(registrars))
+		for i, registrar := range registrars {
+			for _, link := range result.Links {
+				if link.Registrar == registrar {
+					cells[i] = fmt.Sprintf("[buy](%s)", link.URL)
+				}
+			}
+		}
+		fmt.Fprintf(w, "| `%s`%s | %s |\n", result.Domain, availableDetails(result, opts), strings.Join(cells, " | "))
+	}
+}
@@ -1,89 +1,1368 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"math/rand"
 	"os"
+	"os/signal"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
+	"syscall"
+	"time"
+	"unicode"
 
-	"github.com/likexian/whois"
+	"github.com/botsman/domain-checker/namegen"
 )
 
 type Config struct {
-	Keywords     [][]string
+	Keywords [][]string
+	// ListLabels names each keyword list of -lists; nil when none is named.
+	ListLabels   []string
 	Combinations int
 	TLDs         []string
-	Separator    string
+	// Separators join the words of a name; each one yields its own
+	// variant of every combination.
+	Separators []string
+	// JoinTemplates replace the separators for combinations of the size
+	// they are keyed by; each template yields its own variant.
+	JoinTemplates map[int][]joinTemplate
+	Prefixes      []string
+	Suffixes      []string
+	// Anagrams adds the pronounceable reorderings of each keyword.
+	Anagrams bool
+	// Priorities are the @priority annotations of keyword files; a name's
+	// priority is the sum of those of its keywords.
+	Priorities map[string]int
+	// Translations maps the keywords -translations added to the keyword
+	// they translate and their language.
+	Translations map[string]translated
 }
 
 func main() {
+	setupConsole()
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "check":
+			os.Exit(runCheck(os.Args[2:]))
+		case "monitor":
+			os.Exit(runMonitor(os.Args[2:]))
+		case "results":
+			os.Exit(runResults(os.Args[2:]))
+		case "bench":
+			os.Exit(runBench(os.Args[2:]))
+		case "rdap":
+			os.Exit(runRDAP(os.Args[2:]))
+		case "history":
+			os.Exit(runHistory(os.Args[2:]))
+		case "cache":
+			os.Exit(runCache(os.Args[2:]))
+		case "test-patterns":
+			os.Exit(runTestPatterns(os.Args[2:]))
+		case "generate":
+			os.Exit(run(os.Args[2:], true))
+		}
+	}
+
+	os.Exit(run(os.Args[1:], false))
+}
+
+// Exit codes of a check run. 2 is used by the flag package for invalid flags.
+const (
+	exitOK                = 0
+	exitFailure           = 1
+	exitExpectationFailed = 3
+	exitBudgetExhausted   = 4
+	exitQuotaExhausted    = 5
+	exitStdoutClosed      = 6
+)
+
+// run checks the domains built from the flags in args. In generate mode the
+// names are invented from -random patterns instead of combined from keywords.
+func run(args []string, generate bool) int {
 	keywords := flag.String("keywords", "", "Comma-separated keywords (e.g., 'one,two,three')")
 	keywordLists := flag.String("lists", "", "Semicolon-separated lists of keywords (e.g., 'one,two;three,four')")
+	keywordsFile := flag.String("keywords-file", "", "File with one keyword per line, or - for stdin, used like -keywords; allows # comments, !disabled keywords and @priority=N and @lists=NAME annotations")
+	listsFiles := flag.String("lists-files", "", "Comma-separated keyword files, one list each named after its file, used like -lists")
 	combinations := flag.Int("combinations", 2, "Number of keywords to combine (ignored if lists provided)")
-	tlds := flag.String("tlds", "com", "Comma-separated TLDs to check (e.g., 'com,net,org')")
-	useDash := flag.Bool("dash", false, "Use dash separator (e.g., 'one-two' instead of 'onetwo')")
-	workers := flag.Int("workers", 10, "Number of concurrent workers")
+	tlds := flag.String("tlds", "com", "Comma-separated TLDs to check (e.g., 'com,net,org'); @popular, @tech, @startup, @cheap expand to preset lists")
+	tldsFile := flag.String("tlds-file", "", "File with the TLDs to check, separated by commas, spaces or lines, or - for stdin; added after -tlds, which then defaults to none")
+	excludeTLDs := flag.String("exclude-tlds", "", "Comma-separated TLDs or presets not to check, removed from those of -tlds and -tlds-file (e.g. '@popular' with '-exclude-tlds=org')")
+	prefixes := flag.String("prefixes", "", "Comma-separated words to also try in front of each name (e.g., 'get,try')")
+	suffixes := flag.String("suffixes", "", "Comma-separated words to also try after each name (e.g., 'app,hq')")
+	stemKeys := flag.Bool("stem", false, "Merge keywords sharing a stem (run, running, runner) and keep the first of each")
+	strict := flag.Bool("strict", false, "Refuse keywords containing a dot or TLD (myapp.com) and explicit domains with a repeated TLD (myapp.com.com) instead of fixing them with a warning")
+	keepDots := flag.Bool("keep-dots", false, "Check explicit domains such as blog.app.com as given, even when the part left of the TLD ends in a TLD itself")
+	stemKeep := flag.String("stem-keep", "", "Comma-separated keywords -stem never merges (e.g. 'news')")
+	stripStop := flag.Bool("strip-stopwords", false, "Remove English stopwords such as 'the', 'and' and 'of' from the keywords; -prefixes and -suffixes are left alone")
+	translationsFile := flag.String("translations", "", "File mapping keywords to their translations tagged by language (fast = de:schnell, es:rápido), each added after its keyword")
+	languagesFlag := flag.String("languages", "", "Comma-separated languages of -translations to add (e.g. 'de,es'; default: every language in the file)")
+	stopwordsFile := flag.String("stopwords-file", "", "File with one stopword per line to use instead of the built-in list of -strip-stopwords")
+	anagramFlag := flag.Bool("anagrams", false, fmt.Sprintf("Also check pronounceable reorderings of the letters of each keyword of up to %d letters (stream: master, maters, ...)", maxAnagramLength))
+	randomPatterns := flag.String("random", "", "generate: comma-separated patterns of c (consonant) and v (vowel) to invent names from, e.g. 'cvcvc,cvccv'")
+	randomCount := flag.Int("count", 100, "generate: number of names to invent")
+	randomSeed := flag.Int64("seed", 0, "generate: random seed, to invent the same names again (default: new each run)")
+	maxDomains := flag.Int("max-domains", 0, "Check at most this many domains, the first generated; the rest are skipped with a note (0 for no limit)")
+	separator := flag.String("separator", "", "Comma-separated strings to join words with, one variant per separator (e.g. '-' for one-two, 'x' for onextwo); none joins directly, both means none and '-'")
+	useDash := flag.Bool("dash", false, "Deprecated: same as -separator=-")
+	joinTemplates := flag.String("join-templates", "", "Comma-separated templates joining the words of names of one size instead of -separator, e.g. '{0}{1}-{2}' for three-word names like getcloud-stack")
+	explicitDomains := flag.String("domains", "", "Comma-separated domains to check as-is (e.g., 'example.com,example.io')")
+	domainsFile := flag.String("domains-file", "", "File with one domain per line to check as-is, or - for stdin")
+	domainsCSV := flag.String("domains-csv", "", "CSV file with the domains to check as-is in one column, see -csv-column")
+	csvColumn := flag.String("csv-column", "1", "Column of -domains-csv holding the domains: a number from 1, or a header name")
+	csvPassthrough := flag.Bool("csv-passthrough", false, "Carry the other columns of each -domains-csv row into the json output as the metadata of its domain")
+	pacingFlags := addPacingFlags(flag.CommandLine)
+	expect := flag.String("expect", "", "Expected status of every domain (available or taken); exit with 3 and list offenders otherwise")
+	failOnTaken := flag.Bool("fail-on-taken", false, "Same as -expect=available")
+	expectFile := flag.String("expect-file", "", "File of domains with the status each should have (available, taken with an optional registrar, or watch); they are checked, and mismatches exit with 3")
+	suggest := flag.Bool("suggest", false, "When no domain is available, check fallback variants of the taken names")
+	suggestAlways := flag.Bool("suggest-always", false, "Check fallback variants of taken names even when some domains are available")
+	suggestLimit := flag.Int("suggest-limit", 30, "Maximum number of suggestion checks")
+	rank := flag.Bool("rank", false, "Score available domains and list them best first")
+	rankWeights := flag.String("rank-weights", "", "Scoring weights as factor=weight pairs (length, keywords, hyphen, com, pronounce, dictionary), e.g. 'com=5,hyphen=0'")
+	prescore := flag.Bool("prescore", false, "Check the domains most likely to be available first, predicted from length, hyphens and TLD availability in -history, and compare the prediction with the results")
+	prescoreWeights := flag.String("prescore-weights", "", "Pre-score weights as feature=weight pairs (length, hyphen, tld), e.g. 'tld=4'")
+	checkHandles := flag.String("check-handles", "", "Comma-separated platforms (github, twitter, instagram) on which to check the handle of each available name (heuristic, opt-in)")
+	links := flag.String("links", "", "Comma-separated registrars (namecheap, porkbun, cloudflare) or URL templates with {domain} to link available domains to")
+	noHTTP := flag.Bool("no-http", false, "Disable all HTTP enrichment of results: -check-handles is skipped and -prices only uses cached prices")
+	maxHTTPProbes := flag.Int("max-http-probes", 200, "Maximum HTTP requests -check-handles sends to third-party sites per run (0 for no limit)")
+	pricesSource := flag.String("prices", "", "Annotate available domains with TLD prices from a registrar (porkbun); prices are cached for a week")
+	maxPrice := flag.Float64("max-price", 0, "Skip TLDs whose registration or renewal price is above this (USD per year, requires -prices)")
+	showKeywordStats := flag.Bool("keyword-stats", false, "Rank keywords by the share of their generated names that are available (text and json output; ignored for -domains)")
+	quota := flag.Int("quota", 0, "Maximum whois queries to send; once reached, the remaining domains are skipped and the exit code is 5 (0 for no limit)")
+	quotaWindow := flag.Duration("quota-window", 0, "Count the whois queries of earlier runs started within this window (e.g. 24h) against -quota; requires -history")
+	secondPass := flag.Bool("second-pass", false, "Recheck failed domains in a second pass after all others; without it, a second pass only runs when a server rate limited or deferred queries")
+	maxPasses := flag.Int("max-passes", 2, "Maximum passes over the domains, the first included (1 disables rechecking)")
+	passCooldown := flag.Duration("pass-cooldown", 30*time.Second, "Pause before each recheck pass, so rate limited servers can recover")
+	deferredCooldown := flag.Duration("deferred-cooldown", 2*time.Minute, "Pause before a recheck pass that includes domains a registry deferred, e.g. during maintenance")
+	budget := flag.Duration("budget", 0, "Stop checking after this long (e.g. 2m), report what completed and exit with 4; name servers are looked up first so whois is only spent on undelegated names")
+	tui := flag.Bool("tui", false, "Show a live-updating results table (falls back to normal output when stdout is not a terminal)")
+	format := flag.String("format", "text", "Output format: "+rendererNames())
+	output := flag.String("output", "", "Also write the full results to this file (format from extension: .json, .ndjson, .csv, .md)")
+	exportICS := flag.String("export-ics", "", "Write a calendar (.ics) with an event at the expiry date of every taken domain")
+	icsDropDays := flag.Int("ics-drop-days", 0, "Also add an event this many days after expiry, when the domain is likely to drop (e.g. 75)")
+	exportRegistrar := flag.String("export-registrar", "", "Write the available domains in a registrar's bulk-import layout to -export-file: "+registrarExporterNames())
+	exportFile := flag.String("export-file", "", "File written by -export-registrar")
+	exportConfident := flag.Bool("export-confident", false, "Leave low-confidence verdicts, such as those of the DNS fallback, out of -export-registrar")
+	quiet := flag.Bool("quiet", false, "Only print available domains, one per line (same as -format=quiet)")
+	printSchema := flag.Bool("print-schema", false, "Print the JSON Schema of the json output and exit")
+	copyAvailable := flag.Bool("copy", false, "Copy the available domains, one per line, to the system clipboard when the run finishes")
+	renderFlags := addRenderFlags(flag.CommandLine)
+	historyPath := flag.String("history", "", "SQLite database to append every check result to")
+	changesOnly := flag.Bool("changes-only", false, "Only render and notify the domains whose status differs from the previous run, read from -baseline or else -history; -output and -history still get every result")
+	baseline := flag.String("baseline", "", "Results file (json or ndjson) of the previous run for -changes-only, instead of the -history database")
+	runID := flag.String("run-id", "", "Identifier of this run in all outputs (default: random)")
+	cachePath := flag.String("cache", defaultCachePath(), "File where the last verdict of every domain is kept (empty disables the cache)")
+	skipWithin := flag.Duration("skip-if-checked-within", 0, "Reuse cached verdicts of any status younger than this (e.g. 12h), instead of -cache-ttl-taken and -cache-ttl-available")
+	ttlTaken := flag.Duration("cache-ttl-taken", 7*24*time.Hour, "Reuse cached taken (and reserved) verdicts younger than this instead of checking again (0 to always check)")
+	ttlAvailable := flag.Duration("cache-ttl-available", 2*time.Hour, "Reuse cached available verdicts younger than this instead of checking again (0 to always check)")
+	freshAvailable := flag.Bool("fresh-available", false, "Check every domain the cache has as available again, whatever its age")
+	force := flag.Bool("force", false, "Check every domain again, ignoring the cached verdicts")
+	resolverAddr := flag.String("resolver", "", "DNS server (host or host:port) for the DNS fallback, -budget pre-checks and subdomains (default: the system's)")
+	noDNSFallback := flag.Bool("no-dns-fallback", false, "Report domains of TLDs without a whois server as errors instead of checking their name servers")
+	offline := flag.Bool("offline", false, "Answer from the cache only and make no network calls; domains missing from the cache are reported as unchecked")
+	notifySummary := flag.Bool("notify-summary", false, "Send the list of available domains to the configured notifiers when the run finishes")
+	notifyFlags := addNotifyFlags(flag.CommandLine)
+	emailFlags := addEmailFlags(flag.CommandLine)
+	whoisFlags := addWhoisFlags(flag.CommandLine)
+	verbose := flag.Bool("verbose", false, "Explain how the domain list was built, such as the -stem mapping, and name the whois server that answered for each domain")
+	verifyMode := flag.String("verify-available", "", "Cross-check available verdicts: dns looks up every available domain and reports those delegated or existing in DNS as unknown, listed as conflicts")
+	includeExcerpt := flag.Bool("include-raw-excerpt", false, "Include the first lines of the reply behind each error or unknown verdict in the json and ndjson outputs (shown in the text output with -verbose)")
+	explain := flag.Bool("explain", false, "With -verbose, list every step that led to each verdict in the text output: servers asked, DNS lookups, cache hits, rechecks, and the rule that classified the reply")
+	timezone := addTimezoneFlag(flag.CommandLine)
+	debugFlag := flag.Bool("debug", false, "Log diagnostics such as connection budget waits to stderr")
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Domain Checker - Check domain availability\n\n")
 		fmt.Fprintf(os.Stderr, "Usage:\n")
-		fmt.Fprintf(os.Stderr, "  %s [options]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s [options]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s check [options] <domain>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s generate -random=PATTERNS [options]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s monitor [options]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s results <command> [options]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s bench [options]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s cache import|export [options]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s rdap update|show <tld>\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		flag.PrintDefaults()
 		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  # Check a single domain (exit code 0 available, 1 taken, 2 unknown)\n")
+		fmt.Fprintf(os.Stderr, "  %s check example.com\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  # Check 2-word combinations from a single list\n")
 		fmt.Fprintf(os.Stderr, "  %s -keywords=super,fast,cloud\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  # Check combinations between two lists\n")
 		fmt.Fprintf(os.Stderr, "  %s -lists=\"super,fast;cloud,service\"\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  # Use dash separator and check multiple TLDs\n")
-		fmt.Fprintf(os.Stderr, "  %s -keywords=my,app -dash -tlds=com,net,org\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s -keywords=my,app -separator=- -tlds=com,net,org\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Check each combination both joined and with a dash\n")
+		fmt.Fprintf(os.Stderr, "  %s -keywords=cloud,stack -separator=both\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  # Check 3-word combinations\n")
 		fmt.Fprintf(os.Stderr, "  %s -keywords=get,my,app,now -combinations=3\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Fail when any of our brand domains is registered by someone else\n")
+		fmt.Fprintf(os.Stderr, "  %s -domains-file=brand-variants.txt -fail-on-taken\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Invent 200 five-letter names and check them as .com and .io\n")
+		fmt.Fprintf(os.Stderr, "  %s generate -random=cvcvc -count=200 -tlds=com,io\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Try the options without sending any query, with made-up verdicts\n")
+		fmt.Fprintf(os.Stderr, "  %s -keywords=super,fast,cloud -tlds=com,io -backend=fake -fake-latency=200ms\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Watch domains and report status changes every 6 hours\n")
+		fmt.Fprintf(os.Stderr, "  %s monitor -domains-file=watch.txt -interval=6h -state=state.json\n\n", os.Args[0])
 	}
 
-	flag.Parse()
+	flag.CommandLine.Parse(args)
+	if err := setDisplayZone(*timezone); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+	if *debugFlag {
+		debugLog.SetOutput(os.Stderr)
+	}
+	// A closed stdout pipe fails the write instead of killing the process,
+	// and stdout then discards the rest of the output.
+	signal.Ignore(syscall.SIGPIPE)
+	stdout := &closableStdout{w: os.Stdout}
+	if *printSchema {
+		if err := writeSchema(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitFailure
+		}
+		return 0
+	}
 
-	if *keywords == "" && *keywordLists == "" {
-		fmt.Fprintf(os.Stderr, "Error: Either -keywords or -lists must be provided\n\n")
+	explicitMode := *explicitDomains != "" || *domainsFile != "" || *domainsCSV != "" || *expectFile != ""
+	var patterns []string
+	if generate {
+		if *keywordLists != "" || *keywordsFile != "" || *listsFiles != "" || explicitMode {
+			fmt.Fprintf(os.Stderr, "Error: generate invents names from -random; only -keywords may be given, as letters to draw from\n\n")
+			flag.Usage()
+			return exitFailure
+		}
+		var err error
+		if patterns, err = parseRandomPatterns(*randomPatterns); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitFailure
+		}
+		if *randomCount < 1 {
+			fmt.Fprintf(os.Stderr, "Error: -count must be at least 1\n")
+			return exitFailure
+		}
+	} else if *randomPatterns != "" {
+		fmt.Fprintf(os.Stderr, "Error: -random is only used by the generate command\n")
+		return exitFailure
+	}
+	inputs := 0
+	for _, set := range []bool{*keywords != "", *keywordLists != "", *keywordsFile != "", *listsFiles != "", explicitMode} {
+		if set {
+			inputs++
+		}
+	}
+	if inputs == 0 && !generate {
+		fmt.Fprintf(os.Stderr, "Error: One of -keywords, -lists, -keywords-file, -lists-files or -domains/-domains-file/-domains-csv/-expect-file must be provided\n\n")
 		flag.Usage()
-		os.Exit(1)
+		return exitFailure
+	}
+	if inputs > 1 {
+		fmt.Fprintf(os.Stderr, "Error: Cannot combine -keywords, -lists, -keywords-file, -lists-files and -domains/-domains-file/-expect-file\n\n")
+		flag.Usage()
+		return exitFailure
+	}
+
+	if *failOnTaken {
+		*expect = string(StatusAvailable)
+	}
+	if *expect != "" && *expect != string(StatusAvailable) && *expect != string(StatusTaken) {
+		fmt.Fprintf(os.Stderr, "Error: -expect must be 'available' or 'taken'\n")
+		return exitFailure
+	}
+	if *domainsCSV == "" && (*csvPassthrough || *csvColumn != "1") {
+		fmt.Fprintf(os.Stderr, "Error: -csv-column and -csv-passthrough require -domains-csv\n")
+		return exitFailure
+	}
+	if *languagesFlag != "" && *translationsFile == "" {
+		fmt.Fprintf(os.Stderr, "Error: -languages requires -translations\n")
+		return exitFailure
+	}
+	if *stopwordsFile != "" && !*stripStop {
+		fmt.Fprintf(os.Stderr, "Error: -stopwords-file requires -strip-stopwords\n")
+		return exitFailure
+	}
+	if *expect != "" && *expectFile != "" {
+		fmt.Fprintf(os.Stderr, "Error: -expect-file replaces -expect and -fail-on-taken; give the status of each domain in the file\n")
+		return exitFailure
+	}
+	var expectations []Expectation
+	if *expectFile != "" {
+		var err error
+		if expectations, err = readExpectations(*expectFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitFailure
+		}
 	}
 
-	if *keywords != "" && *keywordLists != "" {
-		fmt.Fprintf(os.Stderr, "Error: Cannot use both -keywords and -lists at the same time\n\n")
+	if *quiet {
+		*format = "quiet"
+	}
+	if _, ok := renderers[*format]; !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown -format %q\n\n", *format)
 		flag.Usage()
-		os.Exit(1)
+		return exitFailure
+	}
+
+	if *exportRegistrar != "" {
+		if _, ok := registrarExporters[*exportRegistrar]; !ok {
+			fmt.Fprintf(os.Stderr, "Error: unknown -export-registrar %q (use %s)\n", *exportRegistrar, registrarExporterNames())
+			return exitFailure
+		}
+		if *exportFile == "" {
+			fmt.Fprintf(os.Stderr, "Error: -export-registrar needs -export-file\n")
+			return exitFailure
+		}
+	} else if *exportFile != "" {
+		fmt.Fprintf(os.Stderr, "Error: -export-file needs -export-registrar\n")
+		return exitFailure
+	}
+
+	pacing, err := pacingFlags.Pacing()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+
+	renderOpts, err := renderFlags.Options()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+
+	notifiers, err := notifyFlags.Notifiers()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+	email, err := emailFlags.Notifier()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+	if *notifySummary && len(notifiers) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: -notify-summary requires -slack-webhook or -telegram-token/-telegram-chat-id\n")
+		return exitFailure
+	}
+
+	if *offline {
+		for _, conflict := range []struct {
+			set  bool
+			flag string
+		}{
+			{*checkHandles != "", "-check-handles"},
+			{*notifySummary, "-notify-summary"},
+			{email != nil, "-email-to"},
+			{*verifyMode != "", "-verify-available"},
+		} {
+			if conflict.set {
+				fmt.Fprintf(os.Stderr, "Error: %s needs network access and cannot be used with -offline\n", conflict.flag)
+				return exitFailure
+			}
+		}
+		if *cachePath == "" {
+			fmt.Fprintf(os.Stderr, "Error: -offline requires -cache\n")
+			return exitFailure
+		}
+	}
+
+	if *maxPasses < 1 {
+		fmt.Fprintf(os.Stderr, "Error: -max-passes must be at least 1\n")
+		return exitFailure
+	}
+
+	whoisChecker, err := whoisFlags.Checker()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+
+	if resolver, err = parseResolver(*resolverAddr); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+
+	fake, err := whoisFlags.Fake()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+	switch *verifyMode {
+	case "":
+	case "dns":
+		if fake != nil {
+			fmt.Fprintf(os.Stderr, "Error: -verify-available looks domains up in DNS and cannot be used with -backend=fake\n")
+			return exitFailure
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown -verify-available %q (use dns)\n", *verifyMode)
+		return exitFailure
+	}
+
+	if fake != nil {
+		// Made-up verdicts must not end up in the default cache, where later
+		// real runs would trust them; a -cache given explicitly is used.
+		cacheGiven := false
+		flag.CommandLine.Visit(func(f *flag.Flag) {
+			cacheGiven = cacheGiven || f.Name == "cache"
+		})
+		if !cacheGiven {
+			*cachePath = ""
+		}
+	}
+
+	network := pacing.Throttle(whoisChecker.Check)
+	if fake != nil {
+		// The fake backend answers subdomains too, and has no use for the
+		// DNS lookups that back up whois.
+		network = pacing.Throttle(fake.Check)
+	} else {
+		if !*noDNSFallback {
+			network = withDNSFallback(network)
+		}
+		if *budget > 0 {
+			network = withDNSPrecheck(network)
+		}
+		network = withSubdomains(network)
+	}
+	network = withDomainLock(network)
+	check := network
+	var cache *VerdictCache
+	if *cachePath != "" {
+		cache, err = OpenCache(*cachePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitFailure
+		}
+		if *offline {
+			check = cache.Offline
+		} else {
+			check = cache.Recording(network)
+			ttl := cacheTTL{Available: *ttlAvailable, Taken: *ttlTaken}
+			if *skipWithin > 0 {
+				ttl = cacheTTL{Available: *skipWithin, Taken: *skipWithin}
+			}
+			if *freshAvailable {
+				ttl.Available = 0
+			}
+			if ttl.Available < 0 || ttl.Taken < 0 {
+				fmt.Fprintf(os.Stderr, "Error: -cache-ttl-taken and -cache-ttl-available cannot be negative\n")
+				return exitFailure
+			}
+			if !*force && (ttl.Available > 0 || ttl.Taken > 0) {
+				check = cache.SkipRecent(check, ttl)
+			}
+		}
+	} else if *skipWithin > 0 {
+		fmt.Fprintf(os.Stderr, "Error: -skip-if-checked-within requires -cache\n")
+		return exitFailure
+	}
+
+	var history *HistoryStore
+	if *historyPath != "" {
+		history, err = OpenHistory(*historyPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitFailure
+		}
+		defer history.Close()
+	}
+
+	var previous []DomainResult
+	if *baseline != "" && !*changesOnly {
+		fmt.Fprintf(os.Stderr, "Error: -baseline requires -changes-only\n")
+		return exitFailure
+	}
+	if *changesOnly {
+		if history == nil && *baseline == "" {
+			fmt.Fprintf(os.Stderr, "Error: -changes-only requires -baseline or -history\n")
+			return exitFailure
+		}
+		previous, err = previousResults(history, *baseline)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitFailure
+		}
+	}
+
+	quotaUsedBefore := 0
+	if *quotaWindow > 0 {
+		if history == nil || *quota == 0 {
+			fmt.Fprintf(os.Stderr, "Error: -quota-window requires -quota and -history\n")
+			return exitFailure
+		}
+		quotaUsedBefore, err = history.WhoisQueriesSince(time.Now().Add(-*quotaWindow))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitFailure
+		}
+	}
+	whoisChecker.Quota = newWhoisQuota(*quota, quotaUsedBefore)
+
+	weights, err := parseRankWeights(*rankWeights)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+	var prescorer *Prescorer
+	if *prescore {
+		prescorer = &Prescorer{}
+		if prescorer.Weights, err = parsePrescoreWeights(*prescoreWeights); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitFailure
+		}
+		if history != nil {
+			if prescorer.TLDRates, err = history.tldAvailability(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return exitFailure
+			}
+		}
+	} else if *prescoreWeights != "" {
+		fmt.Fprintf(os.Stderr, "Error: -prescore-weights requires -prescore\n")
+		return exitFailure
+	}
+	renderOpts.Rank = *rank
+	renderOpts.Servers = *verbose
+	if *explain && !*verbose {
+		fmt.Fprintf(os.Stderr, "Error: -explain requires -verbose\n")
+		return exitFailure
+	}
+	renderOpts.Explain = *explain
+	renderOpts.Excerpts = *verbose
+	includeRawExcerpts = *includeExcerpt
+	renderOpts.Expect = Status(*expect)
+	renderOpts.Title = runParameters(flag.CommandLine)
+	renderOpts.FullListPath = *output
+
+	handleSites, err := parseHandlePlatforms(*checkHandles)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+	if *noHTTP && len(handleSites) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: -check-handles is skipped with -no-http\n")
+		handleSites = nil
+	}
+
+	linkTemplates, err := parseLinkTemplates(*links)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+
+	tldOpts := tldOptions{List: *tlds, File: *tldsFile, Exclude: *excludeTLDs}
+	if *tldsFile != "" {
+		// The default of -tlds only applies without a file.
+		tldsGiven := false
+		flag.CommandLine.Visit(func(f *flag.Flag) {
+			tldsGiven = tldsGiven || f.Name == "tlds"
+		})
+		if !tldsGiven {
+			tldOpts.List = ""
+		}
+	}
+	tldList, tldWarnings, err := parseTLDs(tldOpts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+	for _, warning := range tldWarnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
 	}
 
 	config := Config{
 		Combinations: *combinations,
-		TLDs:         parseTLDs(*tlds),
-		Separator:    "",
+		TLDs:         tldList,
+		Anagrams:     *anagramFlag,
+	}
+	for _, affixes := range []struct {
+		kind  string
+		flag  string
+		words *[]string
+	}{
+		{"prefix", *prefixes, &config.Prefixes},
+		{"suffix", *suffixes, &config.Suffixes},
+	} {
+		cleaned, warnings, err := cleanInputs(affixes.kind, parseKeywords(affixes.flag), *strict)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitFailure
+		}
+		for _, warning := range warnings {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+		}
+		*affixes.words = cleaned
 	}
 
 	if *useDash {
-		config.Separator = "-"
+		if *separator != "" {
+			fmt.Fprintf(os.Stderr, "Error: -dash and -separator cannot be combined; use -separator alone\n")
+			return exitFailure
+		}
+		fmt.Fprintf(os.Stderr, "Warning: -dash is deprecated, use -separator=-\n")
+		*separator = "-"
+	}
+	config.Separators, err = parseSeparators(*separator)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+	config.JoinTemplates, err = parseJoinTemplates(*joinTemplates)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitFailure
 	}
 
-	if *keywordLists != "" {
-		config.Keywords = parseKeywordLists(*keywordLists)
+	var domains []Candidate
+	// syntaxSkips counts the generated names skipped for registry rules.
+	var syntaxSkips []syntaxSkip
+	if generate {
+		seed := *randomSeed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+			fmt.Fprintf(os.Stderr, "Inventing names with -seed=%d\n", seed)
+		}
+		cons, vows := letterPools(parseKeywords(*keywords))
+		names := randomNames(patterns, *randomCount, cons, vows, rand.New(rand.NewSource(seed)))
+		if len(names) < *randomCount {
+			fmt.Fprintf(os.Stderr, "Only %d distinct names fit %s with these letters\n", len(names), strings.Join(patterns, ","))
+		}
+		domains = withTLDs(randomCandidates(names), config.TLDs)
+	} else if explicitMode {
+		explicit := parseKeywords(strings.ToLower(*explicitDomains))
+		if *domainsFile != "" {
+			fileDomains, err := readDomainsFile(*domainsFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return exitFailure
+			}
+			explicit = append(explicit, fileDomains...)
+		}
+		var csvMetadata map[string]map[string]string
+		if *domainsCSV != "" {
+			rows, warnings, err := readDomainsCSV(*domainsCSV, *csvColumn, *csvPassthrough)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return exitFailure
+			}
+			for _, warning := range warnings {
+				fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+			}
+			csvMetadata = map[string]map[string]string{}
+			for _, row := range rows {
+				explicit = append(explicit, row.Domain)
+				if row.Metadata != nil {
+					csvMetadata[row.Domain] = row.Metadata
+				}
+			}
+		}
+		for _, e := range expectations {
+			if !slices.Contains(explicit, e.Domain) {
+				explicit = append(explicit, e.Domain)
+			}
+		}
+		explicit, warnings, err := cleanInputs("domain", explicit, *strict)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitFailure
+		}
+		for _, warning := range warnings {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+		}
+		// Drop the root dot, which may only show once what followed it is
+		// cleaned away, as in "example.com.\u200b".
+		for i, domain := range explicit {
+			explicit[i] = strings.TrimSuffix(domain, ".")
+		}
+		if !*keepDots {
+			for i, domain := range explicit {
+				fixed, duplicated := duplicatedSuffix(domain)
+				if !duplicated {
+					continue
+				}
+				if *strict {
+					fmt.Fprintf(os.Stderr, "Error: %s repeats its TLD; check %s, or pass -keep-dots to check it as given\n", domain, fixed)
+					return exitFailure
+				}
+				fmt.Fprintf(os.Stderr, "Warning: treated '%s' as '%s' (-keep-dots checks it as given)\n", domain, fixed)
+				explicit[i] = fixed
+				if metadata, ok := csvMetadata[domain]; ok {
+					csvMetadata[fixed] = metadata
+				}
+			}
+		}
+		domains = explicitCandidates(explicit, VariantExplicit)
+		for i := range domains {
+			domains[i].Metadata = csvMetadata[domains[i].FQDN]
+		}
 	} else {
-		config.Keywords = [][]string{parseKeywords(*keywords)}
+		switch {
+		case *keywordLists != "":
+			config.Keywords, config.ListLabels = parseKeywordLists(*keywordLists)
+		case *keywordsFile != "", *listsFiles != "":
+			var entries []keywordEntry
+			var warnings []string
+			var err error
+			if *keywordsFile != "" {
+				entries, warnings, err = readKeywordFile(*keywordsFile)
+				config.Keywords, config.ListLabels = keywordFileLists(entries)
+			} else {
+				config.Keywords, config.ListLabels, entries, warnings, err = readListFiles(parseKeywords(*listsFiles))
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return exitFailure
+			}
+			for _, warning := range warnings {
+				fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+			}
+			config.Priorities = keywordPriorities(entries)
+		default:
+			config.Keywords = [][]string{parseKeywords(*keywords)}
+		}
+		if *translationsFile != "" {
+			translations, err := readTranslations(*translationsFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return exitFailure
+			}
+			languages := translationLanguages(translations)
+			if *languagesFlag != "" {
+				wanted := map[string]bool{}
+				for _, language := range parseKeywords(strings.ToLower(*languagesFlag)) {
+					if !languages[language] {
+						fmt.Fprintf(os.Stderr, "Warning: -languages: no translation in %s is tagged %s\n", *translationsFile, language)
+					}
+					wanted[language] = true
+				}
+				languages = wanted
+			}
+			config.Translations = map[string]translated{}
+			for i, list := range config.Keywords {
+				expanded, added := expandTranslations(list, translations, languages)
+				for word, t := range added {
+					config.Translations[word] = t
+				}
+				if *verbose {
+					for _, word := range expanded {
+						if t, ok := added[word]; ok {
+							fmt.Fprintf(os.Stderr, "translation: %s -> %s (%s)\n", t.Keyword, word, t.Language)
+						}
+					}
+				}
+				config.Keywords[i] = expanded
+			}
+		}
+		for i, list := range config.Keywords {
+			cleaned, warnings, err := cleanInputs("keyword", list, *strict)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return exitFailure
+			}
+			for _, warning := range warnings {
+				fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+			}
+			config.Keywords[i] = cleaned
+		}
+		for keyword, priority := range config.Priorities {
+			if clean, changed := cleanInvisible(keyword); changed {
+				config.Priorities[clean] = priority
+			}
+		}
+		for keyword, t := range config.Translations {
+			if clean, changed := cleanInvisible(keyword); changed {
+				config.Translations[clean] = t
+			}
+		}
+		for _, list := range config.Keywords {
+			for i, keyword := range list {
+				name := keywordName(keyword)
+				switch {
+				case name == keyword:
+					continue
+				case name == "":
+					fmt.Fprintf(os.Stderr, "Error: keyword %q has no name left of its TLD\n", keyword)
+					return exitFailure
+				case *strict:
+					fmt.Fprintf(os.Stderr, "Error: keyword %q is a domain, not a name; pass %q and the TLD with -tlds\n", keyword, name)
+					return exitFailure
+				}
+				fmt.Fprintf(os.Stderr, "Warning: treated '%s' as '%s'\n", keyword, name)
+				list[i] = name
+				if priority, ok := config.Priorities[keyword]; ok {
+					config.Priorities[name] = priority
+				}
+				if t, ok := config.Translations[keyword]; ok {
+					config.Translations[name] = t
+				}
+			}
+		}
+		if *stripStop {
+			words := defaultStopwords
+			if *stopwordsFile != "" {
+				var err error
+				if words, err = readStopwordsFile(*stopwordsFile); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					return exitFailure
+				}
+			}
+			stop := stopwordSet(words)
+			for i, list := range config.Keywords {
+				kept, removed := stripStopwords(list, stop)
+				switch {
+				case len(removed) > 0:
+					fmt.Fprintf(os.Stderr, "Removed stopwords %s (-strip-stopwords)\n", strings.Join(removed, ", "))
+				case len(kept) > 0 && stop[strings.ToLower(kept[0])]:
+					fmt.Fprintf(os.Stderr, "Warning: kept stopwords %s, they are the only keywords of their list\n", strings.Join(kept, ", "))
+				}
+				config.Keywords[i] = kept
+			}
+		}
+		if *stemKeys {
+			keep := map[string]bool{}
+			for _, keyword := range parseKeywords(strings.ToLower(*stemKeep)) {
+				keep[keyword] = true
+			}
+			for i, list := range config.Keywords {
+				if *verbose {
+					for _, keyword := range list {
+						if keep[strings.ToLower(keyword)] {
+							fmt.Fprintf(os.Stderr, "stem: %s (kept)\n", keyword)
+						} else {
+							fmt.Fprintf(os.Stderr, "stem: %s -> %s\n", keyword, stem(strings.ToLower(keyword)))
+						}
+					}
+				}
+				kept, merges := stemKeywords(list, keep)
+				for _, merge := range merges {
+					fmt.Fprintf(os.Stderr, "Merged %s into %s (-stem)\n", strings.Join(merge.Dropped, ", "), merge.Kept)
+				}
+				config.Keywords[i] = kept
+			}
+		}
+		if config.Anagrams {
+			for _, list := range config.Keywords {
+				for _, keyword := range list {
+					if len(keyword) > maxAnagramLength {
+						fmt.Fprintf(os.Stderr, "Skipping anagrams of %s: longer than %d letters\n", keyword, maxAnagramLength)
+					}
+				}
+			}
+		}
+		domains = generateDomains(config)
+		var invalid []InvalidName
+		domains, invalid = dropInvalidNames(domains)
+		for _, name := range invalid {
+			fmt.Fprintf(os.Stderr, "Skipping %s: %s\n", name.Domain, name.Rule)
+		}
+		domains, invalid, syntaxSkips = dropRefusedNames(domains, whoisChecker.Syntax)
+		if *verbose {
+			for _, name := range invalid {
+				fmt.Fprintf(os.Stderr, "Skipping %s: %s\n", name.Domain, name.Rule)
+			}
+		}
+		if *verbose {
+			for _, candidate := range domains {
+				if slots := candidate.slots(); slots != "" {
+					fmt.Fprintf(os.Stderr, "%s: %s\n", candidate.FQDN, slots)
+				}
+				if n := len(candidate.Combinations); n > 1 {
+					combos := make([]string, n)
+					for i, parts := range candidate.Combinations {
+						combos[i] = strings.Join(parts, "+")
+					}
+					fmt.Fprintf(os.Stderr, "%s produced by %d combinations: %s\n", candidate.FQDN, n, strings.Join(combos, ", "))
+				}
+			}
+		}
 	}
 
-	domains := generateDomains(config)
+	var prices map[string]Price
+	if *pricesSource != "" {
+		prices, err = loadPrices(context.Background(), *pricesSource, cacheDir(), *offline || *noHTTP)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v; continuing without prices\n", err)
+		}
+	} else if *maxPrice > 0 {
+		fmt.Fprintf(os.Stderr, "Error: -max-price requires -prices\n")
+		return exitFailure
+	}
+	if *maxPrice > 0 && prices != nil {
+		var dropped map[string]Price
+		domains, dropped = filterByPrice(domains, prices, *maxPrice)
+		for tld, price := range dropped {
+			fmt.Fprintf(os.Stderr, "Skipping .%s (%s) above -max-price\n", tld, price)
+		}
+	}
+
+	if *maxDomains < 0 {
+		fmt.Fprintf(os.Stderr, "Error: -max-domains cannot be negative\n")
+		return exitFailure
+	}
+	if *maxDomains > 0 && len(domains) > *maxDomains {
+		fmt.Fprintf(os.Stderr, "Checking only the first %d of %d domains (-max-domains)\n", *maxDomains, len(domains))
+		domains = domains[:*maxDomains]
+	}
+	if prescorer != nil {
+		sort.SliceStable(domains, func(i, j int) bool {
+			return prescorer.Predict(domains[i].FQDN) > prescorer.Predict(domains[j].FQDN)
+		})
+	}
 
 	if len(domains) == 0 {
-		fmt.Println("No domains to check")
-		os.Exit(0)
+		fmt.Fprintln(stdout, "No domains to check")
+		return exitOK
+	}
+
+	// Keep stdout machine-readable for structured formats.
+	var banner io.Writer = stdout
+	if *format != "text" && *format != "table" {
+		banner = os.Stderr
+	}
+	if len(syntaxSkips) > 0 {
+		fmt.Fprintln(banner, formatSyntaxSkips(syntaxSkips))
+	}
+	if fake == nil {
+		writeCooldowns(banner, whoisChecker)
+	}
+	fmt.Fprintf(banner, "Checking %d domains (%s, estimated %s)...\n\n",
+		len(domains), pacing, formatETA(pacing.Estimate(len(domains), assumedLatency)))
+
+	if *runID == "" {
+		*runID = newRunID()
+	}
+	ctx := context.Background()
+	if *budget > 0 {
+		sort.SliceStable(domains, func(i, j int) bool { return domains[i].Priority > domains[j].Priority })
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *budget)
+		defer cancel()
+	}
+
+	command := "check"
+	if generate {
+		command = "generate"
+	}
+	report := &Report{RunInfo: RunInfo{ID: *runID, StartedAt: time.Now().UTC(), Manifest: newManifest(flag.CommandLine, command)}}
+	if *tui && isTerminal(os.Stdout) && isTerminal(os.Stdin) && detectConsole(os.Stdout).ANSI {
+		report.Results, err = runTUI(ctx, stdout, domains, pacing, check)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitFailure
+		}
+	} else {
+		stream := checkDomainsStream(ctx, domains, pacing, check)
+		if detectConsole(os.Stderr).ANSI {
+			report.Results = collectWithProgress(os.Stderr, stream, len(domains), pacing)
+		} else {
+			report.Results = collect(stream)
+		}
 	}
 
-	fmt.Printf("Checking %d domains...\n\n", len(domains))
+	report.Results = recheckFailures(ctx, report.Results, recheck{
+		MaxPasses: *maxPasses,
+		Always:    *secondPass,
+		Cooldown:  *passCooldown,
+		Deferred:  *deferredCooldown,
+	}, pacing, check)
 
-	results := checkDomainsConcurrently(domains, *workers)
+	budgetExhausted := *budget > 0 && ctx.Err() != nil
+	if budgetExhausted {
+		report.Results = appendUnchecked(report.Results, domains, errOutOfBudget)
+		fmt.Fprintf(os.Stderr, "Budget of %s exhausted: %d of %d domains left unchecked\n",
+			*budget, summarize(report.Results).Unchecked, len(domains))
+	}
 
-	printResults(results)
+	if whoisChecker.Quota.exhausted() {
+		skipped := 0
+		for _, result := range report.Results {
+			if errors.Is(result.Error, errOutOfQuota) {
+				skipped++
+			}
+		}
+		fmt.Fprintf(os.Stderr, "Whois quota of %d queries reached: %d domains skipped\n", *quota, skipped)
+	}
+
+	if (*suggest || *suggestAlways) && !budgetExhausted {
+		summary := summarize(report.Results)
+		if *suggestAlways || summary.Available == 0 {
+			checked := map[string]bool{}
+			var taken []string
+			for _, result := range report.Results {
+				checked[result.Domain] = true
+				if result.Status == StatusTaken {
+					taken = append(taken, result.Domain)
+				}
+			}
+
+			suggestions := suggestDomains(taken, hyphenatedVariants(config), checked, *suggestLimit)
+			if len(suggestions) > 0 {
+				fmt.Fprintf(banner, "Checking %d suggestions...\n\n", len(suggestions))
+				for _, result := range checkDomainsConcurrently(ctx, explicitCandidates(suggestions, VariantSuggestion), pacing, check) {
+					result.Suggested = true
+					report.Results = append(report.Results, result)
+				}
+			}
+		}
+	}
+	if *verifyMode == "dns" {
+		report.Conflicts = verifyAvailable(report.Results)
+		if len(report.Conflicts) > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: DNS contradicts %d available verdicts, now reported as unknown\n", len(report.Conflicts))
+		}
+	}
+	report.FinishedAt = time.Now().UTC()
+	if *quota > 0 {
+		report.Quota = whoisChecker.Quota.usage()
+	}
+	for i := range report.Results {
+		// The report files, cache and history carry UTC.
+		report.Results[i].CheckedAt = report.Results[i].CheckedAt.UTC()
+		if !report.Results[i].Cached && report.Results[i].Status != StatusUnchecked {
+			report.Results[i].RunID = report.ID
+		}
+	}
+
+	if len(handleSites) > 0 && summarize(report.Results).Available > 0 {
+		fmt.Fprintf(banner, "Checking handles on %s (heuristic)...\n\n", strings.Join(handleSites, ", "))
+		checker := &HandleChecker{
+			Platforms: handleSites,
+			Interval:  time.Second,
+			MaxProbes: *maxHTTPProbes,
+			Client:    newHandleClient(),
+		}
+		checker.CheckResults(context.Background(), report.Results)
+	}
+
+	if prices != nil {
+		for i := range report.Results {
+			if report.Results[i].Status != StatusAvailable {
+				continue
+			}
+			if price, ok := prices[domainTLD(report.Results[i].Domain)]; ok {
+				report.Results[i].Price = &price
+			}
+		}
+	}
+
+	if len(linkTemplates) > 0 {
+		for i := range report.Results {
+			if report.Results[i].Status == StatusAvailable {
+				report.Results[i].Links = purchaseLinks(report.Results[i].Domain, linkTemplates)
+			}
+		}
+	}
+
+	if *rank {
+		scorer := NewScorer(weights, config.Keywords)
+		for i := range report.Results {
+			if report.Results[i].Status == StatusAvailable {
+				report.Results[i].Score = scorer.Score(report.Results[i].Domain)
+			}
+		}
+	}
+	report.summarize()
+	if *showKeywordStats {
+		report.KeywordStats = keywordStats(report.Results)
+	}
+	if expectations != nil {
+		report.Mismatches = mismatches(report.Results, expectations)
+	}
+	results := report.Results
+
+	// With -changes-only the unchanged domains are left out of what is
+	// shown and sent; the report files, the history and the email
+	// attachment keep them.
+	shown := report
+	var changes []StatusChange
+	unchanged := 0
+	if *changesOnly {
+		changes, unchanged = changedResults(results, previous)
+		changed := *report
+		changed.Results = make([]DomainResult, len(changes))
+		for i, change := range changes {
+			changed.Results[i] = change.New
+		}
+		changed.summarize()
+		shown = &changed
+		defer fmt.Fprintf(os.Stderr, "Changes only: %d of %d domains changed since the previous run, %d unchanged not shown\n",
+			len(changes), len(results), unchanged)
+	}
+
+	if err := renderers[*format](stdout, shown, renderOpts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+
+	if !renderOpts.NoSummary {
+		defer fmt.Fprintln(os.Stderr, summaryLine(report))
+	}
+
+	if prescorer != nil {
+		writePrescoreReport(os.Stderr, results, prescorer)
+	}
+
+	if *output != "" {
+		if err := writeReportFile(*output, report); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitFailure
+		}
+	}
+
+	if *copyAvailable {
+		copyAvailableDomains(report, renderOpts)
+	}
+
+	if *exportRegistrar != "" {
+		written, err := exportAvailableDomains(*exportFile, *exportRegistrar, report, renderOpts, *exportConfident)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitFailure
+		}
+		fmt.Fprintf(os.Stderr, "Export: wrote %d available domains to %s\n", written, *exportFile)
+	}
+
+	if *exportICS != "" {
+		skipped, err := writeICSFile(*exportICS, results, *icsDropDays)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitFailure
+		}
+		if skipped > 0 {
+			fmt.Fprintf(os.Stderr, "Calendar: skipped %d taken domains without a known expiry date\n", skipped)
+		}
+	}
+
+	if cache != nil && !*offline {
+		if err := cache.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
+	if history != nil {
+		if err := history.Record(report.RunInfo, freshResults(results)); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		} else if err := history.SetWhoisQueries(report.ID, whoisChecker.Quota.queries()); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
+	summary := formatSummaryMessage(results)
+	if *changesOnly {
+		summary = formatChangesMessage(changes, unchanged)
+	}
+	if *notifySummary {
+		for _, err := range sendMessages(context.Background(), notifiers, summary) {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
+	if email != nil {
+		if err := email.SendReport(context.Background(), "Domain check report", summary, report.Results); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: email report failed: %v\n", err)
+		}
+	}
+
+	if err := stdout.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "Note: stdout was closed before all results were written (%v); the run finished anyway\n", err)
+	}
+
+	if *expect != "" {
+		if offenders := unexpectedResults(results, Status(*expect)); len(offenders) > 0 {
+			printExpectationFailure(os.Stderr, offenders, Status(*expect), len(results))
+			return exitExpectationFailed
+		}
+	}
+	if len(report.Mismatches) > 0 {
+		fmt.Fprintf(os.Stderr, "\n%s EXPECTATIONS FAILED: %d of %d domains in %s do not match\n", sym.Warning, len(report.Mismatches), len(expectations), *expectFile)
+		return exitExpectationFailed
+	}
+
+	if budgetExhausted {
+		return exitBudgetExhausted
+	}
+	if whoisChecker.Quota.exhausted() {
+		return exitQuotaExhausted
+	}
+	if stdout.Err() != nil {
+		return exitStdoutClosed
+	}
+	return exitOK
+}
+
+// recheck configures the passes that replay failed checks after the main
+// one.
+type recheck struct {
+	MaxPasses int
+	// Always rechecks failures even when no server rate limited the run.
+	Always   bool
+	Cooldown time.Duration
+	// Deferred is the longer cooldown of passes that recheck replies a
+	// registry deferred, which always get rechecked.
+	Deferred time.Duration
+}
+
+// recheckFailures checks the domains that failed in a way a later attempt
+// may not again, after a cooldown, and replaces their errors with the new
+// results. Passes continue while failures remain, up to MaxPasses.
+func recheckFailures(ctx context.Context, results []DomainResult, opts recheck, pacing Pacing, check checkFunc) []DomainResult {
+	for pass := 2; pass <= opts.MaxPasses; pass++ {
+		var failed []int
+		rateLimited, deferred := false, false
+		for i, result := range results {
+			if result.Candidate == nil {
+				continue
+			}
+			var cooldown *cooldownError
+			if errors.As(result.Error, &cooldown) {
+				// The server stays in cooldown well past any pass.
+				continue
+			}
+			if result.Status == StatusDeferred {
+				failed = append(failed, i)
+				deferred = true
+				continue
+			}
+			if result.Status != StatusError {
+				continue
+			}
+			category := categorizeError(result.Error)
+			if category.Retriable() || category == ErrorStalled {
+				failed = append(failed, i)
+			}
+			rateLimited = rateLimited || category == ErrorRateLimited
+		}
+		if len(failed) == 0 || !opts.Always && !rateLimited && !deferred {
+			break
+		}
+
+		label := "Second pass"
+		if pass > 2 {
+			label = fmt.Sprintf("Pass %d", pass)
+		}
+		cooldown := opts.Cooldown
+		if deferred {
+			cooldown = max(cooldown, opts.Deferred)
+		}
+		fmt.Fprintf(os.Stderr, "%s: rechecking %d domains after a %s cooldown...\n", label, len(failed), cooldown)
+		select {
+		case <-ctx.Done():
+			return results
+		case <-time.After(cooldown):
+		}
+
+		candidates := make([]Candidate, len(failed))
+		byDomain := map[string][]int{}
+		for i, idx := range failed {
+			candidates[i] = *results[idx].Candidate
+			byDomain[results[idx].Domain] = append(byDomain[results[idx].Domain], idx)
+		}
+		stream := checkDomainsStream(ctx, candidates, pacing, check)
+		var rechecked []DomainResult
+		if detectConsole(os.Stderr).ANSI {
+			rechecked = collectWithProgress(os.Stderr, stream, len(candidates), pacing)
+		} else {
+			rechecked = collect(stream)
+		}
+		for _, result := range rechecked {
+			if indexes := byDomain[result.Domain]; len(indexes) > 0 {
+				for i := range result.Attempts {
+					result.Attempts[i].Pass = pass
+				}
+				result.Attempts = append(slices.Clip(results[indexes[0]].Attempts), result.Attempts...)
+				results[indexes[0]] = result
+				byDomain[result.Domain] = indexes[1:]
+			}
+		}
+	}
+	return results
+}
+
+// appendUnchecked adds an unchecked result, with reason as its error, for
+// every domain that got no result.
+func appendUnchecked(results []DomainResult, domains []Candidate, reason error) []DomainResult {
+	seen := map[string]int{}
+	for _, result := range results {
+		seen[result.Domain]++
+	}
+	for _, candidate := range domains {
+		if seen[candidate.FQDN] > 0 {
+			seen[candidate.FQDN]--
+			continue
+		}
+		results = append(results, DomainResult{Domain: candidate.FQDN, Status: StatusUnchecked, Candidate: &candidate, Error: reason})
+	}
+	return results
+}
+
+// secretFlags are never echoed back in reports.
+var secretFlags = map[string]bool{
+	"smtp-pass":      true,
+	"telegram-token": true,
+	"slack-webhook":  true,
+}
+
+// runParameters describes the flags a run was started with, e.g.
+// "-domains-file=brand.txt -expect=available".
+func runParameters(fs *flag.FlagSet) string {
+	var params []string
+	fs.Visit(func(f *flag.Flag) {
+		value := f.Value.String()
+		if secretFlags[f.Name] {
+			value = "***"
+		}
+		params = append(params, fmt.Sprintf("-%s=%s", f.Name, value))
+	})
+	return strings.Join(params, " ")
+}
+
+// freshResults drops results that were not checked in this run.
+func freshResults(results []DomainResult) []DomainResult {
+	var fresh []DomainResult
+	for _, result := range results {
+		if !result.Cached && result.Status != StatusUnchecked {
+			fresh = append(fresh, result)
+		}
+	}
+	return fresh
+}
+
+// unexpectedResults returns the results whose status differs from expected.
+// Errors always count, since the expectation could not be verified.
+func unexpectedResults(results []DomainResult, expected Status) []DomainResult {
+	var offenders []DomainResult
+	for _, result := range results {
+		if result.Status != expected {
+			offenders = append(offenders, result)
+		}
+	}
+	return offenders
+}
+
+func printExpectationFailure(w io.Writer, offenders []DomainResult, expected Status, total int) {
+	fmt.Fprintf(w, "\n%s EXPECTATION FAILED: %d of %d domains are not %s\n", sym.Warning, len(offenders), total, expected)
+	for _, result := range offenders {
+		line := fmt.Sprintf("  %s: %s", result.Domain, result.Status)
+		if result.Registrar != "" {
+			line += ", registrar " + result.Registrar
+		}
+		if !result.CreatedAt.IsZero() {
+			line += ", created " + result.CreatedAt.Format("2006-01-02")
+		}
+		if result.Error != nil {
+			line += fmt.Sprintf(" (%v)", result.Error)
+		}
+		fmt.Fprintln(w, line)
+	}
 }
 
 func parseKeywords(input string) []string {
@@ -98,128 +1377,347 @@ func parseKeywords(input string) []string {
 	return keywords
 }
 
-func parseKeywordLists(input string) [][]string {
-	lists := strings.Split(input, ";")
-	result := make([][]string, 0, len(lists))
-	for _, list := range lists {
+// parseKeywordLists reads -lists. A list may be named with a "name:"
+// prefix, as in "prefix:get,try;root:cloud"; labels holds the names, ""
+// for unnamed lists, and is nil when no list is named.
+func parseKeywordLists(input string) (lists [][]string, labels []string) {
+	named := false
+	for _, list := range strings.Split(input, ";") {
+		label := ""
+		if name, rest, ok := strings.Cut(list, ":"); ok && isListLabel(strings.TrimSpace(name)) {
+			label, list = strings.TrimSpace(name), rest
+			named = true
+		}
 		keywords := parseKeywords(list)
 		if len(keywords) > 0 {
-			result = append(result, keywords)
+			lists = append(lists, keywords)
+			labels = append(labels, label)
 		}
 	}
-	return result
+	if !named {
+		labels = nil
+	}
+	return lists, labels
 }
 
-func parseTLDs(input string) []string {
-	tlds := parseKeywords(input)
-	for i := range tlds {
-		tlds[i] = strings.TrimPrefix(tlds[i], ".")
+func isListLabel(s string) bool {
+	if s == "" {
+		return false
 	}
-	return tlds
+	for _, r := range s {
+		if !isLDH(r) && r != '-' && r != '_' {
+			return false
+		}
+	}
+	return true
 }
 
-func generateDomains(config Config) []string {
-	var domains []string
+// tldPresets are named TLD sets usable as "@name" in -tlds.
+var tldPresets = map[string][]string{
+	"popular": {"com", "net", "org", "io", "co", "app", "dev"},
+	"tech":    {"io", "dev", "app", "ai", "tech", "cloud", "sh"},
+	"startup": {"com", "io", "co", "ai", "app", "so"},
+	"cheap":   {"xyz", "site", "online", "store", "fun"},
+	"suggest": {"io", "co", "app", "dev", "net", "org"},
+}
 
-	if len(config.Keywords) == 1 {
-		combinations := generateCombinations(config.Keywords[0], config.Combinations)
-		for _, combo := range combinations {
-			domainName := strings.Join(combo, config.Separator)
-			for _, tld := range config.TLDs {
-				domains = append(domains, fmt.Sprintf("%s.%s", domainName, tld))
-			}
+// tldOptions are the flags choosing the TLDs to check.
+type tldOptions struct {
+	// List is -tlds, File -tlds-file and Exclude -exclude-tlds.
+	List, File, Exclude string
+}
+
+// parseTLDs expands the presets of -tlds and -tlds-file, whose TLDs follow
+// those of -tlds, and drops the TLDs already listed, keeping the order of
+// first appearance. Each duplicate is warned about with where it came
+// from, a literal TLD, a preset or the file, grouped by the pair of sources
+// so two overlapping presets give one warning. -exclude-tlds, which takes
+// presets too, removes TLDs whatever listed them, and duplicates it removes
+// are not warned about.
+func parseTLDs(opts tldOptions) (tlds []string, warnings []string, err error) {
+	type overlap struct{ first, second string }
+	source := map[string]string{}
+	var order []overlap
+	dups := map[overlap][]string{}
+	add := func(tld, from string) {
+		tld = strings.ToLower(strings.TrimPrefix(tld, "."))
+		if tld == "" {
+			return
 		}
-	} else {
-		crossProducts := crossProduct(config.Keywords)
-		for _, product := range crossProducts {
-			domainName := strings.Join(product, config.Separator)
-			for _, tld := range config.TLDs {
-				domains = append(domains, fmt.Sprintf("%s.%s", domainName, tld))
+		if first, ok := source[tld]; ok {
+			key := overlap{first, from}
+			if _, ok := dups[key]; !ok {
+				order = append(order, key)
 			}
+			dups[key] = append(dups[key], tld)
+			return
 		}
+		source[tld] = from
+		tlds = append(tlds, tld)
 	}
-
-	return domains
-}
-
-func generateCombinations(keywords []string, n int) [][]string {
-	if n <= 0 || n > len(keywords) {
-		return [][]string{}
+	// expand calls add for the TLDs of entry, labelled literal unless it
+	// names a preset.
+	expand := func(entry, literal string, add func(tld, from string)) error {
+		name, ok := strings.CutPrefix(entry, "@")
+		if !ok {
+			add(entry, literal)
+			return nil
+		}
+		preset, ok := tldPresets[name]
+		if !ok {
+			return fmt.Errorf("unknown TLD preset %q", entry)
+		}
+		for _, tld := range preset {
+			add(tld, entry)
+		}
+		return nil
 	}
 
-	var result [][]string
-	var current []string
+	for _, tld := range parseKeywords(opts.List) {
+		if err := expand(tld, fmt.Sprintf("%q", tld), add); err != nil {
+			return nil, nil, err
+		}
+	}
+	if opts.File != "" {
+		entries, err := readTLDsFile(opts.File)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, tld := range entries {
+			if err := expand(tld, opts.File, add); err != nil {
+				return nil, nil, fmt.Errorf("TLDs file %s: %w", opts.File, err)
+			}
+		}
+	}
 
-	var backtrack func(start int)
-	backtrack = func(start int) {
-		if len(current) == n {
-			combo := make([]string, len(current))
-			copy(combo, current)
-			result = append(result, combo)
-			return
+	excluded := map[string]bool{}
+	for _, entry := range parseKeywords(opts.Exclude) {
+		err := expand(entry, "", func(tld, from string) {
+			tld = strings.ToLower(strings.TrimPrefix(tld, "."))
+			if _, listed := source[tld]; !listed && from == "" && tld != "" {
+				warnings = append(warnings, fmt.Sprintf("-exclude-tlds lists .%s, which is not checked anyway", tld))
+			}
+			excluded[tld] = true
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("-exclude-tlds: %w", err)
 		}
+	}
+	if len(excluded) > 0 {
+		tlds = slices.DeleteFunc(tlds, func(tld string) bool { return excluded[tld] })
+		if len(tlds) == 0 {
+			return nil, nil, fmt.Errorf("-exclude-tlds leaves no TLD to check")
+		}
+	}
 
-		for i := start; i < len(keywords); i++ {
-			current = append(current, keywords[i])
-			backtrack(i + 1)
-			current = current[:len(current)-1]
+	lists := "-tlds lists"
+	if opts.File != "" {
+		lists = "-tlds and -tlds-file list"
+	}
+	for _, key := range order {
+		var names []string
+		for _, tld := range dups[key] {
+			if !excluded[tld] {
+				names = append(names, "."+tld)
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+		list := strings.Join(names, ", ")
+		if key.first == key.second {
+			warnings = append(warnings, fmt.Sprintf("%s %s more than once, checked once", lists, list))
+			continue
 		}
+		warnings = append(warnings, fmt.Sprintf("%s %s in both %s and %s, checked once", lists, list, key.first, key.second))
 	}
+	return tlds, warnings, nil
+}
+
+// readTLDsFile reads -tlds-file: TLDs and @presets separated by commas,
+// spaces or lines. Text after # is a comment.
+func readTLDsFile(path string) ([]string, error) {
+	file, err := openInput(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading TLDs file: %w", err)
+	}
+	defer file.Close()
 
-	backtrack(0)
-	return result
+	var tlds []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		tlds = append(tlds, strings.FieldsFunc(text, func(r rune) bool {
+			return r == ',' || unicode.IsSpace(r)
+		})...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading TLDs file: %w", err)
+	}
+	return tlds, nil
 }
 
-func crossProduct(lists [][]string) [][]string {
-	if len(lists) == 0 {
-		return [][]string{}
+func generateDomains(config Config) []Candidate {
+	opts := namegen.Options{
+		Lists:        config.Keywords,
+		Combinations: config.Combinations,
+		Separators:   config.Separators,
+		Prefixes:     config.Prefixes,
+		Suffixes:     config.Suffixes,
+	}
+	if len(config.Translations) > 0 {
+		opts.Filter = func(name namegen.Name) bool {
+			return !repeatsMeaning(name.Keywords, config.Translations)
+		}
+	}
+	if len(config.JoinTemplates) > 0 {
+		opts.Join = func(words []string) []string {
+			var bases []string
+			for _, template := range config.JoinTemplates[len(words)] {
+				bases = append(bases, template.join(words))
+			}
+			return bases
+		}
 	}
 
-	if len(lists) == 1 {
-		result := make([][]string, len(lists[0]))
-		for i, item := range lists[0] {
-			result[i] = []string{item}
+	gen := namegen.NewGenerator(opts)
+	names := make([]Candidate, 0, min(gen.Count(), 1<<16))
+	for name, ok := gen.Next(); ok; name, ok = gen.Next() {
+		candidate := Candidate{BaseName: name.Base, Keywords: name.Keywords, Variant: string(name.Kind), Affix: name.Affix}
+		candidate.Language = nameLanguage(name.Keywords, config.Translations)
+		for _, keyword := range name.Keywords {
+			candidate.Priority += config.Priorities[keyword]
+		}
+		if len(config.Keywords) > 1 && config.ListLabels != nil {
+			candidate.Lists = config.ListLabels
 		}
-		return result
+		names = append(names, candidate)
+	}
+	names = dedupNames(names)
+	if config.Anagrams {
+		names = append(names, anagramNames(config.Keywords, names)...)
 	}
 
-	subProduct := crossProduct(lists[1:])
-	var result [][]string
+	return withTLDs(names, config.TLDs)
+}
 
-	for _, item := range lists[0] {
-		for _, sub := range subProduct {
-			product := make([]string, 0, len(sub)+1)
-			product = append(product, item)
-			product = append(product, sub...)
-			result = append(result, product)
+// withTLDs returns every name under every TLD, names first.
+func withTLDs(names []Candidate, tlds []string) []Candidate {
+	var candidates []Candidate
+	for _, name := range names {
+		for _, tld := range tlds {
+			candidate := name
+			candidate.TLD = tld
+			candidate.FQDN = fmt.Sprintf("%s.%s", name.BaseName, tld)
+			candidates = append(candidates, candidate)
 		}
 	}
+	return candidates
+}
 
-	return result
+type Status string
+
+const (
+	StatusAvailable Status = "available"
+	StatusTaken     Status = "taken"
+	StatusError     Status = "error"
+	// StatusReserved is a name the registry blocks from registration.
+	StatusReserved Status = "reserved"
+	// StatusUnknown is a reply none of the availability patterns recognised.
+	StatusUnknown Status = "unknown"
+	// StatusDeferred is a reply from a registry that could not answer, such
+	// as a maintenance notice; it says nothing about the domain.
+	StatusDeferred Status = "deferred"
+	// StatusUnchecked is used offline for domains missing from the cache.
+	StatusUnchecked Status = "unchecked"
+	// StatusExists and StatusAbsent are the verdicts for subdomains, which
+	// are looked up in DNS rather than registered.
+	StatusExists Status = "exists"
+	StatusAbsent Status = "absent"
+)
+
+// IsVerdict reports whether the status says something about the domain,
+// as opposed to the check failing or being skipped.
+func (s Status) IsVerdict() bool {
+	switch s {
+	case StatusAvailable, StatusTaken, StatusReserved, StatusExists, StatusAbsent:
+		return true
+	}
+	return false
 }
 
 type DomainResult struct {
 	Domain    string
-	Available bool
+	Status    Status
+	EPPStatus []string
+	Registrar string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	Method    string
+	CheckedAt time.Time
+	Duration  time.Duration
+	Suggested bool
+	Score     float64
+	Handles   map[string]HandleStatus
+	Cached    bool
+	Links     []PurchaseLink
+	Price     *Price
+	RunID     string
+	// Server is the whois server whose reply was classified, and Referral
+	// the registrar server -details asked after it.
+	Server   string
+	Referral string
+	// Confidence is "low" for verdicts drawn from indirect evidence, such as
+	// the DNS fallback.
+	Confidence string
+	// Candidate says how the domain was produced; nil for results read
+	// from older files.
+	Candidate *Candidate
 	Error     error
+	// Attempts are the steps that led to the verdict, in order.
+	Attempts []Attempt
+	// RawExcerpt is the start of the reply behind an error or unknown
+	// verdict, when there was one.
+	RawExcerpt string
 }
 
-func checkDomainsConcurrently(domains []string, workers int) []DomainResult {
-	jobs := make(chan string, len(domains))
+// checkFunc checks a single domain. WhoisChecker.Check is the only
+// implementation that touches the network; the cache wraps or replaces it.
+type checkFunc func(domain string) DomainResult
+
+func checkDomainsConcurrently(ctx context.Context, domains []Candidate, pacing Pacing, check checkFunc) []DomainResult {
+	return collect(checkDomainsStream(ctx, domains, pacing, check))
+}
+
+func collect(stream <-chan DomainResult) []DomainResult {
+	var allResults []DomainResult
+	for result := range stream {
+		allResults = append(allResults, result)
+	}
+	return allResults
+}
+
+// checkDomainsStream checks domains on a pool of workers and delivers each
+// result as soon as it is ready. The channel is closed once all workers are
+// done; after ctx is cancelled, remaining domains are skipped.
+func checkDomainsStream(ctx context.Context, domains []Candidate, pacing Pacing, check checkFunc) <-chan DomainResult {
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	jobs := make(chan Candidate, len(domains))
 	results := make(chan DomainResult, len(domains))
 
 	var wg sync.WaitGroup
-	for i := 0; i < workers; i++ {
+	for i := 0; i < pacing.Workers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			for domain := range jobs {
-				available, err := checkDomain(domain)
-				results <- DomainResult{
-					Domain:    domain,
-					Available: available,
-					Error:     err,
+			for candidate := range jobs {
+				if ctx.Err() != nil {
+					continue
 				}
+				result := check(candidate.FQDN)
+				result.Candidate = &candidate
+				results <- result
 			}
 		}()
 	}
@@ -234,81 +1732,66 @@ func checkDomainsConcurrently(domains []string, workers int) []DomainResult {
 		close(results)
 	}()
 
-	var allResults []DomainResult
-	for result := range results {
-		allResults = append(allResults, result)
-	}
-
-	return allResults
+	return watchStalls(parent, results, domains, pacing.stallTimeout(), cancel)
 }
 
-func checkDomain(domain string) (bool, error) {
-	result, err := whois.Whois(domain)
-	if err != nil {
-		return false, err
-	}
-
-	result = strings.ToLower(result)
-	
-	if strings.Contains(result, "no match") ||
-		strings.Contains(result, "not found") ||
-		strings.Contains(result, "no entries found") ||
-		strings.Contains(result, "no data found") ||
-		strings.Contains(result, "available for registration") ||
-		strings.Contains(result, "status: free") {
-		return true, nil
-	}
-
-	if strings.Contains(result, "domain name:") ||
-		strings.Contains(result, "registrar:") ||
-		strings.Contains(result, "creation date:") ||
-		strings.Contains(result, "expiration date:") ||
-		strings.Contains(result, "updated date:") {
-		return false, nil
-	}
-
-	return false, nil
-}
-
-func printResults(results []DomainResult) {
-	available := []string{}
-	taken := []string{}
-	errors := []DomainResult{}
-
-	for _, result := range results {
-		if result.Error != nil {
-			errors = append(errors, result)
-		} else if result.Available {
-			available = append(available, result.Domain)
-		} else {
-			taken = append(taken, result.Domain)
-		}
-	}
+// watchStalls passes results through until none has arrived for timeout
+// while domains are still pending. The pending domains are then reported as
+// stalled errors, so a query that hangs forever does not take the results
+// gathered so far down with it. cancel stops the jobs not started yet.
+// Once ctx is done, checks still running are not waited for.
+func watchStalls(ctx context.Context, results <-chan DomainResult, domains []Candidate, timeout time.Duration, cancel context.CancelFunc) <-chan DomainResult {
+	out := make(chan DomainResult, len(domains))
+	go func() {
+		defer close(out)
+		defer cancel()
 
-	if len(available) > 0 {
-		fmt.Printf("✓ AVAILABLE (%d):\n", len(available))
-		for _, domain := range available {
-			fmt.Printf("  %s\n", domain)
+		pending := make(map[string]int, len(domains))
+		for _, candidate := range domains {
+			pending[candidate.FQDN]++
 		}
-		fmt.Println()
-	}
 
-	if len(taken) > 0 {
-		fmt.Printf("✗ TAKEN (%d):\n", len(taken))
-		for _, domain := range taken {
-			fmt.Printf("  %s\n", domain)
+		var stall <-chan time.Time
+		var timer *time.Timer
+		if timeout > 0 {
+			timer = time.NewTimer(timeout)
+			defer timer.Stop()
+			stall = timer.C
 		}
-		fmt.Println()
-	}
-
-	if len(errors) > 0 {
-		fmt.Printf("⚠ ERRORS (%d):\n", len(errors))
-		for _, result := range errors {
-			fmt.Printf("  %s: %v\n", result.Domain, result.Error)
+		for {
+			select {
+			case result, ok := <-results:
+				if !ok {
+					return
+				}
+				pending[result.Domain]--
+				out <- result
+				if timer != nil {
+					timer.Reset(timeout)
+				}
+			case <-ctx.Done():
+				return
+			case <-stall:
+				var abandoned []string
+				for _, candidate := range domains {
+					if pending[candidate.FQDN] <= 0 {
+						continue
+					}
+					pending[candidate.FQDN]--
+					abandoned = append(abandoned, candidate.FQDN)
+					out <- DomainResult{
+						Domain:    candidate.FQDN,
+						Status:    StatusError,
+						CheckedAt: time.Now(),
+						Candidate: &candidate,
+						Error:     fmt.Errorf("%w (%s without progress)", errStalled, timeout),
+					}
+				}
+				fmt.Fprintf(os.Stderr, "Warning: no result for %s, abandoned %d checks: %s\n",
+					timeout, len(abandoned), strings.Join(abandoned, ", "))
+				return
+			}
 		}
-		fmt.Println()
-	}
-
-	fmt.Printf("Summary: %d available, %d taken, %d errors (total: %d)\n",
-		len(available), len(taken), len(errors), len(results))
+	}()
+	return out
 }
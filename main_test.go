@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestRecheckDeferred checks that domains a registry deferred are rechecked
+// without -second-pass, after the longer deferred cooldown, and that those
+// deferred again stay deferred for the report.
+func TestRecheckDeferred(t *testing.T) {
+	var mu sync.Mutex
+	var rechecked []string
+	check := func(domain string) DomainResult {
+		mu.Lock()
+		rechecked = append(rechecked, domain)
+		mu.Unlock()
+		if domain == "example.jp" {
+			return DomainResult{Domain: domain, Status: StatusDeferred, Server: "whois.jprs.jp"}
+		}
+		return DomainResult{Domain: domain, Status: StatusTaken}
+	}
+	results := []DomainResult{
+		{Domain: "example.jp", Status: StatusDeferred, Server: "whois.jprs.jp"},
+		{Domain: "example.ru", Status: StatusDeferred, Server: "whois.tcinet.ru"},
+		{Domain: "example.com", Status: StatusTaken},
+		{Domain: "example.net", Status: StatusUnknown},
+	}
+	for i := range results {
+		candidate := newCandidate(results[i].Domain, VariantExplicit)
+		results[i].Candidate = &candidate
+	}
+
+	start := time.Now()
+	results = recheckFailures(context.Background(), results, recheck{MaxPasses: 2, Deferred: 50 * time.Millisecond}, Pacing{Workers: 2}, check)
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("recheck started after %s, before the deferred cooldown", elapsed)
+	}
+	if len(rechecked) != 2 || !strings.Contains(strings.Join(rechecked, " "), "example.jp") || !strings.Contains(strings.Join(rechecked, " "), "example.ru") {
+		t.Errorf("rechecked %v, want example.jp and example.ru", rechecked)
+	}
+	want := map[string]Status{"example.jp": StatusDeferred, "example.ru": StatusTaken, "example.com": StatusTaken, "example.net": StatusUnknown}
+	for _, result := range results {
+		if result.Status != want[result.Domain] {
+			t.Errorf("%s: status = %s, want %s", result.Domain, result.Status, want[result.Domain])
+		}
+	}
+
+	// What the registry still deferred has a section of its own, naming the
+	// server at fault.
+	var b strings.Builder
+	printResults(&b, results, RenderOptions{})
+	out := b.String()
+	section := strings.Index(out, "DEFERRED, THE REGISTRY COULD NOT ANSWER (1)")
+	if section < 0 || !strings.Contains(out[section:], "example.jp [whois.jprs.jp]") {
+		t.Errorf("output lacks the deferred section:\n%s", out)
+	}
+}
+
+// runCaptured runs the command line with stdout discarded, and returns the
+// exit code, what was written to stderr and the domains in the JSON report.
+func runCaptured(t *testing.T, args ...string) (int, string, []string) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CACHE_HOME", dir)
+
+	devnull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devnull.Close()
+	errFile, err := os.Create(filepath.Join(dir, "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer errFile.Close()
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = devnull, errFile
+	defer func() { os.Stdout, os.Stderr = stdout, stderr }()
+
+	commandLine := flag.CommandLine
+	flag.CommandLine = flag.NewFlagSet("domain-checker", flag.ContinueOnError)
+	defer func() { flag.CommandLine = commandLine }()
+
+	output := filepath.Join(dir, "results.json")
+	code := run(append(args, "-output="+output), false)
+
+	logged, err := os.ReadFile(errFile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var domains []string
+	if data, err := os.ReadFile(output); err == nil {
+		var report Report
+		if err := json.Unmarshal(data, &report); err != nil {
+			t.Fatal(err)
+		}
+		for _, result := range report.Results {
+			domains = append(domains, result.Domain)
+		}
+		slices.Sort(domains)
+	}
+	return code, string(logged), domains
+}
+
+// TestKeywordsWithSuffixes checks keywords given as domains, including ones
+// under multi-label suffixes such as co.uk: they are stripped to their name
+// with a warning, or refused with -strict.
+func TestKeywordsWithSuffixes(t *testing.T) {
+	for _, tt := range []struct {
+		keywords string
+		strict   bool
+		code     int
+		logged   string
+		domains  []string
+	}{
+		{"myapp.co.uk", false, exitOK, "treated 'myapp.co.uk' as 'myapp'", []string{"myappcloud.io"}},
+		{"www.myapp.co.uk", false, exitOK, "treated 'www.myapp.co.uk' as 'myapp'", []string{"myappcloud.io"}},
+		{"myapp.com.au", false, exitOK, "treated 'myapp.com.au' as 'myapp'", []string{"myappcloud.io"}},
+		{"myapp.co.uk", true, exitFailure, `keyword "myapp.co.uk" is a domain, not a name; pass "myapp"`, nil},
+		{"co.uk", false, exitFailure, `keyword "co.uk" has no name left of its TLD`, nil},
+		{"myapp", true, exitOK, "", []string{"myappcloud.io"}},
+	} {
+		args := []string{"-backend=fake", "-keywords=" + tt.keywords + ",cloud", "-tlds=io"}
+		if tt.strict {
+			args = append(args, "-strict")
+		}
+		code, logged, domains := runCaptured(t, args...)
+		if code != tt.code {
+			t.Errorf("%v: exit code = %d, want %d\n%s", args, code, tt.code, logged)
+		}
+		if tt.logged != "" && !strings.Contains(logged, tt.logged) || tt.logged == "" && strings.Contains(logged, "treated") {
+			t.Errorf("%v: stderr = %q, want %q", args, logged, tt.logged)
+		}
+		if !slices.Equal(domains, tt.domains) {
+			t.Errorf("%v: checked %v, want %v", args, domains, tt.domains)
+		}
+	}
+}
+
+func TestParseTLDs(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "tlds.txt")
+	if err := os.WriteFile(file, []byte("# ours\nio, dev\n\ncom  @cheap # and the cheap ones\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name     string
+		opts     tldOptions
+		tlds     []string
+		warnings []string
+	}{
+		{
+			name: "plain",
+			opts: tldOptions{List: "com,net,org"},
+			tlds: []string{"com", "net", "org"},
+		},
+		{
+			name: "normalized",
+			opts: tldOptions{List: " .COM, io ,,"},
+			tlds: []string{"com", "io"},
+		},
+		{
+			name:     "repeated literal",
+			opts:     tldOptions{List: "com,io,com,io"},
+			tlds:     []string{"com", "io"},
+			warnings: []string{"-tlds lists .com more than once, checked once", "-tlds lists .io more than once, checked once"},
+		},
+		{
+			name:     "literal after preset",
+			opts:     tldOptions{List: "@popular,com,ai"},
+			tlds:     []string{"com", "net", "org", "io", "co", "app", "dev", "ai"},
+			warnings: []string{`-tlds lists .com in both @popular and "com", checked once`},
+		},
+		{
+			name:     "overlapping presets",
+			opts:     tldOptions{List: "@startup,@tech"},
+			tlds:     []string{"com", "io", "co", "ai", "app", "so", "dev", "tech", "cloud", "sh"},
+			warnings: []string{"-tlds lists .io, .app, .ai in both @startup and @tech, checked once"},
+		},
+		{
+			name:     "repeated preset",
+			opts:     tldOptions{List: "@cheap,@cheap"},
+			tlds:     []string{"xyz", "site", "online", "store", "fun"},
+			warnings: []string{"-tlds lists .xyz, .site, .online, .store, .fun more than once, checked once"},
+		},
+		{
+			name: "file after list",
+			opts: tldOptions{List: "ai", File: file},
+			tlds: []string{"ai", "io", "dev", "com", "xyz", "site", "online", "store", "fun"},
+		},
+		{
+			name:     "file overlapping list",
+			opts:     tldOptions{List: "com,@tech", File: file},
+			tlds:     []string{"com", "io", "dev", "app", "ai", "tech", "cloud", "sh", "xyz", "site", "online", "store", "fun"},
+			warnings: []string{"-tlds and -tlds-file list .io, .dev in both @tech and " + file + ", checked once", `-tlds and -tlds-file list .com in both "com" and ` + file + ", checked once"},
+		},
+		{
+			name: "exclude",
+			opts: tldOptions{List: "@popular", Exclude: "net,.ORG"},
+			tlds: []string{"com", "io", "co", "app", "dev"},
+		},
+		{
+			name: "exclude preset",
+			opts: tldOptions{List: "@popular,ai,xyz", Exclude: "@tech"},
+			tlds: []string{"com", "net", "org", "co", "xyz"},
+		},
+		{
+			name: "exclude wins over file",
+			opts: tldOptions{File: file, Exclude: "@cheap,com"},
+			tlds: []string{"io", "dev"},
+		},
+		{
+			// Duplicates that are excluded are not checked at all.
+			name:     "exclude a duplicate",
+			opts:     tldOptions{List: "@popular,com,io", Exclude: "com"},
+			tlds:     []string{"net", "org", "io", "co", "app", "dev"},
+			warnings: []string{`-tlds lists .io in both @popular and "io", checked once`},
+		},
+		{
+			name:     "exclude not listed",
+			opts:     tldOptions{List: "com,io", Exclude: "io,de,@cheap"},
+			tlds:     []string{"com"},
+			warnings: []string{"-exclude-tlds lists .de, which is not checked anyway"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tlds, warnings, err := parseTLDs(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(tlds, tt.tlds) {
+				t.Errorf("tlds = %q, want %q", tlds, tt.tlds)
+			}
+			if !slices.Equal(warnings, tt.warnings) {
+				t.Errorf("warnings = %q, want %q", warnings, tt.warnings)
+			}
+		})
+	}
+}
+
+func TestParseTLDsErrors(t *testing.T) {
+	bad := filepath.Join(t.TempDir(), "tlds.txt")
+	if err := os.WriteFile(bad, []byte("com\n@nope\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		opts tldOptions
+		err  string
+	}{
+		{tldOptions{List: "com,@nope"}, `unknown TLD preset "@nope"`},
+		{tldOptions{List: "com", File: bad}, `TLDs file ` + bad + `: unknown TLD preset "@nope"`},
+		{tldOptions{List: "com", File: bad + ".missing"}, "reading TLDs file"},
+		{tldOptions{List: "com", Exclude: "@nope"}, `-exclude-tlds: unknown TLD preset "@nope"`},
+		{tldOptions{List: "com,io", Exclude: "io,com"}, "-exclude-tlds leaves no TLD to check"},
+	} {
+		if _, _, err := parseTLDs(tt.opts); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("parseTLDs(%+v): err = %v, want %q", tt.opts, err, tt.err)
+		}
+	}
+}
+
+// TestTLDFlags checks the flags together: -tlds-file replaces the default
+// of -tlds but not a -tlds given, and a TLD listed twice is checked and
+// counted once.
+func TestTLDFlags(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tlds.txt")
+	if err := os.WriteFile(file, []byte("io\ndev\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		args    []string
+		domains []string
+	}{
+		{nil, []string{"cloudstack.com"}},
+		{[]string{"-tlds-file=" + file}, []string{"cloudstack.dev", "cloudstack.io"}},
+		{[]string{"-tlds=com", "-tlds-file=" + file}, []string{"cloudstack.com", "cloudstack.dev", "cloudstack.io"}},
+		{[]string{"-tlds=@popular,com,io", "-exclude-tlds=net,org,co,app"}, []string{"cloudstack.com", "cloudstack.dev", "cloudstack.io"}},
+	} {
+		args := append([]string{"-backend=fake", "-keywords=cloud,stack"}, tt.args...)
+		code, logged, domains := runCaptured(t, args...)
+		if code != exitOK {
+			t.Fatalf("%v: exit code = %d\n%s", args, code, logged)
+		}
+		if !slices.Equal(domains, tt.domains) {
+			t.Errorf("%v: checked %q, want %q", args, domains, tt.domains)
+		}
+		if want := fmt.Sprintf(" total=%d ", len(tt.domains)); !strings.Contains(logged, want) {
+			t.Errorf("%v: the summary does not say %q:\n%s", args, want, logged)
+		}
+	}
+}
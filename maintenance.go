@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maintenanceWindow is a recurring period during which a whois server is
+// known to answer garbage. It is either a daily range of minutes, which
+// wraps past midnight when it ends before it starts, or a cron expression
+// naming every minute of the window.
+type maintenanceWindow struct {
+	spec     string
+	from, to int
+	cron     *cronSchedule
+	loc      *time.Location
+}
+
+// defaultMaintenanceWindows are the documented windows of registries,
+// keyed by whois server. A maintenance line in -whois-servers replaces
+// the windows of its server.
+var defaultMaintenanceWindows = map[string][]string{
+	// DENIC's nightly database maintenance.
+	"whois.denic.de": {"02:00-02:30 Europe/Berlin"},
+}
+
+// parseMaintenanceWindow reads "HH:MM-HH:MM [timezone]" or a five-field
+// cron expression followed by an optional timezone. Times are UTC unless
+// a timezone is given.
+func parseMaintenanceWindow(spec string) (maintenanceWindow, error) {
+	fields := strings.Fields(spec)
+	w := maintenanceWindow{spec: strings.Join(fields, " "), loc: time.UTC}
+
+	var zone string
+	switch {
+	case len(fields) == 1 || len(fields) == 2:
+		from, to, ok := strings.Cut(fields[0], "-")
+		if !ok {
+			return w, fmt.Errorf("maintenance window %q: expected HH:MM-HH:MM or a cron expression", spec)
+		}
+		var err error
+		if w.from, err = parseClock(from); err != nil {
+			return w, fmt.Errorf("maintenance window %q: %w", spec, err)
+		}
+		if w.to, err = parseClock(to); err != nil {
+			return w, fmt.Errorf("maintenance window %q: %w", spec, err)
+		}
+		if w.from == w.to {
+			return w, fmt.Errorf("maintenance window %q is empty", spec)
+		}
+		if len(fields) == 2 {
+			zone = fields[1]
+		}
+	case len(fields) == len(cronFields) || len(fields) == len(cronFields)+1:
+		cron, err := parseCron(strings.Join(fields[:len(cronFields)], " "))
+		if err != nil {
+			return w, fmt.Errorf("maintenance window: %w", err)
+		}
+		w.cron = cron
+		if len(fields) > len(cronFields) {
+			zone = fields[len(cronFields)]
+		}
+	default:
+		return w, fmt.Errorf("maintenance window %q: expected HH:MM-HH:MM or a cron expression", spec)
+	}
+
+	if zone != "" {
+		loc, err := time.LoadLocation(zone)
+		if err != nil {
+			return w, fmt.Errorf("maintenance window %q: %w", spec, err)
+		}
+		w.loc = loc
+	}
+	return w, nil
+}
+
+// parseClock returns the minutes after midnight of "HH:MM".
+func parseClock(s string) (int, error) {
+	hour, minute, ok := strings.Cut(s, ":")
+	h, errH := strconv.Atoi(hour)
+	m, errM := strconv.Atoi(minute)
+	if !ok || errH != nil || errM != nil || h < 0 || h > 24 || m < 0 || m > 59 || h == 24 && m != 0 {
+		return 0, fmt.Errorf("invalid time %q (want HH:MM)", s)
+	}
+	return h*60 + m, nil
+}
+
+func (w maintenanceWindow) contains(t time.Time) bool {
+	t = t.In(w.loc)
+	if w.cron != nil {
+		return w.cron.matches(t)
+	}
+	minute := t.Hour()*60 + t.Minute()
+	if w.from < w.to {
+		return minute >= w.from && minute < w.to
+	}
+	return minute >= w.from || minute < w.to
+}
+
+// end returns when the window containing t is over.
+func (w maintenanceWindow) end(t time.Time) time.Time {
+	t = t.Truncate(time.Minute)
+	// A window never covers a whole week; a cron one that does is capped.
+	for limit := t.AddDate(0, 0, 7); w.contains(t) && t.Before(limit); {
+		t = t.Add(time.Minute)
+	}
+	return t
+}
+
+func (w maintenanceWindow) String() string {
+	return w.spec
+}
+
+// maintenanceError defers a query to a server in a maintenance window.
+type maintenanceError struct {
+	Host   string
+	Window maintenanceWindow
+	Until  time.Time
+}
+
+func (e *maintenanceError) Error() string {
+	return fmt.Sprintf("%v: maintenance window of %s (%s) until %s",
+		errDeferred, e.Host, e.Window, displayTime(e.Until, "15:04"))
+}
+
+func (e *maintenanceError) Unwrap() error {
+	return errDeferred
+}
+
+// maintenance returns the error deferring a query to host at t, or nil
+// when host is not in a maintenance window.
+func (c *WhoisChecker) maintenance(host string, t time.Time) *maintenanceError {
+	for _, w := range c.Maintenance[host] {
+		if w.contains(t) {
+			return &maintenanceError{Host: host, Window: w, Until: w.end(t)}
+		}
+	}
+	return nil
+}
+
+// maintenanceEnd reports when the maintenance window the server of domain
+// is in at t is over, without any network query: a TLD whose server has
+// not been looked up yet is assumed to be available.
+func (c *WhoisChecker) maintenanceEnd(domain string, t time.Time) (time.Time, bool) {
+	tld := domainTLD(domain)
+	var host string
+	if servers := c.Servers[tld]; len(servers) > 0 {
+		host = servers[0].Host
+	} else {
+		c.mu.Lock()
+		host = c.discovered[tld]
+		c.mu.Unlock()
+	}
+	if err := c.maintenance(host, t); err != nil {
+		return err.Until, true
+	}
+	return time.Time{}, false
+}
+
+// defaultMaintenance parses defaultMaintenanceWindows. A window whose
+// timezone the system has no data for is left out.
+func defaultMaintenance() map[string][]maintenanceWindow {
+	windows := map[string][]maintenanceWindow{}
+	for host, specs := range defaultMaintenanceWindows {
+		for _, spec := range specs {
+			w, err := parseMaintenanceWindow(spec)
+			if err != nil {
+				debugLog.Printf("%s: %v", host, err)
+				continue
+			}
+			windows[host] = append(windows[host], w)
+		}
+	}
+	return windows
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Manifest records what produced a run: the tool version, the host and the
+// effective value of every flag, so a results file can be traced back to
+// its configuration long after. It is read from the flag set itself, so it
+// cannot drift from what the run used.
+type Manifest struct {
+	Version string `json:"version"`
+	Host    string `json:"host,omitempty"`
+	// Command is "check", or "generate" for names invented by generate.
+	Command string `json:"command"`
+	// Flags holds every flag, defaults included, and Set names the ones
+	// given on the command line. Secrets are redacted.
+	Flags map[string]string `json:"flags"`
+	Set   []string          `json:"set,omitempty"`
+}
+
+func newManifest(fs *flag.FlagSet, command string) *Manifest {
+	host, _ := os.Hostname()
+	m := &Manifest{Version: version, Host: host, Command: command, Flags: map[string]string{}}
+	fs.VisitAll(func(f *flag.Flag) {
+		m.Flags[f.Name] = redactedFlagValue(f)
+	})
+	fs.Visit(func(f *flag.Flag) {
+		m.Set = append(m.Set, f.Name)
+	})
+	return m
+}
+
+// redactedFlagValue returns the value of f, or *** for a secret that is set,
+// whether on the command line or from the environment.
+func redactedFlagValue(f *flag.Flag) string {
+	value := f.Value.String()
+	if secretFlags[f.Name] && value != "" {
+		return "***"
+	}
+	return value
+}
+
+// lines describes the manifest in a few lines for comment blocks: only the
+// flags given are listed, the others being the defaults of the version.
+func (m *Manifest) lines(run RunInfo) []string {
+	lines := []string{fmt.Sprintf("domain-checker %s %s", m.Version, m.Command)}
+	if m.Host != "" {
+		lines = append(lines, "host: "+m.Host)
+	}
+	if !run.StartedAt.IsZero() {
+		lines = append(lines, "started: "+run.StartedAt.UTC().Format("2006-01-02 15:04:05 MST"))
+	}
+	if run.ID != "" {
+		lines = append(lines, "run: "+run.ID)
+	}
+	flags := "flags: (defaults)"
+	if len(m.Set) > 0 {
+		params := make([]string, len(m.Set))
+		for i, name := range m.Set {
+			params[i] = fmt.Sprintf("-%s=%s", name, m.Flags[name])
+		}
+		flags = "flags: " + strings.Join(params, " ")
+	}
+	return append(lines, flags)
+}
+
+// writeManifest prints the manifest in full, for 'results info'.
+func writeManifest(w io.Writer, m *Manifest, run RunInfo) {
+	fmt.Fprintf(w, "Version: %s\n", m.Version)
+	fmt.Fprintf(w, "Command: %s\n", m.Command)
+	if m.Host != "" {
+		fmt.Fprintf(w, "Host:    %s\n", m.Host)
+	}
+	if run.ID != "" {
+		fmt.Fprintf(w, "Run:     %s\n", run.ID)
+	}
+	if !run.StartedAt.IsZero() {
+		fmt.Fprintf(w, "Started: %s\n", displayTime(run.StartedAt, "2006-01-02 15:04:05"))
+	}
+
+	set := map[string]bool{}
+	for _, name := range m.Set {
+		set[name] = true
+	}
+	names := make([]string, 0, len(m.Flags))
+	for name := range m.Flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(w, "Flags (* given on the command line):")
+	for _, name := range names {
+		marker := " "
+		if set[name] {
+			marker = "*"
+		}
+		fmt.Fprintf(w, "  %s -%s=%s\n", marker, name, m.Flags[name])
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// availabilityMatrix has one row per base name and one column per TLD.
+// Cells of combinations that were never checked, e.g. TLDs dropped by
+// -max-price, are empty.
+type availabilityMatrix struct {
+	TLDs  []string
+	Names []string
+	Cells map[string]map[string]string
+}
+
+// buildMatrix groups results by base name. Names keep the check order, or
+// follow the best score of their available domains with Rank.
+func buildMatrix(results []DomainResult, opts RenderOptions) availabilityMatrix {
+	m := availabilityMatrix{Cells: map[string]map[string]string{}}
+	seenTLD := map[string]bool{}
+	best := map[string]float64{}
+	for _, result := range results {
+		if result.Status == StatusExists || result.Status == StatusAbsent {
+			continue
+		}
+		name, tld := matrixKey(result)
+		if m.Cells[name] == nil {
+			m.Cells[name] = map[string]string{}
+			m.Names = append(m.Names, name)
+		}
+		if !seenTLD[tld] {
+			seenTLD[tld] = true
+			m.TLDs = append(m.TLDs, tld)
+		}
+		m.Cells[name][tld] = matrixCell(result.Status)
+		if result.Status == StatusAvailable && result.Score > best[name] {
+			best[name] = result.Score
+		}
+	}
+	sort.Strings(m.TLDs)
+	if opts.Rank {
+		sort.SliceStable(m.Names, func(i, j int) bool { return best[m.Names[i]] > best[m.Names[j]] })
+	}
+	return m
+}
+
+func matrixKey(result DomainResult) (name, tld string) {
+	if c := result.Candidate; c != nil && c.BaseName != "" {
+		return c.BaseName, c.TLD
+	}
+	tld = domainTLD(result.Domain)
+	return strings.TrimSuffix(result.Domain, "."+tld), tld
+}
+
+// matrixCell keeps errors, reserved and unchecked names apart from taken
+// ones.
+func matrixCell(status Status) string {
+	switch status {
+	case StatusAvailable:
+		return sym.Check
+	case StatusTaken:
+		return sym.Cross
+	case StatusReserved:
+		return sym.Blocked
+	case StatusError:
+		return sym.Warning
+	case StatusUnchecked:
+		return sym.Dash
+	default:
+		return "?"
+	}
+}
+
+func matrixLegend() string {
+	return fmt.Sprintf("%s available, %s taken, %s reserved, ? unknown or deferred, %s error, %s not checked, empty: not generated",
+		sym.Check, sym.Cross, sym.Blocked, sym.Warning, sym.Dash)
+}
+
+func writeMatrixCSV(w io.Writer, report *Report, opts RenderOptions) error {
+	m := buildMatrix(report.Results, opts)
+	cw := csv.NewWriter(w)
+	header := []string{"name"}
+	for _, tld := range m.TLDs {
+		header = append(header, "."+tld)
+	}
+	cw.Write(header)
+	for _, name := range m.Names {
+		row := []string{name}
+		for _, tld := range m.TLDs {
+			row = append(row, m.Cells[name][tld])
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeMatrixMarkdown(w io.Writer, report *Report, opts RenderOptions) error {
+	m := buildMatrix(report.Results, opts)
+	fmt.Fprintf(w, "| name |")
+	for _, tld := range m.TLDs {
+		fmt.Fprintf(w, " .%s |", tld)
+	}
+	fmt.Fprintf(w, "\n|---|%s\n", strings.Repeat(":-:|", len(m.TLDs)))
+	for _, name := range m.Names {
+		fmt.Fprintf(w, "| `%s` |", name)
+		for _, tld := range m.TLDs {
+			fmt.Fprintf(w, " %s |", m.Cells[name][tld])
+		}
+		fmt.Fprintln(w)
+	}
+	_, err := fmt.Fprintf(w, "\n_%s_\n", matrixLegend())
+	return err
+}
@@ -0,0 +1,744 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+type MonitorConfig struct {
+	DomainsFile string
+	Interval    time.Duration
+	Jitter      time.Duration
+	Schedule    *cronSchedule
+	Store       monitorStore
+	Pacing      Pacing
+	Whois       *WhoisChecker
+	Webhook     *WebhookNotifier
+	// Fake answers the checks instead of Whois with -backend=fake.
+	Fake *fakeChecker
+	// WebhookSweeps posts a SweepReport after every completed sweep.
+	WebhookSweeps bool
+	Notifiers     []MessageNotifier
+	Email         *EmailNotifier
+	NotifyOn      string
+	// Hangup receives SIGHUP, which rereads the watch list at once.
+	Hangup <-chan os.Signal
+	// AlertAfterFailures alerts once a domain has failed this many checks
+	// in a row; zero disables the alert.
+	AlertAfterFailures int
+	// Spread runs each domain's check at its own slot across the interval
+	// instead of all at the start of a sweep.
+	Spread bool
+	// Health is reported to for -status-listen and the systemd watchdog;
+	// nil when neither is used.
+	Health *monitorHealth
+}
+
+// DomainState is the last known status of a watched domain.
+type DomainState struct {
+	Status    Status    `json:"status"`
+	EPPStatus []string  `json:"eppStatus,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+	ChangedAt time.Time `json:"changedAt"`
+	// NextCheckAt is when the domain is next due, for monitor status and
+	// to keep its slot across restarts with -spread.
+	NextCheckAt time.Time `json:"nextCheckAt,omitempty"`
+	// FailureStreak counts the checks failed in a row since the last
+	// verdict, and LastError is the error of the latest.
+	FailureStreak int    `json:"failureStreak,omitempty"`
+	LastError     string `json:"lastError,omitempty"`
+}
+
+type MonitorState struct {
+	Domains map[string]DomainState `json:"domains"`
+}
+
+func runMonitor(args []string) int {
+	if len(args) > 0 && args[0] == "status" {
+		return runMonitorStatus(args[1:])
+	}
+
+	fs := flag.NewFlagSet("monitor", flag.ExitOnError)
+	domainsFile := fs.String("domains-file", "", "File with one domain per line to watch (required)")
+	interval := fs.Duration("interval", 6*time.Hour, "Time between sweeps")
+	jitter := fs.Duration("jitter", 5*time.Minute, "Maximum random delay added to each sweep")
+	schedule := fs.String("schedule", "", "Cron schedule for sweeps (e.g. '0 */6 * * *'), used instead of -interval; read in the -timezone zone")
+	spread := fs.Bool("spread", false, "Spread the checks evenly across -interval instead of running them all at the start of a sweep (-jitter is ignored)")
+	stateFile := fs.String("state", "domain-checker-state.json", "File where the last known status of each domain is kept (ignored with -history)")
+	historyPath := fs.String("history", "", "SQLite history database used to record checks and keep the monitor state")
+	pacingFlags := addPacingFlags(fs)
+	webhook := fs.String("webhook", "", "URL to POST a JSON payload to whenever a domain changes status")
+	webhookSecret := fs.String("webhook-secret", "", "Secret used to sign webhook payloads (HMAC-SHA256)")
+	webhookSweeps := fs.Bool("webhook-sweeps", false, "Also POST the summary and per-TLD counts to -webhook after every completed sweep")
+	alertAfterFailures := fs.Int("alert-after-failures", 0, "Alert the notifiers once a domain has failed this many checks in a row (0 to never alert)")
+	notifyOn := fs.String("notify-on", "available", "Which status changes trigger notifications: available, taken (available to taken, someone registered it) or any")
+	notifyFlags := addNotifyFlags(fs)
+	emailFlags := addEmailFlags(fs)
+	whoisFlags := addWhoisFlags(fs)
+	timezone := addTimezoneFlag(fs)
+	debugFlag := fs.Bool("debug", false, "Log diagnostics such as connection budget waits to stderr")
+	statusListen := fs.String("status-listen", "", "Address to serve /healthz, /readyz and /status on (e.g. ':9090'); off by default")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Domain Checker - Monitor domains for status changes\n\n")
+		fmt.Fprintf(os.Stderr, "Usage:\n")
+		fmt.Fprintf(os.Stderr, "  %s monitor [options]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  # Re-check watched domains every 6 hours\n")
+		fmt.Fprintf(os.Stderr, "  %s monitor -domains-file=watch.txt -interval=6h -state=state.json\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Re-check at minute 0 of every sixth hour\n")
+		fmt.Fprintf(os.Stderr, "  %s monitor -domains-file=watch.txt -schedule=\"0 */6 * * *\"\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Check 500 domains at an even pace, one every 1.8 seconds\n")
+		fmt.Fprintf(os.Stderr, "  %s monitor -domains-file=watch.txt -interval=15m -spread\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  # Show when each domain is next due\n")
+		fmt.Fprintf(os.Stderr, "  %s monitor status -state=state.json\n\n", os.Args[0])
+	}
+
+	fs.Parse(args)
+	if err := setDisplayZone(*timezone); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if *domainsFile == "" {
+		fmt.Fprintf(os.Stderr, "Error: -domains-file must be provided\n\n")
+		fs.Usage()
+		return 1
+	}
+
+	config := MonitorConfig{
+		DomainsFile: *domainsFile,
+		Interval:    *interval,
+		Jitter:      *jitter,
+		Store:       &fileMonitorStore{path: *stateFile},
+		NotifyOn:    *notifyOn,
+		Spread:      *spread,
+
+		AlertAfterFailures: *alertAfterFailures,
+	}
+	if config.AlertAfterFailures < 0 {
+		fmt.Fprintf(os.Stderr, "Error: -alert-after-failures cannot be negative\n")
+		return 1
+	}
+
+	if config.NotifyOn != "available" && config.NotifyOn != "taken" && config.NotifyOn != "any" {
+		fmt.Fprintf(os.Stderr, "Error: -notify-on must be 'available', 'taken' or 'any'\n")
+		return 1
+	}
+
+	if *webhook != "" {
+		config.Webhook = &WebhookNotifier{URL: *webhook, Secret: *webhookSecret}
+		config.WebhookSweeps = *webhookSweeps
+	}
+
+	notifiers, err := notifyFlags.Notifiers()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	config.Notifiers = notifiers
+
+	config.Email, err = emailFlags.Notifier()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	config.Whois, err = whoisFlags.Checker()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	config.Fake, err = whoisFlags.Fake()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	config.Pacing, err = pacingFlags.Pacing()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if *debugFlag {
+		debugLog.SetOutput(os.Stderr)
+	}
+
+	if *schedule != "" {
+		if config.Spread {
+			fmt.Fprintf(os.Stderr, "Error: -spread works with -interval, not -schedule\n")
+			return 1
+		}
+		cron, err := parseCron(*schedule)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		config.Schedule = cron
+	} else if config.Interval <= 0 {
+		fmt.Fprintf(os.Stderr, "Error: -interval must be positive\n")
+		return 1
+	}
+
+	if *historyPath != "" {
+		history, err := OpenHistory(*historyPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		defer history.Close()
+		config.Store = &historyMonitorStore{history: history, path: *historyPath}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+	config.Hangup = hangup
+
+	logger := log.New(zonedLogWriter{os.Stdout}, "", 0)
+	watchdog := sdWatchdogInterval()
+	if *statusListen != "" || watchdog > 0 {
+		period := config.Interval
+		if config.Schedule != nil {
+			first := config.Schedule.Next(time.Now().In(displayZone))
+			period = config.Schedule.Next(first).Sub(first)
+		}
+		config.Health = newMonitorHealth(period + config.Jitter)
+	}
+	if *statusListen != "" {
+		if err := config.Health.serveStatus(ctx, *statusListen); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		logger.Printf("serving /healthz, /readyz and /status on %s", *statusListen)
+	}
+	if watchdog > 0 {
+		go config.Health.runWatchdog(ctx, watchdog, logger)
+	}
+
+	if err := monitor(ctx, config, logger); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// monitorStore persists the monitor state between sweeps and restarts.
+type monitorStore interface {
+	Load() (*MonitorState, error)
+	Save(run RunInfo, state *MonitorState, results []DomainResult) error
+	String() string
+}
+
+type fileMonitorStore struct {
+	path string
+}
+
+func (f *fileMonitorStore) Load() (*MonitorState, error) {
+	return loadMonitorState(f.path)
+}
+
+func (f *fileMonitorStore) Save(run RunInfo, state *MonitorState, results []DomainResult) error {
+	return saveMonitorState(f.path, state)
+}
+
+func (f *fileMonitorStore) String() string {
+	return f.path
+}
+
+type historyMonitorStore struct {
+	history *HistoryStore
+	path    string
+}
+
+func (h *historyMonitorStore) Load() (*MonitorState, error) {
+	return h.history.LoadMonitorState()
+}
+
+func (h *historyMonitorStore) Save(run RunInfo, state *MonitorState, results []DomainResult) error {
+	if err := h.history.Record(run, results); err != nil {
+		return err
+	}
+	return h.history.SaveMonitorState(state)
+}
+
+func (h *historyMonitorStore) String() string {
+	return h.path
+}
+
+func monitor(ctx context.Context, config MonitorConfig, logger *log.Logger) error {
+	state, err := config.Store.Load()
+	if err != nil {
+		return err
+	}
+	logger.Printf("loaded state for %d domains from %s", len(state.Domains), config.Store)
+
+	watched := &watchList{path: config.DomainsFile}
+	if _, _, err := watched.reload(true); err != nil {
+		return err
+	}
+	state.watch(watched.domains)
+	config.Health.stateLoaded(len(watched.domains))
+	if err := sdNotify("READY=1"); err != nil {
+		logger.Printf("%v", err)
+	}
+
+	checkOne := config.Whois.Check
+	if config.Fake != nil {
+		checkOne = config.Fake.Check
+	}
+	check := config.Pacing.Throttle(checkOne)
+	var spread *spreadScheduler
+	if config.Spread {
+		spread = newSpreadScheduler(check, config.Pacing.Workers, config.Whois.maintenanceEnd)
+	}
+
+	for {
+		domains := watched.domains
+		run := RunInfo{ID: newRunID(), StartedAt: time.Now().UTC()}
+		var results []DomainResult
+		if spread != nil {
+			logger.Printf("run %s: checking %d domains over %s", run.ID, len(domains), config.Interval)
+			results = spread.sweep(ctx, domains, state, run.StartedAt, config.Interval, logger)
+		} else {
+			logger.Printf("run %s: checking %d domains", run.ID, len(domains))
+			results = checkDomainsConcurrently(ctx, explicitCandidates(domains, VariantExplicit), config.Pacing, check)
+		}
+		run.FinishedAt = time.Now().UTC()
+
+		var transitions []Transition
+		var alerts []FailureAlert
+		for i := range results {
+			// The state, history and webhooks carry UTC.
+			results[i].CheckedAt = results[i].CheckedAt.UTC()
+			results[i].RunID = run.ID
+			result := results[i]
+			if result.Status == StatusDeferred {
+				logger.Printf("run %s: %s: %v, keeping previous status", run.ID, result.Domain, result.Error)
+				continue
+			}
+			if result.Status == StatusError || result.Status == StatusUnknown {
+				if result.Status == StatusError {
+					logger.Printf("run %s: %s: check failed: %v", run.ID, result.Domain, result.Error)
+				} else {
+					logger.Printf("run %s: %s: reply not recognised, keeping previous status", run.ID, result.Domain)
+				}
+				streak := state.fail(result)
+				if streak == config.AlertAfterFailures {
+					alerts = append(alerts, FailureAlert{
+						Event:     "unmonitorable",
+						Domain:    result.Domain,
+						Failures:  streak,
+						Error:     state.Domains[result.Domain].LastError,
+						CheckedAt: result.CheckedAt,
+						RunID:     run.ID,
+					})
+				}
+				continue
+			}
+			if !result.Status.IsVerdict() {
+				continue
+			}
+			if streak := state.Domains[result.Domain].FailureStreak; config.AlertAfterFailures > 0 && streak >= config.AlertAfterFailures {
+				logger.Printf("run %s: %s: checked again after %d failures", run.ID, result.Domain, streak)
+			}
+			if t, changed := state.apply(result); changed {
+				t.RunID = run.ID
+				t.RunStartedAt = run.StartedAt
+				logger.Printf("run %s: %s", run.ID, t)
+				if t.PreviousStatus != "" && shouldNotify(t, config.NotifyOn) {
+					transitions = append(transitions, t)
+				}
+			}
+		}
+
+		next := nextSweep(config, time.Now())
+		if spread != nil {
+			// Slots are relative to the sweep start, so the next sweep
+			// keeps the pace even when this one ran late.
+			next = run.StartedAt.Add(config.Interval)
+		} else {
+			if moved := avoidMaintenance(config.Whois, domains, next); !moved.Equal(next) {
+				logger.Printf("run %s: next sweep moved from %s to %s, after a whois maintenance window",
+					run.ID, displayTime(next, time.RFC3339), displayTime(moved, time.RFC3339))
+				next = moved
+			}
+			for _, domain := range domains {
+				ds := state.Domains[domain]
+				ds.NextCheckAt = next.UTC()
+				state.Domains[domain] = ds
+			}
+		}
+
+		if err := config.Store.Save(run, state, results); err != nil {
+			return err
+		}
+		config.Health.sweepDone(run, next, state, len(domains))
+		config.Health.notificationsPending(len(transitions) + len(alerts))
+
+		// Transitions already recorded in the state would never be reported
+		// again, so deliver them even while shutting down.
+		notifyCtx := context.WithoutCancel(ctx)
+		if config.Webhook != nil {
+			for _, t := range transitions {
+				if err := config.Webhook.Send(notifyCtx, t); err != nil {
+					logger.Printf("run %s: %s: webhook delivery failed: %v", run.ID, t.Domain, err)
+				}
+			}
+			// An interrupted sweep would skew the per-TLD counts.
+			if config.WebhookSweeps && ctx.Err() == nil {
+				if err := config.Webhook.SendSweep(notifyCtx, newSweepReport(run, results)); err != nil {
+					logger.Printf("run %s: sweep webhook delivery failed: %v", run.ID, err)
+				}
+			}
+		}
+		if len(alerts) > 0 {
+			message := formatFailuresMessage(alerts)
+			for _, a := range alerts {
+				logger.Printf("run %s: %s", run.ID, a)
+				if config.Webhook != nil {
+					if err := config.Webhook.SendAlert(notifyCtx, a); err != nil {
+						logger.Printf("run %s: %s: webhook delivery failed: %v", run.ID, a.Domain, err)
+					}
+				}
+			}
+			for _, err := range sendMessages(notifyCtx, config.Notifiers, message) {
+				logger.Printf("run %s: %v", run.ID, err)
+			}
+			if config.Email != nil {
+				if err := config.Email.SendReport(notifyCtx, "Domains that cannot be monitored", message, results); err != nil {
+					logger.Printf("run %s: email notification failed: %v", run.ID, err)
+				}
+			}
+		}
+		if len(transitions) > 0 {
+			message := formatTransitionsMessage(transitions)
+			for _, err := range sendMessages(notifyCtx, config.Notifiers, message) {
+				logger.Printf("run %s: %v", run.ID, err)
+			}
+			if config.Email != nil {
+				if err := config.Email.SendReport(notifyCtx, "Domain status changes", message, results); err != nil {
+					logger.Printf("run %s: email notification failed: %v", run.ID, err)
+				}
+			}
+		}
+
+		config.Health.notificationsPending(0)
+
+		if ctx.Err() != nil {
+			logger.Printf("shutting down, state saved to %s", config.Store)
+			return nil
+		}
+
+		logger.Printf("run %s: finished in %s, next check at %s",
+			run.ID, run.FinishedAt.Sub(run.StartedAt).Round(time.Millisecond), displayTime(next, time.RFC3339))
+
+		if done := waitForSweep(ctx, next, watched, state, config.Hangup, logger); done {
+			logger.Printf("shutting down, state saved to %s", config.Store)
+			return nil
+		}
+	}
+}
+
+// avoidMaintenance moves a sweep due at next past the maintenance windows
+// the whois servers of the domains are in at that time. Windows are short,
+// so delaying the whole sweep beats reporting those domains as deferred.
+func avoidMaintenance(whois *WhoisChecker, domains []string, next time.Time) time.Time {
+	// Back-to-back windows are followed a few times, not forever.
+	for i := 0; i < 10; i++ {
+		moved := false
+		for _, domain := range domains {
+			if end, ok := whois.maintenanceEnd(domain, next); ok {
+				next, moved = end, true
+			}
+		}
+		if !moved {
+			break
+		}
+	}
+	return next
+}
+
+// watchListPoll is how often the watch list file is checked for changes
+// between sweeps.
+const watchListPoll = 10 * time.Second
+
+// waitForSweep waits until next, rereading the watch list whenever its file
+// changes or SIGHUP arrives. It reports true when ctx is done first.
+func waitForSweep(ctx context.Context, next time.Time, watched *watchList, state *MonitorState, hangup <-chan os.Signal, logger *log.Logger) bool {
+	timer := time.NewTimer(time.Until(next))
+	defer timer.Stop()
+	poll := time.NewTicker(watchListPoll)
+	defer poll.Stop()
+	for {
+		force := false
+		select {
+		case <-ctx.Done():
+			return true
+		case <-timer.C:
+			return false
+		case <-hangup:
+			logger.Printf("SIGHUP: rereading %s", watched.path)
+			force = true
+		case <-poll.C:
+		}
+
+		added, removed, err := watched.reload(force)
+		if err != nil {
+			logger.Printf("keeping the previous watch list of %d domains: %v", len(watched.domains), err)
+			continue
+		}
+		if len(added) > 0 {
+			logger.Printf("watch list: added %s", strings.Join(added, ", "))
+		}
+		if len(removed) > 0 {
+			logger.Printf("watch list: removed %s (their history is kept)", strings.Join(removed, ", "))
+		}
+		state.watch(added)
+	}
+}
+
+// watchList is the set of domains the monitor checks, read from a file.
+type watchList struct {
+	path    string
+	modTime time.Time
+	size    int64
+	domains []string
+}
+
+// reload rereads the file when it changed since the last read, or always
+// with force, and returns the domains added and removed. On error the
+// previous list stays in place, so a file caught mid-write does not empty
+// the watch list.
+func (w *watchList) reload(force bool) (added, removed []string, err error) {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading domains file: %w", err)
+	}
+	if !force && info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+		return nil, nil, nil
+	}
+	domains, err := readDomainsFile(w.path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	before := make(map[string]bool, len(w.domains))
+	for _, domain := range w.domains {
+		before[domain] = true
+	}
+	after := make(map[string]bool, len(domains))
+	for _, domain := range domains {
+		after[domain] = true
+		if !before[domain] {
+			added = append(added, domain)
+		}
+	}
+	for _, domain := range w.domains {
+		if !after[domain] {
+			removed = append(removed, domain)
+		}
+	}
+	w.domains, w.modTime, w.size = domains, info.ModTime(), info.Size()
+	return added, removed, nil
+}
+
+// watch starts domains not seen before as unknown.
+func (s *MonitorState) watch(domains []string) {
+	for _, domain := range domains {
+		if _, ok := s.Domains[domain]; !ok {
+			s.Domains[domain] = DomainState{Status: StatusUnknown}
+		}
+	}
+}
+
+func nextSweep(config MonitorConfig, now time.Time) time.Time {
+	var next time.Time
+	if config.Schedule != nil {
+		next = config.Schedule.Next(now.In(displayZone))
+	} else {
+		next = now.Add(config.Interval)
+	}
+	if config.Jitter > 0 {
+		next = next.Add(time.Duration(rand.Int63n(int64(config.Jitter))))
+	}
+	return next
+}
+
+// fail counts a failed check of the domain and returns how many checks
+// have failed in a row. The last known status is kept.
+func (s *MonitorState) fail(result DomainResult) int {
+	ds := s.Domains[result.Domain]
+	ds.FailureStreak++
+	ds.LastError = "reply not recognised"
+	if result.Error != nil {
+		ds.LastError = result.Error.Error()
+	}
+	s.Domains[result.Domain] = ds
+	return ds.FailureStreak
+}
+
+// apply records the result and reports whether the domain's status changed.
+// The first verdict for a domain, including one watched as unknown so far,
+// is a change with an empty PreviousStatus. Failed checks never overwrite
+// the last known status, and a verdict ends the failure streak.
+func (s *MonitorState) apply(result DomainResult) (Transition, bool) {
+	if !result.Status.IsVerdict() {
+		return Transition{}, false
+	}
+
+	prev, known := s.Domains[result.Domain]
+	if !prev.Status.IsVerdict() {
+		prev, known = DomainState{}, false
+	}
+	current := DomainState{
+		Status:      result.Status,
+		EPPStatus:   result.EPPStatus,
+		CheckedAt:   result.CheckedAt,
+		ChangedAt:   prev.ChangedAt,
+		NextCheckAt: s.Domains[result.Domain].NextCheckAt,
+	}
+
+	changed := !known || prev.Status != current.Status || !slices.Equal(prev.EPPStatus, current.EPPStatus)
+	if changed {
+		current.ChangedAt = result.CheckedAt
+	}
+	s.Domains[result.Domain] = current
+
+	if !changed {
+		return Transition{}, false
+	}
+	return Transition{
+		Domain:            result.Domain,
+		PreviousStatus:    prev.Status,
+		PreviousEPPStatus: prev.EPPStatus,
+		NewStatus:         current.Status,
+		NewEPPStatus:      current.EPPStatus,
+		CheckedAt:         result.CheckedAt,
+		Registrar:         result.Registrar,
+	}, true
+}
+
+func (t Transition) String() string {
+	if t.PreviousStatus == "" {
+		return fmt.Sprintf("%s: %s", t.Domain, describeStatus(t.NewStatus, t.NewEPPStatus))
+	}
+	s := fmt.Sprintf("%s: %s → %s", t.Domain,
+		describeStatus(t.PreviousStatus, t.PreviousEPPStatus),
+		describeStatus(t.NewStatus, t.NewEPPStatus))
+	if t.NewStatus == StatusTaken && t.Registrar != "" {
+		s += ", registrar " + t.Registrar
+	}
+	return s
+}
+
+func describeStatus(status Status, eppStatus []string) string {
+	if len(eppStatus) == 0 {
+		return string(status)
+	}
+	return fmt.Sprintf("%s (%s)", status, strings.Join(eppStatus, ", "))
+}
+
+func loadMonitorState(path string) (*MonitorState, error) {
+	state := &MonitorState{Domains: map[string]DomainState{}}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading state file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("parsing state file %s: %w", path, err)
+	}
+	if state.Domains == nil {
+		state.Domains = map[string]DomainState{}
+	}
+	return state, nil
+}
+
+func saveMonitorState(path string, state *MonitorState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("writing state file: %w", err)
+	}
+	return nil
+}
+
+// writeFileAtomic writes to a temporary file first so a crash mid-write
+// never leaves a truncated file behind. The data is synced before the
+// rename, and the rename after it, so a power loss leaves either the old
+// file or the new one.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	// Not every system can sync a directory (Windows cannot); the rename
+	// is then as durable as the system makes it.
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
+
+func readDomainsFile(path string) ([]string, error) {
+	file, err := openInput(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading domains file: %w", err)
+	}
+	defer file.Close()
+
+	var domains []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains = append(domains, strings.ToLower(strings.TrimSuffix(line, ".")))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading domains file: %w", err)
+	}
+	return domains, nil
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// monitorHealth is what the status listener of -status-listen and the
+// systemd watchdog know about a running monitor. The monitor loop reports
+// to it; a nil *monitorHealth ignores the reports.
+type monitorHealth struct {
+	mu sync.Mutex
+	// now is the clock, time.Now unless replaced to test readiness.
+	now       func() time.Time
+	startedAt time.Time
+	loaded    bool
+	watched   int
+	// lastSweep is when the latest sweep finished, and period the time
+	// between sweeps it was scheduled with.
+	lastSweep time.Time
+	nextSweep time.Time
+	period    time.Duration
+	pending   int
+	streaks   map[string]int
+}
+
+func newMonitorHealth(period time.Duration) *monitorHealth {
+	h := &monitorHealth{now: time.Now, period: period}
+	h.startedAt = h.now()
+	return h
+}
+
+// stateLoaded records that the state was loaded and how many domains are
+// watched.
+func (h *monitorHealth) stateLoaded(watched int) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.loaded = true
+	h.watched = watched
+}
+
+// sweepDone records a finished sweep, the next one and the failure streaks
+// of the state. The period between sweeps is learned from the schedule,
+// for -schedule sweeps that are not a fixed interval apart.
+func (h *monitorHealth) sweepDone(run RunInfo, next time.Time, state *MonitorState, watched int) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastSweep = run.FinishedAt
+	h.nextSweep = next
+	if period := next.Sub(run.StartedAt); period > h.period {
+		h.period = period
+	}
+	h.watched = watched
+	h.streaks = map[string]int{}
+	for domain, ds := range state.Domains {
+		if ds.FailureStreak > 0 {
+			h.streaks[domain] = ds.FailureStreak
+		}
+	}
+}
+
+// notificationsPending records how many notifications of the latest sweep
+// are still to be delivered.
+func (h *monitorHealth) notificationsPending(n int) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.pending = n
+}
+
+// ready reports whether the monitor is making progress: the state is
+// loaded and a sweep finished within twice the period between sweeps,
+// counted from the start while the first sweep runs. The reason says what
+// is wrong otherwise.
+func (h *monitorHealth) ready() (bool, string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.loaded {
+		return false, "state not loaded"
+	}
+	since, what := h.lastSweep, "last sweep finished %s ago"
+	if since.IsZero() {
+		since, what = h.startedAt, "first sweep running for %s"
+	}
+	if age := h.now().Sub(since); age > 2*h.period {
+		return false, fmt.Sprintf(what+", more than twice the %s between sweeps", age.Round(time.Second), h.period)
+	}
+	return true, ""
+}
+
+// monitorStatus is the body of /status.
+type monitorStatus struct {
+	Ready                bool           `json:"ready"`
+	Reason               string         `json:"reason,omitempty"`
+	StartedAt            time.Time      `json:"startedAt"`
+	Watched              int            `json:"watched"`
+	LastSweepAt          *time.Time     `json:"lastSweepAt,omitempty"`
+	NextSweepAt          *time.Time     `json:"nextSweepAt,omitempty"`
+	PendingNotifications int            `json:"pendingNotifications"`
+	FailureStreaks       map[string]int `json:"failureStreaks"`
+}
+
+func (h *monitorHealth) status() monitorStatus {
+	ready, reason := h.ready()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := monitorStatus{
+		Ready:                ready,
+		Reason:               reason,
+		StartedAt:            h.startedAt.UTC(),
+		Watched:              h.watched,
+		PendingNotifications: h.pending,
+		FailureStreaks:       map[string]int{},
+	}
+	if !h.lastSweep.IsZero() {
+		last, next := h.lastSweep.UTC(), h.nextSweep.UTC()
+		s.LastSweepAt, s.NextSweepAt = &last, &next
+	}
+	for domain, n := range h.streaks {
+		s.FailureStreaks[domain] = n
+	}
+	return s
+}
+
+// handler serves /healthz, which only says the process is up, /readyz and
+// /status, on a mux of its own.
+func (h *monitorHealth) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		if ready, reason := h.ready(); !ready {
+			http.Error(w, reason, http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ready")
+	})
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(h.status())
+	})
+	return mux
+}
+
+// serveStatus listens on addr and serves the handler until ctx is done.
+// Listening happens before it returns, so a taken port is reported at once.
+func (h *monitorHealth) serveStatus(ctx context.Context, addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("status listener: %w", err)
+	}
+	srv := &http.Server{Handler: h.handler(), ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			debugLog.Printf("status listener: %v", err)
+		}
+	}()
+	return nil
+}
+
+// sdNotify sends a state such as READY=1 to systemd when it started the
+// process with Type=notify; without NOTIFY_SOCKET it does nothing.
+func sdNotify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	if strings.HasPrefix(socket, "@") {
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.Dial("unixgram", socket)
+	if err != nil {
+		return fmt.Errorf("sd_notify: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return fmt.Errorf("sd_notify: %w", err)
+	}
+	return nil
+}
+
+// sdWatchdogInterval is how often to ping the systemd watchdog: half its
+// WatchdogSec, or zero when the watchdog is off.
+func sdWatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond / 2
+}
+
+// runWatchdog pings the systemd watchdog while the monitor is ready, so a
+// monitor stuck in a sweep is restarted.
+func (h *monitorHealth) runWatchdog(ctx context.Context, interval time.Duration, logger *log.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if ready, reason := h.ready(); !ready {
+			logger.Printf("not pinging the systemd watchdog: %s", reason)
+			continue
+		}
+		if err := sdNotify("WATCHDOG=1"); err != nil {
+			logger.Printf("%v", err)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock the test moves by hand.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// newTestHealth returns a monitorHealth started at the fake clock's time.
+func newTestHealth(clock *fakeClock, period time.Duration) *monitorHealth {
+	h := newMonitorHealth(period)
+	h.now = clock.now
+	h.startedAt = clock.now()
+	return h
+}
+
+// sweep records a sweep that ran for d from the clock's time, and moves
+// the clock to its end.
+func sweep(h *monitorHealth, clock *fakeClock, d, period time.Duration, state *MonitorState) {
+	run := RunInfo{StartedAt: clock.now()}
+	clock.advance(d)
+	run.FinishedAt = clock.now()
+	h.sweepDone(run, run.StartedAt.Add(period), state, len(state.Domains))
+}
+
+func TestMonitorReadiness(t *testing.T) {
+	clock := &fakeClock{time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)}
+	h := newTestHealth(clock, time.Hour)
+	state := &MonitorState{Domains: map[string]DomainState{}}
+
+	steps := []struct {
+		what   string
+		step   func()
+		ready  bool
+		reason string
+	}{
+		{"started", func() {}, false, "state not loaded"},
+		{"loaded", func() { h.stateLoaded(3) }, true, ""},
+		{"first sweep at twice the period", func() { clock.advance(2 * time.Hour) }, true, ""},
+		{"first sweep past twice the period", func() { clock.advance(time.Second) }, false, "first sweep running for 2h0m1s, more than twice the 1h0m0s between sweeps"},
+		{"first sweep done", func() { sweep(h, clock, time.Minute, time.Hour, state) }, true, ""},
+		{"one period later", func() { clock.advance(time.Hour) }, true, ""},
+		{"missed one sweep", func() { clock.advance(59 * time.Minute) }, true, ""},
+		{"missed two sweeps", func() { clock.advance(90 * time.Second) }, false, "last sweep finished 2h0m30s ago, more than twice the 1h0m0s between sweeps"},
+		{"caught up", func() { sweep(h, clock, 5*time.Minute, time.Hour, state) }, true, ""},
+	}
+	for _, s := range steps {
+		s.step()
+		ready, reason := h.ready()
+		if ready != s.ready || reason != s.reason {
+			t.Errorf("%s: ready() = %v, %q; want %v, %q", s.what, ready, reason, s.ready, s.reason)
+		}
+	}
+}
+
+// TestMonitorReadinessSchedule checks that a -schedule with uneven gaps
+// between sweeps, such as weekdays only, is not reported as stalled over
+// the weekend: the longest gap seen becomes the period.
+func TestMonitorReadinessSchedule(t *testing.T) {
+	clock := &fakeClock{time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)} // a Friday
+	h := newTestHealth(clock, 24*time.Hour)
+	state := &MonitorState{Domains: map[string]DomainState{}}
+	h.stateLoaded(1)
+
+	sweep(h, clock, time.Minute, 72*time.Hour, state)
+	clock.advance(60 * time.Hour) // Monday morning, before the next sweep
+	if ready, reason := h.ready(); !ready {
+		t.Errorf("over the weekend: not ready: %s", reason)
+	}
+	clock.advance(85 * time.Hour)
+	if ready, _ := h.ready(); ready {
+		t.Error("145h after the last sweep of a 72h period: still ready")
+	}
+}
+
+func TestMonitorStatus(t *testing.T) {
+	clock := &fakeClock{time.Date(2026, 10, 16, 14, 0, 0, 0, time.FixedZone("CEST", 2*60*60))}
+	h := newTestHealth(clock, 30*time.Minute)
+	h.stateLoaded(2)
+	state := &MonitorState{Domains: map[string]DomainState{
+		"superfast.com": {FailureStreak: 3},
+		"superfast.io":  {},
+	}}
+	sweep(h, clock, 2*time.Minute, 30*time.Minute, state)
+	h.notificationsPending(1)
+
+	s := h.status()
+	want := time.Date(2026, 10, 16, 12, 2, 0, 0, time.UTC)
+	if !s.Ready || s.Watched != 2 || s.PendingNotifications != 1 || s.LastSweepAt == nil || !s.LastSweepAt.Equal(want) || s.LastSweepAt.Location() != time.UTC {
+		t.Errorf("status = %+v", s)
+	}
+	if s.NextSweepAt == nil || !s.NextSweepAt.Equal(want.Add(28*time.Minute)) {
+		t.Errorf("next sweep = %v", s.NextSweepAt)
+	}
+	if len(s.FailureStreaks) != 1 || s.FailureStreaks["superfast.com"] != 3 {
+		t.Errorf("failure streaks = %v", s.FailureStreaks)
+	}
+
+	// Before the first sweep the sweep times are left out.
+	data, err := json.Marshal(newTestHealth(clock, time.Hour).status())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "SweepAt") || !strings.Contains(string(data), `"failureStreaks":{}`) {
+		t.Errorf("status before the first sweep = %s", data)
+	}
+}
+
+func TestMonitorHealthHandler(t *testing.T) {
+	clock := &fakeClock{time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)}
+	h := newTestHealth(clock, time.Hour)
+	srv := httptest.NewServer(h.handler())
+	defer srv.Close()
+
+	get := func(path string) (int, string) {
+		t.Helper()
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, string(body)
+	}
+
+	if code, _ := get("/healthz"); code != http.StatusOK {
+		t.Errorf("/healthz before the state is loaded = %d", code)
+	}
+	if code, body := get("/readyz"); code != http.StatusServiceUnavailable || !strings.Contains(body, "state not loaded") {
+		t.Errorf("/readyz before the state is loaded = %d %q", code, body)
+	}
+	h.stateLoaded(1)
+	if code, _ := get("/readyz"); code != http.StatusOK {
+		t.Errorf("/readyz once loaded = %d", code)
+	}
+	clock.advance(3 * time.Hour)
+	if code, body := get("/readyz"); code != http.StatusServiceUnavailable || !strings.Contains(body, "first sweep running for 3h0m0s") {
+		t.Errorf("/readyz of a stuck first sweep = %d %q", code, body)
+	}
+	if code, _ := get("/healthz"); code != http.StatusOK {
+		t.Errorf("/healthz of a stuck monitor = %d", code)
+	}
+
+	code, body := get("/status")
+	var s monitorStatus
+	if err := json.Unmarshal([]byte(body), &s); code != http.StatusOK || err != nil {
+		t.Fatalf("/status = %d %q: %v", code, body, err)
+	}
+	if s.Ready || s.Watched != 1 || !strings.Contains(s.Reason, "first sweep running") {
+		t.Errorf("/status = %+v", s)
+	}
+	if code, _ := get("/"); code != http.StatusNotFound {
+		t.Errorf("/ = %d, want 404", code)
+	}
+}
+
+func TestNilMonitorHealth(t *testing.T) {
+	var h *monitorHealth
+	h.stateLoaded(1)
+	h.sweepDone(RunInfo{}, time.Time{}, &MonitorState{}, 0)
+	h.notificationsPending(1)
+}
+
+func TestSDNotify(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	if err := sdNotify("READY=1"); err != nil {
+		t.Errorf("sdNotify without NOTIFY_SOCKET: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unixgram sockets: %v", err)
+	}
+	defer conn.Close()
+	t.Setenv("NOTIFY_SOCKET", path)
+	if err := sdNotify("READY=1"); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 64)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil || string(buf[:n]) != "READY=1" {
+		t.Errorf("received %q, %v", buf[:n], err)
+	}
+}
+
+func TestSDWatchdogInterval(t *testing.T) {
+	for _, tt := range []struct {
+		usec, pid string
+		want      time.Duration
+	}{
+		{"", "", 0},
+		{"30000000", "", 15 * time.Second},
+		{"30000000", "1", 0},
+		{"0", "", 0},
+		{"soon", "", 0},
+	} {
+		t.Setenv("WATCHDOG_USEC", tt.usec)
+		t.Setenv("WATCHDOG_PID", tt.pid)
+		if got := sdWatchdogInterval(); got != tt.want {
+			t.Errorf("WATCHDOG_USEC=%q WATCHDOG_PID=%q: interval = %s, want %s", tt.usec, tt.pid, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// spreadScheduler runs the checks of a monitor sweep at evenly spaced
+// times across the interval instead of all at its start, so the whois
+// servers see a steady trickle rather than the same burst every sweep.
+// A check still running when its domain is due again is not doubled up;
+// its result joins the sweep in which it arrives.
+type spreadScheduler struct {
+	check checkFunc
+	// maintenanceEnd moves slots falling into a maintenance window of the
+	// domain's whois server to its end.
+	maintenanceEnd func(domain string, t time.Time) (time.Time, bool)
+	workers        chan struct{}
+	results        chan DomainResult
+
+	mu      sync.Mutex
+	running map[string]bool
+}
+
+func newSpreadScheduler(check checkFunc, workers int, maintenanceEnd func(string, time.Time) (time.Time, bool)) *spreadScheduler {
+	return &spreadScheduler{
+		check:          check,
+		maintenanceEnd: maintenanceEnd,
+		workers:        make(chan struct{}, workers),
+		results:        make(chan DomainResult, workers),
+		running:        map[string]bool{},
+	}
+}
+
+// slot is when a domain is due in a sweep.
+type slot struct {
+	Domain string
+	Due    time.Time
+}
+
+// schedule gives domain i of n the slot at i/n of the period, unless the
+// state holds a due time within this sweep, as after a restart. A slot in
+// a maintenance window of the domain's server moves to the end of the
+// window when that is still within the sweep.
+func schedule(domains []string, state *MonitorState, start time.Time, period time.Duration, maintenanceEnd func(string, time.Time) (time.Time, bool)) []slot {
+	end := start.Add(period)
+	slots := make([]slot, len(domains))
+	for i, domain := range domains {
+		due := state.Domains[domain].NextCheckAt
+		if due.Before(start) || !due.Before(end) {
+			due = start.Add(period * time.Duration(i) / time.Duration(len(domains)))
+		}
+		if maintenanceEnd != nil {
+			if after, ok := maintenanceEnd(domain, due); ok && after.Before(end) {
+				due = after
+			}
+		}
+		slots[i] = slot{Domain: domain, Due: due}
+	}
+	sort.SliceStable(slots, func(i, j int) bool { return slots[i].Due.Before(slots[j].Due) })
+	return slots
+}
+
+// sweep checks every domain at its slot and returns the results that
+// arrived before the period ended, or all of them once no check is left
+// running. Each domain's next due time is recorded in state.
+func (s *spreadScheduler) sweep(ctx context.Context, domains []string, state *MonitorState, start time.Time, period time.Duration, logger *log.Logger) []DomainResult {
+	var results []DomainResult
+	drain := func() {
+		for {
+			select {
+			case result := <-s.results:
+				results = append(results, result)
+			default:
+				return
+			}
+		}
+	}
+
+	for _, slot := range schedule(domains, state, start, period, s.maintenanceEnd) {
+		if wait := time.Until(slot.Due); wait > 0 {
+			timer := time.NewTimer(wait)
+			for waiting := true; waiting; {
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return append(results, s.collect(ctx, start.Add(period))...)
+				case result := <-s.results:
+					results = append(results, result)
+				case <-timer.C:
+					waiting = false
+				}
+			}
+		}
+		drain()
+
+		ds := state.Domains[slot.Domain]
+		ds.NextCheckAt = slot.Due.Add(period).UTC()
+		state.Domains[slot.Domain] = ds
+
+		s.mu.Lock()
+		busy := s.running[slot.Domain]
+		if !busy {
+			s.running[slot.Domain] = true
+		}
+		s.mu.Unlock()
+		if busy {
+			logger.Printf("%s: previous check still running, skipped this sweep", slot.Domain)
+			continue
+		}
+		go func(domain string) {
+			s.workers <- struct{}{}
+			result := s.check(domain)
+			<-s.workers
+			s.mu.Lock()
+			delete(s.running, domain)
+			s.mu.Unlock()
+			s.results <- result
+		}(slot.Domain)
+	}
+	return append(results, s.collect(ctx, start.Add(period))...)
+}
+
+// collect gathers results until no check is running or deadline passes.
+// On cancellation it still waits for running checks, which are short next
+// to a sweep, so their results are saved.
+func (s *spreadScheduler) collect(ctx context.Context, deadline time.Time) []DomainResult {
+	var results []DomainResult
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	for s.busy() {
+		select {
+		case result := <-s.results:
+			results = append(results, result)
+		case <-timer.C:
+			if ctx.Err() == nil {
+				return results
+			}
+		}
+	}
+	for {
+		select {
+		case result := <-s.results:
+			results = append(results, result)
+		default:
+			return results
+		}
+	}
+}
+
+func (s *spreadScheduler) busy() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.running) > 0
+}
+
+// runMonitorStatus prints the last status, next due time and failure
+// streak of every domain in the monitor state.
+func runMonitorStatus(args []string) int {
+	fs := flag.NewFlagSet("monitor status", flag.ExitOnError)
+	stateFile := fs.String("state", "domain-checker-state.json", "State file of the monitor (ignored with -history)")
+	historyPath := fs.String("history", "", "SQLite history database the monitor keeps its state in")
+	timezone := addTimezoneFlag(fs)
+	fs.Parse(args)
+	if err := setDisplayZone(*timezone); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	var store monitorStore = &fileMonitorStore{path: *stateFile}
+	if *historyPath != "" {
+		history, err := OpenHistory(*historyPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		defer history.Close()
+		store = &historyMonitorStore{history: history, path: *historyPath}
+	}
+	state, err := store.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if len(state.Domains) == 0 {
+		fmt.Printf("No domains in %s\n", store)
+		return 0
+	}
+
+	domains := make([]string, 0, len(state.Domains))
+	for domain := range state.Domains {
+		domains = append(domains, domain)
+	}
+	sort.Slice(domains, func(i, j int) bool {
+		a, b := state.Domains[domains[i]].NextCheckAt, state.Domains[domains[j]].NextCheckAt
+		if !a.Equal(b) {
+			// Domains never scheduled go last.
+			return !a.IsZero() && (b.IsZero() || a.Before(b))
+		}
+		return domains[i] < domains[j]
+	})
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DOMAIN\tSTATUS\tCHECKED\tNEXT CHECK\tFAILED IN A ROW")
+	for _, domain := range domains {
+		ds := state.Domains[domain]
+		streak := "-"
+		if ds.FailureStreak > 0 {
+			streak = fmt.Sprintf("%d (%s)", ds.FailureStreak, ds.LastError)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", domain, describeStatus(ds.Status, ds.EPPStatus), formatStatusTime(ds.CheckedAt), formatStatusTime(ds.NextCheckAt), streak)
+	}
+	tw.Flush()
+	return 0
+}
+
+func formatStatusTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return displayTime(t, "2006-01-02 15:04:05")
+}
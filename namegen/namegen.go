@@ -0,0 +1,286 @@
+// Package namegen enumerates the names domain-checker builds from keywords:
+// the combinations of one list or the cross product of several, joined with
+// each separator and followed by their prefixed and suffixed forms. It does
+// no networking, so other tools can use the generation on its own.
+//
+// A Generator yields one name at a time and keeps only its position in the
+// space of combinations, so spaces far too large to hold in memory can be
+// walked, or cut short, at the cost of a few small allocations per
+// combination.
+package namegen
+
+import (
+	"math"
+	"math/bits"
+	"strings"
+)
+
+// Kind says whether a name has an affix.
+type Kind string
+
+const (
+	Plain    Kind = ""
+	Prefixed Kind = "prefix"
+	Suffixed Kind = "suffix"
+)
+
+// Name is one generated name, without a TLD.
+type Name struct {
+	Base string
+	// Keywords are the keywords combined, in order. The names of one
+	// combination share the slice, which must not be modified.
+	Keywords  []string
+	Separator string
+	Kind      Kind
+	// Affix is the prefix or suffix of a Prefixed or Suffixed name.
+	Affix string
+}
+
+// Options describe the names to generate.
+type Options struct {
+	// Lists holds the keywords. With one list, names combine Combinations
+	// distinct keywords of it, in list order; with several, names take one
+	// keyword of each list in turn.
+	Lists        [][]string
+	Combinations int
+	// Separators join the keywords of a combination, each giving its own
+	// name; none means the keywords are joined directly.
+	Separators []string
+	// Prefixes and Suffixes are also tried in front of and after every
+	// name, joined with its separator.
+	Prefixes []string
+	Suffixes []string
+	// Join, when set, may replace the separators for a combination: it
+	// returns the joined names to use, whose affixes are then joined with
+	// the first separator, or nothing to join with the separators.
+	Join func(keywords []string) []string
+	// Filter, when set, drops the names it returns false for.
+	Filter func(Name) bool
+}
+
+// Generator yields the names of its Options in a fixed order: combination
+// by combination, each joined with every separator in turn, the plain name
+// followed by its prefixed then suffixed forms. A single keyword has its
+// plain name only once, under the first separator.
+type Generator struct {
+	opts       Options
+	separators []string
+
+	// indexes is the current combination: ascending positions in the only
+	// list, or one position per list.
+	indexes []int
+	started bool
+	done    bool
+
+	keywords []string
+	bases    []string
+	joinedBy []string
+	base     int
+	// affix counts the forms of the current base already yielded: the
+	// plain name, then each prefix, then each suffix.
+	affix int
+}
+
+// NewGenerator returns a generator positioned before the first name.
+func NewGenerator(opts Options) *Generator {
+	g := &Generator{opts: opts, separators: opts.Separators}
+	if len(g.separators) == 0 {
+		g.separators = []string{""}
+	}
+	return g
+}
+
+// Next returns the next name, or false when there are no more.
+func (g *Generator) Next() (Name, bool) {
+	forms := 1 + len(g.opts.Prefixes) + len(g.opts.Suffixes)
+	for {
+		if g.base == len(g.bases) {
+			if !g.nextCombination() {
+				return Name{}, false
+			}
+			continue
+		}
+
+		index := g.base
+		base, separator := g.bases[index], g.joinedBy[index]
+		name := Name{Base: base, Keywords: g.keywords, Separator: separator}
+		switch i := g.affix; {
+		case i == 0:
+		case i <= len(g.opts.Prefixes):
+			name.Kind, name.Affix = Prefixed, g.opts.Prefixes[i-1]
+			name.Base = name.Affix + separator + base
+		default:
+			name.Kind, name.Affix = Suffixed, g.opts.Suffixes[i-1-len(g.opts.Prefixes)]
+			name.Base = base + separator + name.Affix
+		}
+		if g.affix++; g.affix == forms {
+			g.affix = 0
+			g.base++
+		}
+		// A single keyword is the same plain name under every separator,
+		// so only its first is yielded.
+		if name.Kind == Plain && index > 0 && len(g.keywords) == 1 && base == g.bases[0] {
+			continue
+		}
+		if g.opts.Filter == nil || g.opts.Filter(name) {
+			return name, true
+		}
+	}
+}
+
+// nextCombination advances to the next combination and joins it.
+func (g *Generator) nextCombination() bool {
+	if g.done || !g.advance() {
+		g.done = true
+		return false
+	}
+
+	lists := g.opts.Lists
+	g.keywords = make([]string, len(g.indexes))
+	for i, index := range g.indexes {
+		if len(lists) == 1 {
+			g.keywords[i] = lists[0][index]
+		} else {
+			g.keywords[i] = lists[i][index]
+		}
+	}
+
+	g.bases, g.joinedBy, g.base, g.affix = g.bases[:0], g.joinedBy[:0], 0, 0
+	if g.opts.Join != nil {
+		for _, base := range g.opts.Join(g.keywords) {
+			g.bases = append(g.bases, base)
+			g.joinedBy = append(g.joinedBy, g.separators[0])
+		}
+	}
+	if len(g.bases) == 0 {
+		for _, separator := range g.separators {
+			g.bases = append(g.bases, strings.Join(g.keywords, separator))
+			g.joinedBy = append(g.joinedBy, separator)
+		}
+	}
+	return true
+}
+
+// advance moves indexes to the next combination, in the order of nested
+// loops: the last position turns fastest.
+func (g *Generator) advance() bool {
+	lists := g.opts.Lists
+	if len(lists) == 1 {
+		n, k := len(lists[0]), g.opts.Combinations
+		if k <= 0 || k > n {
+			return false
+		}
+		if !g.started {
+			g.started = true
+			g.indexes = make([]int, k)
+			for i := range g.indexes {
+				g.indexes[i] = i
+			}
+			return true
+		}
+		// Find the last position that can still move right, move it and
+		// pack the following ones behind it.
+		i := k - 1
+		for i >= 0 && g.indexes[i] == n-k+i {
+			i--
+		}
+		if i < 0 {
+			return false
+		}
+		g.indexes[i]++
+		for j := i + 1; j < k; j++ {
+			g.indexes[j] = g.indexes[j-1] + 1
+		}
+		return true
+	}
+
+	if len(lists) == 0 {
+		return false
+	}
+	if !g.started {
+		g.started = true
+		for _, list := range lists {
+			if len(list) == 0 {
+				return false
+			}
+		}
+		g.indexes = make([]int, len(lists))
+		return true
+	}
+	for i := len(lists) - 1; i >= 0; i-- {
+		if g.indexes[i]++; g.indexes[i] < len(lists[i]) {
+			return true
+		}
+		g.indexes[i] = 0
+	}
+	return false
+}
+
+// Count returns how many names the generator yields in all, before Filter,
+// saturating at math.MaxInt. With Join it is an estimate that counts a name
+// per separator for every combination.
+func (g *Generator) Count() int {
+	combinations := g.Combinations()
+	count := saturatingMul(combinations, len(g.separators)*(1+len(g.opts.Prefixes)+len(g.opts.Suffixes)))
+	if len(g.opts.Lists) == 1 && g.opts.Combinations == 1 && g.opts.Join == nil && count < math.MaxInt {
+		// Single keywords have one plain name, not one per separator.
+		count -= combinations * (len(g.separators) - 1)
+	}
+	return count
+}
+
+// Combinations returns how many combinations of keywords there are: the
+// binomial coefficient for one list, the product of the list lengths for
+// several; none when the combinations would have no keyword. It saturates
+// at math.MaxInt.
+func (g *Generator) Combinations() int {
+	lists := g.opts.Lists
+	if len(lists) == 1 {
+		if g.opts.Combinations <= 0 {
+			return 0
+		}
+		return binomial(len(lists[0]), g.opts.Combinations)
+	}
+	if len(lists) == 0 {
+		return 0
+	}
+	count := 1
+	for _, list := range lists {
+		count = saturatingMul(count, len(list))
+	}
+	return count
+}
+
+// binomial returns n choose k, saturating at math.MaxInt.
+func binomial(n, k int) int {
+	if k < 0 || k > n {
+		return 0
+	}
+	k = min(k, n-k)
+	result := 1
+	for i := 1; i <= k; i++ {
+		// result*(n-k+i) is divisible by i at every step; the 128-bit
+		// product keeps it exact until the result itself overflows.
+		hi, lo := bits.Mul64(uint64(result), uint64(n-k+i))
+		if hi >= uint64(i) {
+			return math.MaxInt
+		}
+		quo, _ := bits.Div64(hi, lo, uint64(i))
+		if quo > math.MaxInt {
+			return math.MaxInt
+		}
+		result = int(quo)
+	}
+	return result
+}
+
+func saturatingMul(a, b int) int {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	hi, lo := bits.Mul64(uint64(a), uint64(b))
+	if hi != 0 || lo > math.MaxInt {
+		return math.MaxInt
+	}
+	return int(lo)
+}
@@ -0,0 +1,184 @@
+package namegen
+
+import (
+	"math"
+	"math/big"
+	"math/rand"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+// randomOptions are small Options whose keywords are distinct, of one
+// length and drawn from letters, while affixes are digits and separators
+// dashes: no two different names can then join to the same base.
+type randomOptions struct{ Options }
+
+func (randomOptions) Generate(r *rand.Rand, size int) reflect.Value {
+	words := func(n int) []string {
+		length := 1 + r.Intn(3)
+		seen := map[string]bool{}
+		var list []string
+		for len(list) < n {
+			b := make([]byte, length)
+			for i := range b {
+				b[i] = byte('a' + r.Intn(26))
+			}
+			if !seen[string(b)] {
+				seen[string(b)] = true
+				list = append(list, string(b))
+			}
+		}
+		return list
+	}
+	affixes := func() []string {
+		var list []string
+		for i := r.Intn(3); i > 0; i-- {
+			list = append(list, strings.Repeat("7", i))
+		}
+		return list
+	}
+
+	var opts Options
+	if r.Intn(2) == 0 {
+		list := words(r.Intn(7))
+		opts.Lists = [][]string{list}
+		opts.Combinations = r.Intn(len(list) + 2)
+	} else {
+		for i := r.Intn(4); i > 0; i-- {
+			opts.Lists = append(opts.Lists, words(r.Intn(4)))
+		}
+	}
+	for i := r.Intn(3); i > 0; i-- {
+		opts.Separators = append(opts.Separators, strings.Repeat("-", i-1))
+	}
+	opts.Prefixes, opts.Suffixes = affixes(), affixes()
+	return reflect.ValueOf(randomOptions{opts})
+}
+
+func names(opts Options) []Name {
+	var all []Name
+	gen := NewGenerator(opts)
+	for name, ok := gen.Next(); ok; name, ok = gen.Next() {
+		all = append(all, name)
+	}
+	return all
+}
+
+func TestCountMatchesNames(t *testing.T) {
+	property := func(o randomOptions) bool {
+		return NewGenerator(o.Options).Count() == len(names(o.Options))
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNoDuplicateNames(t *testing.T) {
+	property := func(o randomOptions) bool {
+		seen := map[string]bool{}
+		for _, name := range names(o.Options) {
+			if seen[name.Base] {
+				t.Logf("%s generated twice from %+v", name.Base, o.Options)
+				return false
+			}
+			seen[name.Base] = true
+		}
+		return true
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestCombinationsFormula compares the count of combinations with n choose
+// k for one list, and with the product of the list lengths for several.
+func TestCombinationsFormula(t *testing.T) {
+	property := func(o randomOptions) bool {
+		want := big.NewInt(1)
+		switch len(o.Lists) {
+		case 0:
+			want.SetInt64(0)
+		case 1:
+			n, k := int64(len(o.Lists[0])), int64(o.Combinations)
+			// A combination of no keywords makes no name.
+			if k == 0 || k > n {
+				want.SetInt64(0)
+			} else {
+				want.Binomial(n, k)
+			}
+		default:
+			for _, list := range o.Lists {
+				want.Mul(want, big.NewInt(int64(len(list))))
+			}
+		}
+		return int64(NewGenerator(o.Options).Combinations()) == want.Int64()
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestKeywordsInListOrder checks that one-list combinations take distinct
+// keywords in list order, and several-list ones a keyword of each list.
+func TestKeywordsInListOrder(t *testing.T) {
+	property := func(o randomOptions) bool {
+		for _, name := range names(o.Options) {
+			if len(o.Lists) == 1 {
+				last := -1
+				for _, keyword := range name.Keywords {
+					i := slices.Index(o.Lists[0], keyword)
+					if i <= last {
+						return false
+					}
+					last = i
+				}
+				continue
+			}
+			for i, keyword := range name.Keywords {
+				if !slices.Contains(o.Lists[i], keyword) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBinomialSaturates(t *testing.T) {
+	for _, tt := range []struct{ n, k int }{
+		{0, 0}, {5, 6}, {5, -1}, {10, 3}, {60, 30}, {66, 33}, {67, 33}, {68, 34}, {1000, 10}, {1000, 500},
+	} {
+		want := math.MaxInt
+		if tt.k < 0 || tt.k > tt.n {
+			want = 0
+		} else if b := new(big.Int).Binomial(int64(tt.n), int64(tt.k)); b.IsInt64() {
+			want = int(b.Int64())
+		}
+		if got := binomial(tt.n, tt.k); got != want {
+			t.Errorf("binomial(%d, %d) = %d, want %d", tt.n, tt.k, got, want)
+		}
+	}
+}
+
+// TestCountSaturates walks only the start of a space too large to count.
+func TestCountSaturates(t *testing.T) {
+	list := make([]string, 200)
+	for i := range list {
+		list[i] = strings.Repeat("a", i+1)
+	}
+	gen := NewGenerator(Options{Lists: [][]string{list}, Combinations: 100})
+	if got := gen.Count(); got != math.MaxInt {
+		t.Errorf("Count() = %d, want math.MaxInt", got)
+	}
+	for i := 0; i < 1000; i++ {
+		if _, ok := gen.Next(); !ok {
+			t.Fatalf("generator stopped after %d names", i)
+		}
+	}
+}
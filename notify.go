@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Transition describes a change in a watched domain's status.
+type Transition struct {
+	Domain            string    `json:"domain"`
+	PreviousStatus    Status    `json:"previousStatus"`
+	PreviousEPPStatus []string  `json:"previousEppStatus,omitempty"`
+	NewStatus         Status    `json:"newStatus"`
+	NewEPPStatus      []string  `json:"newEppStatus,omitempty"`
+	CheckedAt         time.Time `json:"checkedAt"`
+	RunID             string    `json:"runId"`
+	RunStartedAt      time.Time `json:"runStartedAt"`
+	// Registrar is the new registrar of a domain that became taken.
+	Registrar string `json:"registrar,omitempty"`
+}
+
+func shouldNotify(t Transition, notifyOn string) bool {
+	switch notifyOn {
+	case "any":
+		return true
+	case "taken":
+		return t.PreviousStatus == StatusAvailable && t.NewStatus == StatusTaken
+	}
+	return t.NewStatus == StatusAvailable
+}
+
+// MessageNotifier delivers a human-readable message to a chat service.
+type MessageNotifier interface {
+	Name() string
+	SendMessage(ctx context.Context, text string) error
+}
+
+type NotifyFlags struct {
+	SlackWebhook   *string
+	TelegramToken  *string
+	TelegramChatID *string
+}
+
+func addNotifyFlags(fs *flag.FlagSet) *NotifyFlags {
+	return &NotifyFlags{
+		SlackWebhook:   fs.String("slack-webhook", "", "Slack incoming webhook URL to post notifications to"),
+		TelegramToken:  fs.String("telegram-token", "", "Telegram bot token used to send notifications"),
+		TelegramChatID: fs.String("telegram-chat-id", "", "Telegram chat ID to send notifications to"),
+	}
+}
+
+func (f *NotifyFlags) Notifiers() ([]MessageNotifier, error) {
+	var notifiers []MessageNotifier
+	if *f.SlackWebhook != "" {
+		notifiers = append(notifiers, &SlackNotifier{WebhookURL: *f.SlackWebhook})
+	}
+	if (*f.TelegramToken == "") != (*f.TelegramChatID == "") {
+		return nil, fmt.Errorf("-telegram-token and -telegram-chat-id must be used together")
+	}
+	if *f.TelegramToken != "" {
+		notifiers = append(notifiers, &TelegramNotifier{Token: *f.TelegramToken, ChatID: *f.TelegramChatID})
+	}
+	return notifiers, nil
+}
+
+const maxMessageDomains = 50
+
+func formatTransitionsMessage(transitions []Transition) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Domain status changes (%d):\n", len(transitions))
+	for i, t := range transitions {
+		if i == maxMessageDomains {
+			fmt.Fprintf(&b, "… and %d more\n", len(transitions)-i)
+			break
+		}
+		fmt.Fprintf(&b, "• %s\n", t)
+	}
+	return b.String()
+}
+
+func formatSummaryMessage(results []DomainResult) string {
+	var available []string
+	for _, result := range results {
+		if result.Status == StatusAvailable {
+			available = append(available, result.Domain)
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Domain check finished: %d of %d domains available\n", len(available), len(results))
+	for i, domain := range available {
+		if i == maxMessageDomains {
+			fmt.Fprintf(&b, "… and %d more\n", len(available)-i)
+			break
+		}
+		fmt.Fprintf(&b, "• %s\n", domain)
+	}
+	return b.String()
+}
+
+// truncateMessage cuts text to at most limit bytes on a line boundary.
+func truncateMessage(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+	const notice = "… (message truncated)\n"
+	cut := strings.LastIndex(text[:limit-len(notice)], "\n")
+	if cut < 0 {
+		cut = limit - len(notice)
+	}
+	return text[:cut+1] + notice
+}
+
+func sendMessages(ctx context.Context, notifiers []MessageNotifier, text string) []error {
+	var errs []error
+	for _, n := range notifiers {
+		if err := n.SendMessage(ctx, text); err != nil {
+			errs = append(errs, fmt.Errorf("%s notification failed: %w", n.Name(), err))
+		}
+	}
+	return errs
+}
+
+type SlackNotifier struct {
+	WebhookURL string
+}
+
+func (s *SlackNotifier) Name() string { return "slack" }
+
+func (s *SlackNotifier) SendMessage(ctx context.Context, text string) error {
+	body, err := json.Marshal(map[string]string{"text": truncateMessage(text, 3000)})
+	if err != nil {
+		return err
+	}
+	return postWithRetry(ctx, s.WebhookURL, "application/json", body, nil)
+}
+
+type TelegramNotifier struct {
+	Token  string
+	ChatID string
+}
+
+func (t *TelegramNotifier) Name() string { return "telegram" }
+
+func (t *TelegramNotifier) SendMessage(ctx context.Context, text string) error {
+	form := url.Values{
+		"chat_id": {t.ChatID},
+		"text":    {truncateMessage(text, 4096)},
+	}
+	endpoint := "https://api.telegram.org/bot" + t.Token + "/sendMessage"
+	return postWithRetry(ctx, endpoint, "application/x-www-form-urlencoded", []byte(form.Encode()), nil)
+}
+
+const webhookSignatureHeader = "X-Domain-Checker-Signature"
+
+// WebhookNotifier POSTs transitions as JSON. When Secret is set, the body is
+// signed with HMAC-SHA256 and the hex digest sent as "sha256=<digest>" in the
+// X-Domain-Checker-Signature header.
+type WebhookNotifier struct {
+	URL    string
+	Secret string
+}
+
+// SweepReport is posted after every completed monitor sweep when
+// -webhook-sweeps is set. Event tells it apart from transitions.
+type SweepReport struct {
+	Event string `json:"event"`
+	RunInfo
+	Summary    Summary      `json:"summary"`
+	TLDSummary []TLDSummary `json:"tldSummary"`
+}
+
+func newSweepReport(run RunInfo, results []DomainResult) SweepReport {
+	return SweepReport{Event: "sweep", RunInfo: run, Summary: summarize(results), TLDSummary: summarizeTLDs(results)}
+}
+
+// FailureAlert is sent once when a watched domain has failed
+// -alert-after-failures checks in a row, so it is no longer watched in
+// practice even though its status never changed.
+type FailureAlert struct {
+	Event     string    `json:"event"`
+	Domain    string    `json:"domain"`
+	Failures  int       `json:"failures"`
+	Error     string    `json:"error"`
+	CheckedAt time.Time `json:"checkedAt"`
+	RunID     string    `json:"runId"`
+}
+
+func (a FailureAlert) String() string {
+	return fmt.Sprintf("%s: failed the last %d checks (%s)", a.Domain, a.Failures, a.Error)
+}
+
+func formatFailuresMessage(alerts []FailureAlert) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Domains that cannot be monitored (%d):\n", len(alerts))
+	for i, a := range alerts {
+		if i == maxMessageDomains {
+			fmt.Fprintf(&b, "… and %d more\n", len(alerts)-i)
+			break
+		}
+		fmt.Fprintf(&b, "• %s\n", a)
+	}
+	return b.String()
+}
+
+func (w *WebhookNotifier) SendAlert(ctx context.Context, a FailureAlert) error {
+	return w.post(ctx, a)
+}
+
+func (w *WebhookNotifier) Send(ctx context.Context, t Transition) error {
+	return w.post(ctx, t)
+}
+
+func (w *WebhookNotifier) SendSweep(ctx context.Context, report SweepReport) error {
+	return w.post(ctx, report)
+}
+
+func (w *WebhookNotifier) post(ctx context.Context, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	headers := map[string]string{}
+	if w.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(w.Secret))
+		mac.Write(body)
+		headers[webhookSignatureHeader] = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	return postWithRetry(ctx, w.URL, "application/json", body, headers)
+}
+
+var notifyClient = &http.Client{Timeout: 10 * time.Second, Transport: budgetTransport{}}
+
+const notifyRetries = 4
+
+// postWithRetry POSTs body, retrying network errors, 429 and 5xx responses
+// with exponential backoff.
+func postWithRetry(ctx context.Context, endpoint, contentType string, body []byte, headers map[string]string) error {
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		retriable, err := post(ctx, endpoint, contentType, body, headers)
+		if err == nil || !retriable || attempt == notifyRetries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post delivers body once and reports whether a failure is worth retrying.
+func post(ctx context.Context, endpoint, contentType string, body []byte, headers map[string]string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", userAgent)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := notifyClient.Do(req)
+	if err != nil {
+		// Drop the URL from the error: it may embed a bot token.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return true, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		retriable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return retriable, fmt.Errorf("server returned %s", resp.Status)
+	}
+	return false, nil
+}
+
+func newRunID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,1116 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// Report is everything a run produced; it is the document written by the
+// json format and read back by the results subcommands.
+type Report struct {
+	// SchemaVersion is set to outputSchemaVersion when the report is
+	// written as json.
+	SchemaVersion int `json:"schemaVersion"`
+	RunInfo
+	Summary    Summary      `json:"summary"`
+	TLDSummary []TLDSummary `json:"tldSummary,omitempty"`
+	// KeywordStats is only filled in with -keyword-stats.
+	KeywordStats []KeywordStat `json:"keywordStats,omitempty"`
+	// Stats is always filled in; the text output shows it with -stats.
+	Stats *NameStats `json:"stats,omitempty"`
+	// Mismatches is only filled in with -expect-file.
+	Mismatches []Mismatch `json:"mismatches,omitempty"`
+	// Conflicts is only filled in with -verify-available.
+	Conflicts []VerifyConflict `json:"conflicts,omitempty"`
+	Results   []DomainResult   `json:"results"`
+}
+
+// summarize fills in the summaries from the results.
+func (r *Report) summarize() {
+	r.Summary = summarize(r.Results)
+	r.TLDSummary = summarizeTLDs(r.Results)
+	r.Stats = nameStats(r.Results)
+}
+
+// RunInfo identifies a run in every output it produces.
+type RunInfo struct {
+	ID         string    `json:"runId,omitempty"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	// Quota is only set with -quota.
+	Quota *QuotaUsage `json:"quota,omitempty"`
+	// Manifest is nil for reports merged from others or read from older
+	// files.
+	Manifest *Manifest `json:"manifest,omitempty"`
+}
+
+func (r RunInfo) String() string {
+	s := "Run"
+	if r.ID != "" {
+		s += " " + r.ID
+	}
+	s += " started " + displayTime(r.StartedAt, "2006-01-02 15:04:05")
+	if !r.FinishedAt.IsZero() {
+		s += ", took " + r.FinishedAt.Sub(r.StartedAt).Round(time.Millisecond).String()
+	}
+	if r.Quota != nil {
+		s += fmt.Sprintf(", whois quota %d/%d used, %d left", r.Quota.Used, r.Quota.Limit, r.Quota.Remaining)
+	}
+	return s
+}
+
+type Summary struct {
+	Available int `json:"available"`
+	Taken     int `json:"taken"`
+	Errors    int `json:"errors"`
+	Reserved  int `json:"reserved,omitempty"`
+	Unknown   int `json:"unknown,omitempty"`
+	Deferred  int `json:"deferred,omitempty"`
+	Unchecked int `json:"unchecked,omitempty"`
+	Exists    int `json:"exists,omitempty"`
+	Absent    int `json:"absent,omitempty"`
+	Cached    int `json:"cached,omitempty"`
+	// CachedAvailable and CachedTaken split Cached by verdict.
+	CachedAvailable int `json:"cachedAvailable,omitempty"`
+	CachedTaken     int `json:"cachedTaken,omitempty"`
+	Total           int `json:"total"`
+}
+
+func (s Summary) String() string {
+	text := fmt.Sprintf("%d available, %d taken, %d errors", s.Available, s.Taken, s.Errors)
+	if s.Reserved > 0 {
+		text += fmt.Sprintf(", %d reserved", s.Reserved)
+	}
+	if s.Unknown > 0 {
+		text += fmt.Sprintf(", %d unknown", s.Unknown)
+	}
+	if s.Deferred > 0 {
+		text += fmt.Sprintf(", %d deferred by the registry", s.Deferred)
+	}
+	if s.Exists > 0 || s.Absent > 0 {
+		text += fmt.Sprintf(", %d subdomains exist, %d do not", s.Exists, s.Absent)
+	}
+	return text + fmt.Sprintf(" (total: %d)", s.Total)
+}
+
+// summaryLine is the single-line summary printed on stderr at the end of a
+// run. Its format is a stable interface for scripts: "result" followed by
+// key=value pairs named as in the json output, every counter included even
+// when zero, the run duration in seconds and the run ID. Keys are listed
+// here rather than taken from Summary so that the line only changes on
+// purpose.
+func summaryLine(report *Report) string {
+	s := report.Summary
+	var b strings.Builder
+	fmt.Fprintf(&b, "result available=%d taken=%d errors=%d reserved=%d unknown=%d deferred=%d unchecked=%d exists=%d absent=%d",
+		s.Available, s.Taken, s.Errors, s.Reserved, s.Unknown, s.Deferred, s.Unchecked, s.Exists, s.Absent)
+	fmt.Fprintf(&b, " cached=%d cachedAvailable=%d cachedTaken=%d total=%d", s.Cached, s.CachedAvailable, s.CachedTaken, s.Total)
+	duration := report.FinishedAt.Sub(report.StartedAt).Seconds()
+	fmt.Fprintf(&b, " duration=%s runId=%s", strconv.FormatFloat(duration, 'f', 1, 64), report.ID)
+	if report.Quota != nil {
+		fmt.Fprintf(&b, " quotaUsed=%d quotaRemaining=%d", report.Quota.Used, report.Quota.Remaining)
+	}
+	return b.String()
+}
+
+func summarize(results []DomainResult) Summary {
+	s := Summary{Total: len(results)}
+	for _, result := range results {
+		switch result.Status {
+		case StatusAvailable:
+			s.Available++
+		case StatusError:
+			s.Errors++
+		case StatusUnchecked:
+			s.Unchecked++
+		case StatusUnknown:
+			s.Unknown++
+		case StatusDeferred:
+			s.Deferred++
+		case StatusReserved:
+			s.Reserved++
+		case StatusExists:
+			s.Exists++
+		case StatusAbsent:
+			s.Absent++
+		default:
+			s.Taken++
+		}
+		if result.Cached {
+			s.Cached++
+			switch result.Status {
+			case StatusAvailable:
+				s.CachedAvailable++
+			case StatusTaken:
+				s.CachedTaken++
+			}
+		}
+	}
+	return s
+}
+
+type jsonDomainResult struct {
+	Domain     string                  `json:"domain"`
+	Status     Status                  `json:"status"`
+	EPPStatus  []string                `json:"eppStatus,omitempty"`
+	Registrar  string                  `json:"registrar,omitempty"`
+	CreatedAt  *time.Time              `json:"createdAt,omitempty"`
+	ExpiresAt  *time.Time              `json:"expiresAt,omitempty"`
+	Method     string                  `json:"method,omitempty"`
+	CheckedAt  time.Time               `json:"checkedAt"`
+	DurationMs int64                   `json:"durationMs"`
+	Suggested  bool                    `json:"suggested,omitempty"`
+	Score      float64                 `json:"score,omitempty"`
+	Handles    map[string]HandleStatus `json:"handles,omitempty"`
+	Cached     bool                    `json:"cached,omitempty"`
+	Links      []PurchaseLink          `json:"links,omitempty"`
+	Price      *Price                  `json:"price,omitempty"`
+	RunID      string                  `json:"runId,omitempty"`
+	Server     string                  `json:"server,omitempty"`
+	Referral   string                  `json:"referral,omitempty"`
+	Confidence string                  `json:"confidence,omitempty"`
+	Candidate  *Candidate              `json:"candidate,omitempty"`
+	Error      string                  `json:"error,omitempty"`
+	Attempts   []Attempt               `json:"attempts,omitempty"`
+	RawExcerpt string                  `json:"rawExcerpt,omitempty"`
+}
+
+// includeRawExcerpts adds the reply excerpts to the json outputs, for
+// -include-raw-excerpt. They are left out by default: replies may hold
+// contact data.
+var includeRawExcerpts bool
+
+func (r DomainResult) MarshalJSON() ([]byte, error) {
+	j := jsonDomainResult{
+		Domain:     r.Domain,
+		Status:     r.Status,
+		EPPStatus:  r.EPPStatus,
+		Registrar:  r.Registrar,
+		CreatedAt:  optionalTime(r.CreatedAt),
+		ExpiresAt:  optionalTime(r.ExpiresAt),
+		Method:     r.Method,
+		CheckedAt:  r.CheckedAt.UTC(),
+		DurationMs: r.Duration.Milliseconds(),
+		Suggested:  r.Suggested,
+		Score:      r.Score,
+		Handles:    r.Handles,
+		Cached:     r.Cached,
+		Links:      r.Links,
+		Price:      r.Price,
+		RunID:      r.RunID,
+		Server:     r.Server,
+		Referral:   r.Referral,
+		Confidence: r.Confidence,
+		Candidate:  r.Candidate,
+		Attempts:   r.Attempts,
+	}
+	if includeRawExcerpts {
+		j.RawExcerpt = r.RawExcerpt
+	}
+	if r.Error != nil {
+		j.Error = r.Error.Error()
+	}
+	return json.Marshal(j)
+}
+
+func (r *DomainResult) UnmarshalJSON(data []byte) error {
+	var j jsonDomainResult
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	*r = DomainResult{
+		Domain:     j.Domain,
+		Status:     j.Status,
+		EPPStatus:  j.EPPStatus,
+		Registrar:  j.Registrar,
+		Method:     j.Method,
+		CheckedAt:  j.CheckedAt,
+		Duration:   time.Duration(j.DurationMs) * time.Millisecond,
+		Suggested:  j.Suggested,
+		Score:      j.Score,
+		Handles:    j.Handles,
+		Cached:     j.Cached,
+		Links:      j.Links,
+		Price:      j.Price,
+		RunID:      j.RunID,
+		Server:     j.Server,
+		Referral:   j.Referral,
+		Confidence: j.Confidence,
+		Candidate:  j.Candidate,
+		Attempts:   j.Attempts,
+		RawExcerpt: j.RawExcerpt,
+	}
+	if r.Candidate != nil {
+		r.Candidate.FQDN = r.Domain
+	}
+	if j.CreatedAt != nil {
+		r.CreatedAt = *j.CreatedAt
+	}
+	if j.ExpiresAt != nil {
+		r.ExpiresAt = *j.ExpiresAt
+	}
+	if j.Error != "" {
+		r.Error = errors.New(j.Error)
+	}
+	return nil
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
+
+// RenderOptions control the human-oriented formats; structured formats
+// always contain everything.
+type RenderOptions struct {
+	Sections  []Status
+	NoSummary bool
+	Rank      bool
+	// Expect is the status every domain should have, as set by -expect.
+	Expect Status
+	// Title describes the run, e.g. the flags it was started with.
+	Title string
+	// Columns and Wide shape the table format.
+	Columns []string
+	Wide    bool
+	// MaxList caps the entries of each text section; zero shows them all.
+	// FullListPath names the file holding everything, for the notice.
+	MaxList      int
+	FullListPath string
+	// ErrorsFull lists every failed domain instead of grouping the errors.
+	ErrorsFull bool
+	// Sort orders the available domains: "length" or "name"; empty keeps
+	// the check order (or the score order with Rank).
+	Sort string
+	// Servers adds the whois server that answered to each listed domain.
+	Servers bool
+	// ShorterThan only lists available names shorter than this; zero lists
+	// them all.
+	ShorterThan int
+	// Stats adds the name statistics to the text output.
+	Stats bool
+	// Explain adds the attempts behind every verdict to the text output.
+	Explain bool
+	// Excerpts adds the start of the replies behind errors and unknown
+	// verdicts to the text output.
+	Excerpts bool
+}
+
+func (o RenderOptions) showSection(status Status) bool {
+	return len(o.Sections) == 0 || slices.Contains(o.Sections, status)
+}
+
+type RenderFlags struct {
+	Show        *string
+	NoSummary   *bool
+	Columns     *string
+	Wide        *bool
+	MaxList     *int
+	ErrorsFull  *bool
+	Sort        *string
+	ShorterThan *int
+	Stats       *bool
+}
+
+func addRenderFlags(fs *flag.FlagSet) *RenderFlags {
+	return &RenderFlags{
+		Show:        fs.String("show", "", "Comma-separated report sections to show: available, taken, reserved, errors, unknown, deferred, unchecked, exists, absent (default: all)"),
+		NoSummary:   fs.Bool("no-summary", false, "Do not print the summary line"),
+		Columns:     fs.String("columns", "", "Comma-separated columns of the table format: "+tableColumnNames()),
+		Wide:        fs.Bool("wide", false, "Do not truncate long values in the table format"),
+		ErrorsFull:  fs.Bool("errors-full", false, "List every failed domain with its error instead of grouping identical errors"),
+		Sort:        fs.String("sort", "", "Order of the available domains: length (shortest name first, TLD excluded) or name (default: check order, or score with -rank)"),
+		ShorterThan: fs.Int("shorter-than", 0, "Only list available domains whose name, TLD excluded, is shorter than this many characters; everything is still checked"),
+		Stats:       fs.Bool("stats", false, "Show how name length, dashes and the number of keywords combined relate to availability (the json output always has them)"),
+		MaxList:     fs.Int("max-list", 200, "Maximum entries listed per section of the text output (0 for no limit); files always get everything"),
+	}
+}
+
+var sectionNames = map[string]Status{
+	"available": StatusAvailable,
+	"taken":     StatusTaken,
+	"errors":    StatusError,
+	"reserved":  StatusReserved,
+	"unknown":   StatusUnknown,
+	"deferred":  StatusDeferred,
+	"unchecked": StatusUnchecked,
+	"exists":    StatusExists,
+	"absent":    StatusAbsent,
+}
+
+func (f *RenderFlags) Options() (RenderOptions, error) {
+	if *f.MaxList < 0 {
+		return RenderOptions{}, fmt.Errorf("-max-list cannot be negative")
+	}
+	switch *f.Sort {
+	case "", "length", "name":
+	default:
+		return RenderOptions{}, fmt.Errorf("unknown -sort %q (use length or name)", *f.Sort)
+	}
+	if *f.ShorterThan < 0 {
+		return RenderOptions{}, fmt.Errorf("-shorter-than cannot be negative")
+	}
+	opts := RenderOptions{NoSummary: *f.NoSummary, Wide: *f.Wide, MaxList: *f.MaxList, ErrorsFull: *f.ErrorsFull, Sort: *f.Sort, ShorterThan: *f.ShorterThan, Stats: *f.Stats}
+	for _, name := range parseKeywords(strings.ToLower(*f.Columns)) {
+		if _, ok := tableColumns[name]; !ok {
+			return opts, fmt.Errorf("unknown -columns column %q (use %s)", name, tableColumnNames())
+		}
+		opts.Columns = append(opts.Columns, name)
+	}
+	for _, name := range parseKeywords(*f.Show) {
+		status, ok := sectionNames[strings.ToLower(name)]
+		if !ok {
+			return opts, fmt.Errorf("unknown -show section %q (use available, taken, reserved, errors, unknown, deferred, unchecked, exists or absent)", name)
+		}
+		opts.Sections = append(opts.Sections, status)
+	}
+	return opts, nil
+}
+
+type renderer func(io.Writer, *Report, RenderOptions) error
+
+var renderers = map[string]renderer{
+	"text":            writeText,
+	"json":            writeJSON,
+	"ndjson":          writeNDJSON,
+	"markdown":        writeMarkdown,
+	"quiet":           writeQuiet,
+	"junit":           writeJUnit,
+	"table":           writeTable,
+	"matrix":          writeMatrixCSV,
+	"matrix-markdown": writeMatrixMarkdown,
+	"csv": func(w io.Writer, report *Report, opts RenderOptions) error {
+		if report.Manifest != nil {
+			for _, line := range report.Manifest.lines(report.RunInfo) {
+				fmt.Fprintf(w, "# %s\n", line)
+			}
+		}
+		return writeCSV(w, report.Results)
+	},
+}
+
+func rendererNames() string {
+	return "text, table, json, ndjson, csv, markdown, junit, matrix, matrix-markdown or quiet"
+}
+
+// formatForPath picks the output format of a results file from its extension.
+func formatForPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ndjson", ".jsonl":
+		return "ndjson"
+	case ".csv":
+		return "csv"
+	case ".txt":
+		return "text"
+	case ".md", ".markdown":
+		return "markdown"
+	case ".xml":
+		return "junit"
+	default:
+		return "json"
+	}
+}
+
+func writeReportFile(path string, report *Report) error {
+	return writeReportFileFormat(path, formatForPath(path), report)
+}
+
+func writeReportFileFormat(path, format string, report *Report) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("writing output file: %w", err)
+	}
+	w := bufio.NewWriter(file)
+	if err := renderers[format](w, report, RenderOptions{}); err != nil {
+		file.Close()
+		return fmt.Errorf("writing output file: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("writing output file: %w", err)
+	}
+	return file.Close()
+}
+
+// readReportFile loads a results file written in the json or ndjson format.
+func readReportFile(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading results file: %w", err)
+	}
+
+	report := &Report{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var first json.RawMessage
+	if err := dec.Decode(&first); err != nil {
+		return nil, fmt.Errorf("parsing results file %s: %w", path, err)
+	}
+
+	// A json report is a single object with a results array; anything else
+	// is treated as one result per line.
+	var probe struct {
+		Results json.RawMessage `json:"results"`
+	}
+	if json.Unmarshal(first, &probe) == nil && probe.Results != nil && !dec.More() {
+		if err := json.Unmarshal(first, report); err != nil {
+			return nil, fmt.Errorf("parsing results file %s: %w", path, err)
+		}
+		if report.SchemaVersion > outputSchemaVersion {
+			return nil, fmt.Errorf("results file %s has schema version %d, newer than the %d this version reads", path, report.SchemaVersion, outputSchemaVersion)
+		}
+		if report.TLDSummary == nil {
+			report.TLDSummary = summarizeTLDs(report.Results)
+		}
+		return report, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var result DomainResult
+		if err := json.Unmarshal([]byte(text), &result); err != nil {
+			return nil, fmt.Errorf("parsing results file %s line %d: %w", path, line, err)
+		}
+		report.Results = append(report.Results, result)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading results file: %w", err)
+	}
+	report.summarize()
+	return report, nil
+}
+
+func writeJSON(w io.Writer, report *Report, opts RenderOptions) error {
+	versioned := *report
+	versioned.SchemaVersion = outputSchemaVersion
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(versioned)
+}
+
+func writeNDJSON(w io.Writer, report *Report, opts RenderOptions) error {
+	enc := json.NewEncoder(w)
+	for _, result := range report.Results {
+		if err := enc.Encode(result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeText(w io.Writer, report *Report, opts RenderOptions) error {
+	printResults(w, report.Results, opts)
+	if len(report.Conflicts) > 0 {
+		fmt.Fprintln(w)
+		writeConflicts(w, report.Conflicts)
+	}
+	if opts.Explain {
+		fmt.Fprintln(w)
+		writeAttempts(w, report.Results)
+	}
+	if opts.Excerpts && hasExcerpts(report.Results) {
+		fmt.Fprintln(w)
+		writeExcerpts(w, report.Results)
+	}
+	if len(report.Mismatches) > 0 {
+		fmt.Fprintln(w)
+		writeMismatches(w, report.Mismatches)
+	}
+	if len(report.KeywordStats) > 0 {
+		fmt.Fprintln(w)
+		writeKeywordStats(w, report.KeywordStats)
+	}
+	if opts.Stats && report.Stats != nil {
+		fmt.Fprintln(w)
+		writeNameStats(w, report.Stats)
+	}
+	if !opts.NoSummary && !report.StartedAt.IsZero() {
+		fmt.Fprintln(w, report.RunInfo)
+	}
+	return nil
+}
+
+// formatAge renders a duration in the largest whole unit, e.g. "3 days".
+func formatAge(d time.Duration) string {
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s", unit)
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+	switch {
+	case d >= 48*time.Hour:
+		return plural(int(d/(24*time.Hour)), "day")
+	case d >= 2*time.Hour:
+		return plural(int(d/time.Hour), "hour")
+	case d >= time.Minute:
+		return plural(int(d/time.Minute), "minute")
+	default:
+		return "less than a minute"
+	}
+}
+
+// writeQuiet prints only the available domains, one per line.
+func writeQuiet(w io.Writer, report *Report, opts RenderOptions) error {
+	for _, result := range rankedAvailable(report.Results, opts) {
+		if _, err := fmt.Fprintln(w, result.Domain); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeMarkdown(w io.Writer, report *Report, opts RenderOptions) error {
+	if report.Manifest != nil {
+		fmt.Fprintf(w, "<!--\n%s\n-->\n", strings.Join(report.Manifest.lines(report.RunInfo), "\n"))
+	}
+	fmt.Fprintf(w, "# Domain check results\n\n")
+	if checkedAt := reportCheckedAt(report); !checkedAt.IsZero() {
+		fmt.Fprintf(w, "_Checked %s_\n\n", displayTime(checkedAt, "2006-01-02 15:04 MST"))
+	}
+
+	sections := []struct {
+		title  string
+		status Status
+	}{
+		{"✓ Available", StatusAvailable},
+		{"✗ Taken", StatusTaken},
+		{"⊘ Reserved / blocked", StatusReserved},
+		{"⚠ Errors", StatusError},
+		{"? Unknown (reply not recognised)", StatusUnknown},
+		{"⏸ Deferred (registry unavailable, not the name)", StatusDeferred},
+		{"? Unchecked", StatusUnchecked},
+		{"Subdomains that exist in DNS", StatusExists},
+		{"Subdomains that do not exist in DNS", StatusAbsent},
+	}
+	results, suggested := splitSuggestions(report.Results)
+	for _, section := range sections {
+		if !opts.showSection(section.status) {
+			continue
+		}
+		var rows []DomainResult
+		if section.status == StatusAvailable {
+			rows = rankedAvailable(results, opts)
+		} else {
+			for _, result := range results {
+				if result.Status == section.status {
+					rows = append(rows, result)
+				}
+			}
+		}
+		if len(rows) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(w, "## %s (%d)\n\n", section.title, len(rows))
+		if section.status == StatusAvailable {
+			writeMarkdownAvailable(w, rows, opts)
+			fmt.Fprintln(w)
+			continue
+		}
+		for _, result := range rows {
+			switch {
+			case result.Error != nil:
+				fmt.Fprintf(w, "- `%s`: %s\n", result.Domain, result.Error)
+			default:
+				fmt.Fprintf(w, "- `%s`%s\n", result.Domain, replayNote(result))
+			}
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(report.Conflicts) > 0 {
+		fmt.Fprintf(w, "## ⚠ Whois/DNS conflicts (%d)\n\n", len(report.Conflicts))
+		for _, c := range report.Conflicts {
+			fmt.Fprintf(w, "- `%s`: %s said available, but %s\n", c.Domain, c.Whois, c.DNS)
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(report.Mismatches) > 0 {
+		fmt.Fprintf(w, "## ⚠ Mismatches (%d)\n\n", len(report.Mismatches))
+		for _, m := range report.Mismatches {
+			fmt.Fprintf(w, "- `%s`: %s\n", m.Domain, m.Reason)
+		}
+		fmt.Fprintln(w)
+	}
+
+	suggestedAvailable := rankedAvailable(suggested, opts)
+	if len(suggestedAvailable) > 0 && opts.showSection(StatusAvailable) {
+		fmt.Fprintf(w, "## 💡 Suggestions available (%d)\n\n", len(suggestedAvailable))
+		writeMarkdownAvailable(w, suggestedAvailable, opts)
+		fmt.Fprintln(w)
+	}
+
+	if opts.NoSummary {
+		return nil
+	}
+	s := summarize(results)
+	_, err := fmt.Fprintf(w, "**Summary:** %s\n", s)
+	if cached, skipped := cacheCounts(results); err == nil && (cached > 0 || skipped > 0) {
+		_, err = fmt.Fprintf(w, "\n**Cache:** %d answered from cache%s, %d skipped\n", cached, cachedBreakdown(results), skipped)
+	}
+	if err == nil && !report.StartedAt.IsZero() {
+		_, err = fmt.Fprintf(w, "\n_%s_\n", report.RunInfo)
+	}
+	return err
+}
+
+// splitSuggestions separates the results of the -suggest pass from the rest.
+func splitSuggestions(results []DomainResult) (regular, suggested []DomainResult) {
+	for _, result := range results {
+		if result.Suggested {
+			suggested = append(suggested, result)
+		} else {
+			regular = append(regular, result)
+		}
+	}
+	return regular, suggested
+}
+
+// rankedAvailable returns the available results, best score first when
+// ranking is enabled.
+func rankedAvailable(results []DomainResult, opts RenderOptions) []DomainResult {
+	var available []DomainResult
+	for _, result := range results {
+		if result.Status != StatusAvailable {
+			continue
+		}
+		if opts.ShorterThan > 0 && nameLength(result) >= opts.ShorterThan {
+			continue
+		}
+		available = append(available, result)
+	}
+	if opts.Rank {
+		sort.SliceStable(available, func(i, j int) bool {
+			return available[i].Score > available[j].Score
+		})
+	}
+	switch opts.Sort {
+	case "length":
+		sort.SliceStable(available, func(i, j int) bool {
+			return nameLength(available[i]) < nameLength(available[j])
+		})
+	case "name":
+		sort.SliceStable(available, func(i, j int) bool {
+			return available[i].Domain < available[j].Domain
+		})
+	}
+	return available
+}
+
+// nameLength is the length of the name without its TLD, in characters of
+// the Unicode form, so münchen counts 7 rather than the 14 of its punycode.
+func nameLength(result DomainResult) int {
+	name := strings.TrimSuffix(result.Domain, "."+domainTLD(result.Domain))
+	if result.Candidate != nil && result.Candidate.BaseName != "" {
+		name = result.Candidate.BaseName
+	}
+	if unicode, err := idna.ToUnicode(name); err == nil {
+		name = unicode
+	}
+	return utf8.RuneCountInString(name)
+}
+
+func availableLine(result DomainResult, opts RenderOptions) string {
+	return result.Domain + availableDetails(result, opts)
+}
+
+func availableDetails(result DomainResult, opts RenderOptions) string {
+	line := replayNote(result) + confidenceNote(result) + serverNote(result, opts)
+	if result.Price != nil {
+		line += fmt.Sprintf(" (%s)", result.Price)
+	}
+	if opts.Rank {
+		line += fmt.Sprintf(" (score %.1f)", result.Score)
+	}
+	if handles := formatHandles(result.Handles); handles != "" {
+		line += "  " + handles
+	}
+	return line
+}
+
+func printLinks(w io.Writer, links []PurchaseLink) {
+	for _, link := range links {
+		fmt.Fprintf(w, "    %s: %s\n", link.Registrar, link.URL)
+	}
+}
+
+// replayNote marks verdicts taken from the cache instead of checked now.
+func replayNote(result DomainResult) string {
+	if !result.Cached {
+		return ""
+	}
+	return fmt.Sprintf(" [cached, %s ago]", formatAge(time.Since(result.CheckedAt)))
+}
+
+// serverNote names the servers that answered, with -verbose.
+func serverNote(result DomainResult, opts RenderOptions) string {
+	if !opts.Servers || result.Server == "" {
+		return ""
+	}
+	return " [via " + serverPath(result) + "]"
+}
+
+// registryNote names the server that gave a deferred reply, so the registry
+// at fault is visible without -verbose.
+func registryNote(result DomainResult) string {
+	if result.Server == "" {
+		return ""
+	}
+	return " [" + result.Server + "]"
+}
+
+// serverPath is the server that answered, followed by the referral.
+func serverPath(result DomainResult) string {
+	if result.Referral == "" {
+		return result.Server
+	}
+	return result.Server + " " + sym.Arrow + " " + result.Referral
+}
+
+// reasonNote explains an unknown verdict the checker recorded a reason for.
+func reasonNote(result DomainResult) string {
+	if result.Error == nil {
+		return ""
+	}
+	return fmt.Sprintf(" (%v)", result.Error)
+}
+
+// confidenceNote marks verdicts drawn from indirect evidence.
+func confidenceNote(result DomainResult) string {
+	if result.Confidence != ConfidenceLow {
+		return ""
+	}
+	return fmt.Sprintf(" [%s, low confidence]", result.Method)
+}
+
+// errorExamples is how many domains are shown for each group of errors.
+const errorExamples = 5
+
+// errorGroup is a distinct error message and the domains that got it.
+type errorGroup struct {
+	Category ErrorCategory
+	Message  string
+	Domains  []string
+}
+
+// groupErrors merges identical errors, most frequent first, so a registry
+// refusing every query shows up once rather than hundreds of times.
+func groupErrors(errors []DomainResult) []errorGroup {
+	var groups []errorGroup
+	index := map[string]int{}
+	for _, result := range errors {
+		category := categorizeError(result.Error)
+		message := "unknown error"
+		if result.Error != nil {
+			message = result.Error.Error()
+		}
+		key := string(category) + "\x00" + message
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, errorGroup{Category: category, Message: message})
+		}
+		groups[i].Domains = append(groups[i].Domains, result.Domain)
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return len(groups[i].Domains) > len(groups[j].Domains) })
+	return groups
+}
+
+// listed is how many of n section entries the text output shows.
+func (o RenderOptions) listed(n int) int {
+	if o.MaxList > 0 && n > o.MaxList {
+		return o.MaxList
+	}
+	return n
+}
+
+// printMore closes a truncated section with where the rest can be found.
+func (o RenderOptions) printMore(w io.Writer, n int) {
+	hidden := n - o.listed(n)
+	if hidden == 0 {
+		return
+	}
+	where := "raise -max-list or set it to 0 to list them all"
+	if o.FullListPath != "" {
+		where = "see " + o.FullListPath + " for the full list"
+	}
+	fmt.Fprintf(w, "  %s and %s more (%s)\n", sym.Ellipsis, formatCount(hidden), where)
+}
+
+// formatCount writes n with thousands separators, e.g. 29,800.
+func formatCount(n int) string {
+	s := strconv.Itoa(n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
+
+func printResults(w io.Writer, results []DomainResult, opts RenderOptions) {
+	taken := []string{}
+	errors := []DomainResult{}
+	unchecked := []string{}
+	unknown := []string{}
+	deferred := []string{}
+	reserved := []string{}
+	exists := []string{}
+	absent := []string{}
+
+	results, suggested := splitSuggestions(results)
+	available := rankedAvailable(results, opts)
+	for _, result := range results {
+		switch result.Status {
+		case StatusError:
+			errors = append(errors, result)
+		case StatusUnchecked:
+			unchecked = append(unchecked, result.Domain+uncheckedNote(result))
+		case StatusUnknown:
+			unknown = append(unknown, result.Domain+replayNote(result)+serverNote(result, opts)+reasonNote(result))
+		case StatusDeferred:
+			deferred = append(deferred, result.Domain+registryNote(result)+reasonNote(result))
+		case StatusReserved:
+			reserved = append(reserved, result.Domain+replayNote(result)+serverNote(result, opts))
+		case StatusExists:
+			exists = append(exists, result.Domain+replayNote(result))
+		case StatusAbsent:
+			absent = append(absent, result.Domain+replayNote(result))
+		case StatusAvailable:
+		default:
+			taken = append(taken, result.Domain+replayNote(result)+confidenceNote(result)+serverNote(result, opts))
+		}
+	}
+
+	if len(available) > 0 && opts.showSection(StatusAvailable) {
+		fmt.Fprintf(w, "%s AVAILABLE (%d):\n", sym.Check, len(available))
+		for _, result := range available[:opts.listed(len(available))] {
+			fmt.Fprintf(w, "  %s\n", availableLine(result, opts))
+			printLinks(w, result.Links)
+		}
+		opts.printMore(w, len(available))
+		fmt.Fprintln(w)
+	}
+
+	if len(taken) > 0 && opts.showSection(StatusTaken) {
+		fmt.Fprintf(w, "%s TAKEN (%d):\n", sym.Cross, len(taken))
+		for _, domain := range taken[:opts.listed(len(taken))] {
+			fmt.Fprintf(w, "  %s\n", domain)
+		}
+		opts.printMore(w, len(taken))
+		fmt.Fprintln(w)
+	}
+
+	if len(reserved) > 0 && opts.showSection(StatusReserved) {
+		fmt.Fprintf(w, "%s RESERVED / BLOCKED BY THE REGISTRY (%d):\n", sym.Blocked, len(reserved))
+		for _, domain := range reserved[:opts.listed(len(reserved))] {
+			fmt.Fprintf(w, "  %s\n", domain)
+		}
+		opts.printMore(w, len(reserved))
+		fmt.Fprintln(w)
+	}
+
+	if len(errors) > 0 && opts.showSection(StatusError) {
+		fmt.Fprintf(w, "%s ERRORS (%d):\n", sym.Warning, len(errors))
+		if opts.ErrorsFull {
+			for _, result := range errors[:opts.listed(len(errors))] {
+				fmt.Fprintf(w, "  %s: %v\n", result.Domain, result.Error)
+			}
+			opts.printMore(w, len(errors))
+		} else {
+			groups := groupErrors(errors)
+			for _, group := range groups[:opts.listed(len(groups))] {
+				fmt.Fprintf(w, "  %s (%d): %s\n", group.Category, len(group.Domains), group.Message)
+				examples := group.Domains[:min(len(group.Domains), errorExamples)]
+				more := ""
+				if n := len(group.Domains) - len(examples); n > 0 {
+					more = fmt.Sprintf(" and %s more", formatCount(n))
+				}
+				fmt.Fprintf(w, "    %s%s\n", strings.Join(examples, ", "), more)
+			}
+			opts.printMore(w, len(groups))
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(unknown) > 0 && opts.showSection(StatusUnknown) {
+		fmt.Fprintf(w, "? UNKNOWN, REPLY NOT RECOGNISED (%d):\n", len(unknown))
+		for _, domain := range unknown[:opts.listed(len(unknown))] {
+			fmt.Fprintf(w, "  %s\n", domain)
+		}
+		opts.printMore(w, len(unknown))
+		fmt.Fprintln(w)
+	}
+
+	if len(deferred) > 0 && opts.showSection(StatusDeferred) {
+		fmt.Fprintf(w, "%s DEFERRED, THE REGISTRY COULD NOT ANSWER (%d):\n", sym.Warning, len(deferred))
+		for _, domain := range deferred[:opts.listed(len(deferred))] {
+			fmt.Fprintf(w, "  %s\n", domain)
+		}
+		opts.printMore(w, len(deferred))
+		fmt.Fprintln(w)
+	}
+
+	if len(unchecked) > 0 && opts.showSection(StatusUnchecked) {
+		fmt.Fprintf(w, "? UNCHECKED (%d):\n", len(unchecked))
+		for _, domain := range unchecked[:opts.listed(len(unchecked))] {
+			fmt.Fprintf(w, "  %s\n", domain)
+		}
+		opts.printMore(w, len(unchecked))
+		fmt.Fprintln(w)
+	}
+
+	for _, section := range []struct {
+		title   string
+		status  Status
+		domains []string
+	}{
+		{"SUBDOMAINS THAT EXIST IN DNS", StatusExists, exists},
+		{"SUBDOMAINS THAT DO NOT EXIST IN DNS", StatusAbsent, absent},
+	} {
+		if len(section.domains) > 0 && opts.showSection(section.status) {
+			fmt.Fprintf(w, "%s (%d):\n", section.title, len(section.domains))
+			for _, domain := range section.domains[:opts.listed(len(section.domains))] {
+				fmt.Fprintf(w, "  %s\n", domain)
+			}
+			opts.printMore(w, len(section.domains))
+			fmt.Fprintln(w)
+		}
+	}
+
+	suggestedAvailable := rankedAvailable(suggested, opts)
+	if len(suggestedAvailable) > 0 && opts.showSection(StatusAvailable) {
+		fmt.Fprintf(w, "%s SUGGESTIONS AVAILABLE (%d):\n", sym.Idea, len(suggestedAvailable))
+		for _, result := range suggestedAvailable[:opts.listed(len(suggestedAvailable))] {
+			fmt.Fprintf(w, "  %s\n", availableLine(result, opts))
+			printLinks(w, result.Links)
+		}
+		opts.printMore(w, len(suggestedAvailable))
+		fmt.Fprintln(w)
+	}
+
+	if opts.NoSummary {
+		return
+	}
+	fmt.Fprintf(w, "Summary: %s\n", summarize(results))
+	if len(suggested) > 0 {
+		fmt.Fprintf(w, "Suggestions: %d of %d available\n", len(suggestedAvailable), len(suggested))
+	}
+	if tlds := summarizeTLDs(results); len(tlds) > 1 {
+		fmt.Fprintf(w, "Available by TLD: %s\n", tldLine(tlds))
+	}
+	if prices := tldPrices(results); prices != "" {
+		fmt.Fprintf(w, "Prices: %s\n", prices)
+	}
+	if cached, skipped := cacheCounts(results); cached > 0 || skipped > 0 {
+		fmt.Fprintf(w, "Cache: %d answered from cache%s, %d skipped\n", cached, cachedBreakdown(results), skipped)
+	}
+}
+
+// cachedBreakdown splits the cached answers by verdict, e.g.
+// " (10 taken, 2 available)".
+func cachedBreakdown(results []DomainResult) string {
+	s := summarize(results)
+	if s.Cached == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%d taken, %d available)", s.CachedTaken, s.CachedAvailable)
+}
+
+// cacheCounts counts results answered from the cache and those skipped
+// offline because the cache had no answer.
+func cacheCounts(results []DomainResult) (cached, skipped int) {
+	for _, result := range results {
+		switch {
+		case result.Cached:
+			cached++
+		case result.Status == StatusUnchecked && result.Error == nil:
+			skipped++
+		}
+	}
+	return cached, skipped
+}
+
+// uncheckedNote says why a domain was not checked: offline without a cached
+// answer unless the result carries its own reason.
+func uncheckedNote(result DomainResult) string {
+	if result.Error != nil {
+		return fmt.Sprintf(" (%v)", result.Error)
+	}
+	return " (not in cache)"
+}
+
+func writeCSV(w io.Writer, results []DomainResult) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"domain", "status", "epp_status", "checked_at", "error", "run_id"})
+	for _, result := range results {
+		errText := ""
+		if result.Error != nil {
+			errText = result.Error.Error()
+		}
+		checkedAt := ""
+		if !result.CheckedAt.IsZero() {
+			checkedAt = result.CheckedAt.UTC().Format(time.RFC3339)
+		}
+		cw.Write([]string{
+			result.Domain,
+			string(result.Status),
+			strings.Join(result.EPPStatus, " "),
+			checkedAt,
+			errText,
+			result.RunID,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func hasExcerpts(results []DomainResult) bool {
+	for _, result := range results {
+		if result.RawExcerpt != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// writeExcerpts prints the start of the reply behind every error and
+// unknown verdict that had one, for -verbose.
+func writeExcerpts(w io.Writer, results []DomainResult) {
+	fmt.Fprintln(w, "REPLY EXCERPTS:")
+	for _, result := range results {
+		if result.RawExcerpt == "" {
+			continue
+		}
+		fmt.Fprintf(w, "  %s: %s%s\n", result.Domain, result.Status, serverNote(result, RenderOptions{Servers: true}))
+		for _, line := range strings.Split(result.RawExcerpt, "\n") {
+			fmt.Fprintf(w, "    | %s\n", line)
+		}
+	}
+}
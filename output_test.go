@@ -0,0 +1,41 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSummaryLine pins the result line scripts parse: changing it breaks
+// them, so a new key has to be added here on purpose.
+func TestSummaryLine(t *testing.T) {
+	startedAt := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	report := &Report{
+		RunInfo: RunInfo{ID: "3f9a1c2e", StartedAt: startedAt, FinishedAt: startedAt.Add(94250 * time.Millisecond)},
+		Summary: Summary{Available: 12, Taken: 140, Errors: 5, Unknown: 3, Cached: 4, CachedTaken: 4, Total: 160},
+	}
+	want := "result available=12 taken=140 errors=5 reserved=0 unknown=3 deferred=0 unchecked=0 exists=0 absent=0" +
+		" cached=4 cachedAvailable=0 cachedTaken=4 total=160 duration=94.2 runId=3f9a1c2e"
+	if got := summaryLine(report); got != want {
+		t.Errorf("summaryLine =\n%s\nwant\n%s", got, want)
+	}
+
+	report.Quota = &QuotaUsage{Limit: 200, Used: 150, Remaining: 50}
+	if got := summaryLine(report); got != want+" quotaUsed=150 quotaRemaining=50" {
+		t.Errorf("summaryLine with -quota = %s", got)
+	}
+}
+
+// TestSummaryLineCoversSummary fails when a Summary counter is added without
+// a key in the result line.
+func TestSummaryLineCoversSummary(t *testing.T) {
+	line := summaryLine(&Report{})
+	summary := reflect.TypeOf(Summary{})
+	for i := 0; i < summary.NumField(); i++ {
+		name, _, _ := strings.Cut(summary.Field(i).Tag.Get("json"), ",")
+		if !strings.Contains(line, " "+name+"=") {
+			t.Errorf("result line lacks %s: %s", name, line)
+		}
+	}
+}
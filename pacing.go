@@ -0,0 +1,238 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Pacing controls how fast domains are checked. It is the single place that
+// knows the pacing parameters; the worker pool and the run estimate both
+// read it.
+type Pacing struct {
+	Workers int
+	// Rate is the maximum number of queries per second across all workers;
+	// zero means no limit.
+	Rate float64
+	// Delay is how long each worker waits between its queries.
+	Delay time.Duration
+	// StallTimeout is how long the pool waits without any result before it
+	// abandons the checks still running; zero means forever.
+	StallTimeout time.Duration
+}
+
+type PacingFlags struct {
+	Workers *int
+	Rate    *float64
+	Delay   *time.Duration
+	// MaxConnections is not part of Pacing: it bounds the sockets of every
+	// stage, not the domains in flight.
+	MaxConnections *int
+	StallTimeout   *time.Duration
+}
+
+func addPacingFlags(fs *flag.FlagSet) *PacingFlags {
+	return &PacingFlags{
+		Workers:        fs.Int("workers", 10, "Number of concurrent workers"),
+		Rate:           fs.Float64("rate", 0, "Maximum queries per second across all workers (0 for no limit)"),
+		Delay:          fs.Duration("delay", 0, "Pause of each worker between queries (e.g. 500ms)"),
+		StallTimeout:   fs.Duration("stall-timeout", 5*time.Minute, "Give up on checks still running when no result has arrived for this long, and report them as stalled (0 to wait forever)"),
+		MaxConnections: fs.Int("max-connections", 0, "Maximum sockets open at once across whois and HTTP probes; a quarter is kept for whois (0 for no limit)"),
+	}
+}
+
+func (f *PacingFlags) Pacing() (Pacing, error) {
+	p := Pacing{Workers: *f.Workers, Rate: *f.Rate, Delay: *f.Delay, StallTimeout: *f.StallTimeout}
+	if p.Workers < 1 {
+		return p, fmt.Errorf("-workers must be at least 1")
+	}
+	if p.Rate < 0 || p.Delay < 0 || p.StallTimeout < 0 {
+		return p, fmt.Errorf("-rate, -delay and -stall-timeout cannot be negative")
+	}
+	if *f.MaxConnections < 0 {
+		return p, fmt.Errorf("-max-connections cannot be negative")
+	}
+	connections = newConnBudget(*f.MaxConnections)
+	return p, nil
+}
+
+// stallTimeout is StallTimeout stretched by the pacing, so a slow -rate or
+// -delay alone never looks like a stall.
+func (p Pacing) stallTimeout() time.Duration {
+	if p.StallTimeout <= 0 {
+		return 0
+	}
+	return p.StallTimeout + p.interval() + p.Delay
+}
+
+// interval is the minimum time between two queries imposed by Rate.
+func (p Pacing) interval() time.Duration {
+	if p.Rate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / p.Rate)
+}
+
+// Throttle applies Rate and Delay to check. Wrap only the calls that reach
+// the network, so cached answers are not slowed down.
+func (p Pacing) Throttle(check checkFunc) checkFunc {
+	interval := p.interval()
+	if interval == 0 && p.Delay == 0 {
+		return check
+	}
+
+	var mu sync.Mutex
+	var next time.Time
+	return func(domain string) DomainResult {
+		if interval > 0 {
+			mu.Lock()
+			now := time.Now()
+			slot := next
+			if slot.Before(now) {
+				slot = now
+			}
+			next = slot.Add(interval)
+			mu.Unlock()
+			time.Sleep(time.Until(slot))
+		}
+
+		result := check(domain)
+		time.Sleep(p.Delay)
+		return result
+	}
+}
+
+// assumedLatency is the expected duration of a single query before any has
+// been observed.
+const assumedLatency = time.Second
+
+// Estimate is how long checking n domains should take when each query takes
+// latency.
+func (p Pacing) Estimate(n int, latency time.Duration) time.Duration {
+	if n <= 0 {
+		return 0
+	}
+	// Each worker handles one query per latency+delay; the rate limit caps
+	// the total throughput.
+	perSecond := float64(p.Workers) / (latency + p.Delay).Seconds()
+	if p.Rate > 0 && p.Rate < perSecond {
+		perSecond = p.Rate
+	}
+	return time.Duration(float64(n) / perSecond * float64(time.Second))
+}
+
+func (p Pacing) String() string {
+	parts := []string{fmt.Sprintf("%d workers", p.Workers)}
+	if p.Rate > 0 {
+		parts = append(parts, fmt.Sprintf("at most %g/s", p.Rate))
+	}
+	if p.Delay > 0 {
+		parts = append(parts, fmt.Sprintf("%s delay", p.Delay))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatETA rounds an estimate to a readable precision.
+func formatETA(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return "under a second"
+	case d < time.Minute:
+		return d.Round(time.Second).String()
+	default:
+		return d.Round(time.Minute).String()
+	}
+}
+
+// collectWithProgress gathers results from a check stream and keeps a single
+// progress line with a refined estimate up to date on w, based on the latency
+// observed so far.
+func collectWithProgress(w io.Writer, stream <-chan DomainResult, total int, pacing Pacing) []DomainResult {
+	var results []DomainResult
+	var latency time.Duration
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	print := func() {
+		avg := assumedLatency
+		if len(results) > 0 && latency > 0 {
+			avg = latency / time.Duration(len(results))
+		}
+		eta := pacing.Estimate(total-len(results), avg)
+		fmt.Fprintf(w, "\r\033[KChecked %d/%d, about %s left", len(results), total, formatETA(eta))
+	}
+
+	for {
+		select {
+		case result, ok := <-stream:
+			if !ok {
+				fmt.Fprint(w, "\r\033[K")
+				return results
+			}
+			results = append(results, result)
+			latency += result.Duration
+		case <-ticker.C:
+			print()
+		}
+	}
+}
+
+// keyedMutex hands out one lock per key. Entries are dropped once nobody
+// holds or waits for them, so the map stays as small as the work in flight.
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+type keyedLock struct {
+	sync.Mutex
+	refs int
+}
+
+// Lock blocks until key is free and returns the function that frees it.
+func (k *keyedMutex) Lock(key string) (unlock func()) {
+	k.mu.Lock()
+	if k.locks == nil {
+		k.locks = make(map[string]*keyedLock)
+	}
+	l := k.locks[key]
+	if l == nil {
+		l = &keyedLock{}
+		k.locks[key] = l
+	}
+	l.refs++
+	k.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		k.mu.Lock()
+		if l.refs--; l.refs == 0 {
+			delete(k.locks, key)
+		}
+		k.mu.Unlock()
+	}
+}
+
+// withDomainLock runs the checks of one registrable domain one after the
+// other, so its whois, referral and DNS queries never overlap. This matters
+// when a domain is listed twice, and in the second pass while an abandoned
+// check of the same domain is still waiting for its server. Different
+// domains still run in parallel.
+func withDomainLock(check checkFunc) checkFunc {
+	var locks keyedMutex
+	return func(domain string) DomainResult {
+		key := domain
+		if registrable, err := publicsuffix.EffectiveTLDPlusOne(domain); err == nil {
+			key = registrable
+		}
+		unlock := locks.Lock(key)
+		defer unlock()
+		return check(domain)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// TestDomainLockNoOverlap runs many checks of the names of two registrable
+// domains at once and fails if two of the same domain are ever in flight
+// together, or if the two domains never run in parallel.
+func TestDomainLockNoOverlap(t *testing.T) {
+	var mu sync.Mutex
+	inFlight := map[string]int{}
+	maxTotal, total := 0, 0
+	check := withDomainLock(func(domain string) DomainResult {
+		key, _ := publicsuffix.EffectiveTLDPlusOne(domain)
+		mu.Lock()
+		inFlight[key]++
+		total++
+		if inFlight[key] > 1 {
+			t.Errorf("%d queries for %s in flight at once", inFlight[key], key)
+		}
+		maxTotal = max(maxTotal, total)
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		inFlight[key]--
+		total--
+		mu.Unlock()
+		return DomainResult{Domain: domain, Status: StatusAvailable}
+	})
+
+	domains := []string{"example.com", "www.example.com", "example.com", "example.co.uk", "shop.example.co.uk"}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		for _, domain := range domains {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				check(domain)
+			}()
+		}
+	}
+	wg.Wait()
+
+	if maxTotal < 2 {
+		t.Errorf("example.com and example.co.uk never ran in parallel")
+	}
+}
+
+func TestKeyedMutexForgetsFreeKeys(t *testing.T) {
+	var k keyedMutex
+	unlock := k.Lock("example.com")
+	done := make(chan struct{})
+	go func() {
+		k.Lock("example.com")()
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("second Lock of a held key did not wait")
+	case <-time.After(10 * time.Millisecond):
+	}
+	unlock()
+	<-done
+	if len(k.locks) != 0 {
+		t.Errorf("locks left after every holder unlocked: %v", k.locks)
+	}
+}
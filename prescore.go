@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// PrescoreWeights weigh the features -prescore predicts availability from;
+// each feature is normalized to 0..1, higher meaning more likely available.
+type PrescoreWeights struct {
+	Length float64
+	Hyphen float64
+	TLD    float64
+}
+
+var defaultPrescoreWeights = PrescoreWeights{
+	Length: 2,
+	Hyphen: 1,
+	TLD:    2,
+}
+
+// parsePrescoreWeights overrides the defaults with "feature=weight" pairs,
+// as parseRankWeights does for -rank.
+func parsePrescoreWeights(input string) (PrescoreWeights, error) {
+	weights := defaultPrescoreWeights
+	fields := map[string]*float64{
+		"length": &weights.Length,
+		"hyphen": &weights.Hyphen,
+		"tld":    &weights.TLD,
+	}
+	for _, pair := range parseKeywords(input) {
+		name, value, ok := strings.Cut(pair, "=")
+		field, known := fields[strings.TrimSpace(name)]
+		if !ok || !known {
+			return weights, fmt.Errorf("invalid prescore weight %q (use feature=weight with features length, hyphen, tld)", pair)
+		}
+		w, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || w < 0 {
+			return weights, fmt.Errorf("invalid prescore weight %q", pair)
+		}
+		*field = w
+	}
+	return weights, nil
+}
+
+// minTLDChecks is how many verdicts the history needs for a TLD before its
+// own availability rate replaces defaultTLDRate.
+const minTLDChecks = 20
+
+// defaultTLDRate is the availability assumed for a TLD without enough
+// history: .com is nearly exhausted, newer TLDs much less so.
+func defaultTLDRate(tld string) float64 {
+	if tld == "com" {
+		return 0.1
+	}
+	return 0.5
+}
+
+// Prescorer predicts how likely a domain is to be available, to check the
+// likeliest first. It is a transparent weighted average, not a model.
+type Prescorer struct {
+	Weights PrescoreWeights
+	// TLDRates are the availability rates learned from the history, by TLD.
+	TLDRates map[string]float64
+}
+
+// Predict returns the predicted chance, 0 to 1, that domain is available.
+func (p *Prescorer) Predict(domain string) float64 {
+	label, _, _ := strings.Cut(domain, ".")
+	tld := domainTLD(domain)
+	rate, ok := p.TLDRates[tld]
+	if !ok {
+		rate = defaultTLDRate(tld)
+	}
+	w := p.Weights
+
+	// Short names are taken almost without exception; from 16 characters
+	// on, length stops helping.
+	factors := []struct{ weight, value float64 }{
+		{w.Length, clamp(float64(len(strings.ReplaceAll(label, "-", ""))-4) / 12)},
+		{w.Hyphen, boolFactor(strings.Contains(label, "-"))},
+		{w.TLD, rate},
+	}
+	var total, weightSum float64
+	for _, f := range factors {
+		total += f.weight * f.value
+		weightSum += f.weight
+	}
+	if weightSum == 0 {
+		return 0
+	}
+	return total / weightSum
+}
+
+// tldAvailability returns the share of available verdicts among the
+// available and taken checks in the history, for TLDs with at least
+// minTLDChecks of them.
+func (h *HistoryStore) tldAvailability() (map[string]float64, error) {
+	rows, err := h.db.Query(`SELECT domain, status FROM checks WHERE status IN (?, ?)`, StatusAvailable, StatusTaken)
+	if err != nil {
+		return nil, fmt.Errorf("reading history: %w", err)
+	}
+	defer rows.Close()
+
+	checked := map[string]int{}
+	available := map[string]int{}
+	for rows.Next() {
+		var domain, status string
+		if err := rows.Scan(&domain, &status); err != nil {
+			return nil, fmt.Errorf("reading history: %w", err)
+		}
+		tld := domainTLD(domain)
+		checked[tld]++
+		if Status(status) == StatusAvailable {
+			available[tld]++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading history: %w", err)
+	}
+
+	rates := map[string]float64{}
+	for tld, n := range checked {
+		if n >= minTLDChecks {
+			rates[tld] = float64(available[tld]) / float64(n)
+		}
+	}
+	return rates, nil
+}
+
+// prescoreBand groups results by predicted availability, to compare the
+// prediction with what the run found.
+type prescoreBand struct {
+	name      string
+	min       float64
+	checked   int
+	available int
+	predicted float64
+}
+
+// writePrescoreReport prints, per band of prediction, how many of the
+// domains with a verdict were predicted and found available.
+func writePrescoreReport(w io.Writer, results []DomainResult, p *Prescorer) {
+	bands := []*prescoreBand{
+		{name: "likely", min: 0.6},
+		{name: "maybe", min: 0.3},
+		{name: "unlikely", min: 0},
+	}
+	total := 0
+	for _, result := range results {
+		if result.Suggested || (result.Status != StatusAvailable && result.Status != StatusTaken) {
+			continue
+		}
+		predicted := p.Predict(result.Domain)
+		for _, band := range bands {
+			if predicted >= band.min {
+				band.checked++
+				band.predicted += predicted
+				if result.Status == StatusAvailable {
+					band.available++
+				}
+				total++
+				break
+			}
+		}
+	}
+
+	if total == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "Pre-score: predicted vs actual availability")
+	for _, band := range bands {
+		if band.checked == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "  %-8s (from %2.0f%%): %d checked, predicted %.0f%%, actual %.0f%% (%d available)\n",
+			band.name, band.min*100, band.checked,
+			band.predicted*100/float64(band.checked),
+			float64(band.available)*100/float64(band.checked), band.available)
+	}
+}
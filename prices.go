@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Price is what a registrar charges per year for a TLD.
+type Price struct {
+	Registration float64 `json:"registration"`
+	Renewal      float64 `json:"renewal"`
+	Currency     string  `json:"currency"`
+}
+
+func (p Price) String() string {
+	if p.Renewal != p.Registration {
+		return fmt.Sprintf("$%.2f/yr, renews $%.2f", p.Registration, p.Renewal)
+	}
+	return fmt.Sprintf("$%.2f/yr", p.Registration)
+}
+
+// Max is the higher of the registration and renewal price.
+func (p Price) Max() float64 {
+	return max(p.Registration, p.Renewal)
+}
+
+// priceCacheTTL is how long fetched prices are reused before asking again.
+const priceCacheTTL = 7 * 24 * time.Hour
+
+type priceList struct {
+	FetchedAt time.Time        `json:"fetchedAt"`
+	Prices    map[string]Price `json:"prices"`
+}
+
+var priceSources = map[string]func(context.Context) (map[string]Price, error){
+	"porkbun": fetchPorkbunPrices,
+}
+
+// loadPrices returns per-TLD prices from source, using a copy cached in dir
+// when it is less than a week old. Offline, any cached copy is used and
+// nothing is fetched.
+func loadPrices(ctx context.Context, source, dir string, offline bool) (map[string]Price, error) {
+	fetch, ok := priceSources[source]
+	if !ok {
+		return nil, fmt.Errorf("unknown -prices source %q (use porkbun)", source)
+	}
+
+	path := ""
+	if dir != "" {
+		path = filepath.Join(dir, "prices-"+source+".json")
+		if data, err := os.ReadFile(path); err == nil {
+			var cached priceList
+			if json.Unmarshal(data, &cached) == nil && (offline || time.Since(cached.FetchedAt) < priceCacheTTL) {
+				return cached.Prices, nil
+			}
+		}
+	}
+	if offline {
+		return nil, fmt.Errorf("no cached %s prices", source)
+	}
+
+	prices, err := fetch(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s prices: %w", source, err)
+	}
+
+	if path != "" {
+		data, err := json.Marshal(priceList{FetchedAt: time.Now(), Prices: prices})
+		if err == nil && os.MkdirAll(dir, 0o755) == nil {
+			writeFileAtomic(path, data)
+		}
+	}
+	return prices, nil
+}
+
+const porkbunPricingURL = "https://api.porkbun.com/api/json/v3/pricing/get"
+
+func fetchPorkbunPrices(ctx context.Context) (map[string]Price, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, porkbunPricingURL, strings.NewReader("{}"))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := notifyClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var body struct {
+		Status  string `json:"status"`
+		Pricing map[string]struct {
+			Registration string `json:"registration"`
+			Renewal      string `json:"renewal"`
+		} `json:"pricing"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	if body.Status != "SUCCESS" {
+		return nil, fmt.Errorf("status %q", body.Status)
+	}
+
+	prices := make(map[string]Price, len(body.Pricing))
+	for tld, p := range body.Pricing {
+		registration, err1 := strconv.ParseFloat(p.Registration, 64)
+		renewal, err2 := strconv.ParseFloat(p.Renewal, 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		prices[strings.ToLower(tld)] = Price{Registration: registration, Renewal: renewal, Currency: "USD"}
+	}
+	return prices, nil
+}
+
+// filterByPrice drops domains whose TLD costs more than maxPrice to register
+// or renew. TLDs without a known price are kept.
+func filterByPrice(domains []Candidate, prices map[string]Price, maxPrice float64) (kept []Candidate, dropped map[string]Price) {
+	dropped = map[string]Price{}
+	for _, domain := range domains {
+		if price, ok := prices[domain.TLD]; ok && price.Max() > maxPrice {
+			dropped[domain.TLD] = price
+			continue
+		}
+		kept = append(kept, domain)
+	}
+	return kept, dropped
+}
+
+// tldPrices lists the prices of the TLDs of the available results.
+func tldPrices(results []DomainResult) string {
+	seen := map[string]Price{}
+	for _, result := range results {
+		if result.Status == StatusAvailable && result.Price != nil {
+			seen[domainTLD(result.Domain)] = *result.Price
+		}
+	}
+	if len(seen) == 0 {
+		return ""
+	}
+
+	tlds := make([]string, 0, len(seen))
+	for tld := range seen {
+		tlds = append(tlds, tld)
+	}
+	sort.Strings(tlds)
+
+	parts := make([]string, len(tlds))
+	for i, tld := range tlds {
+		parts[i] = fmt.Sprintf(".%s %s", tld, seen[tld])
+	}
+	return strings.Join(parts, "; ")
+}
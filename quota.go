@@ -0,0 +1,63 @@
+package main
+
+import "sync"
+
+// whoisQuota counts whois queries and refuses new ones once limit queries
+// have been sent, including those counted by earlier runs. A zero limit
+// only counts.
+type whoisQuota struct {
+	limit  int
+	before int
+
+	mu      sync.Mutex
+	used    int
+	refused bool
+}
+
+func newWhoisQuota(limit, before int) *whoisQuota {
+	return &whoisQuota{limit: limit, before: before}
+}
+
+// take reserves one query, or reports false when the quota is spent.
+func (q *whoisQuota) take() bool {
+	if q == nil {
+		return true
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.limit > 0 && q.before+q.used >= q.limit {
+		q.refused = true
+		return false
+	}
+	q.used++
+	return true
+}
+
+// queries is the number of queries sent by this run.
+func (q *whoisQuota) queries() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.used
+}
+
+// exhausted reports whether a query was refused.
+func (q *whoisQuota) exhausted() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.refused
+}
+
+func (q *whoisQuota) usage() *QuotaUsage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	used := q.before + q.used
+	return &QuotaUsage{Limit: q.limit, Used: used, Remaining: max(q.limit-used, 0)}
+}
+
+// QuotaUsage is the state of -quota at the end of a run, earlier runs in
+// -quota-window included.
+type QuotaUsage struct {
+	Limit     int `json:"limit"`
+	Used      int `json:"used"`
+	Remaining int `json:"remaining"`
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const rdapBootstrapURL = "https://data.iana.org/rdap/dns.json"
+
+// rdapClient makes the RDAP requests. runRDAP puts the HTTP cache in front
+// of its transport unless -no-http-cache is given.
+var rdapClient = &http.Client{Timeout: 10 * time.Second, Transport: budgetTransport{}}
+
+// rdapBootstrap is the IANA registry of RDAP servers (RFC 9224). Each service
+// pairs a list of TLDs with the base URLs of the servers for them.
+type rdapBootstrap struct {
+	Version     string       `json:"version"`
+	Publication string       `json:"publication"`
+	Services    [][][]string `json:"services"`
+}
+
+// Endpoints returns the RDAP base URLs for tld, HTTPS ones first, each
+// ending with a slash.
+func (b *rdapBootstrap) Endpoints(tld string) []string {
+	tld = strings.ToLower(strings.TrimPrefix(tld, "."))
+	var urls []string
+	for _, service := range b.Services {
+		if len(service) < 2 {
+			continue
+		}
+		for _, entry := range service[0] {
+			if strings.EqualFold(entry, tld) {
+				for _, url := range service[1] {
+					if !strings.HasSuffix(url, "/") {
+						url += "/"
+					}
+					urls = append(urls, url)
+				}
+				break
+			}
+		}
+	}
+	sort.SliceStable(urls, func(i, j int) bool {
+		return strings.HasPrefix(urls[i], "https://") && !strings.HasPrefix(urls[j], "https://")
+	})
+	return urls
+}
+
+// rdapBootstrapFile is the saved copy of the bootstrap registry, used when
+// the registry cannot be fetched.
+type rdapBootstrapFile struct {
+	FetchedAt time.Time     `json:"fetchedAt"`
+	Bootstrap rdapBootstrap `json:"bootstrap"`
+}
+
+func rdapBootstrapPath(dir string) string {
+	return filepath.Join(dir, "rdap-dns.json")
+}
+
+func readRDAPBootstrap(path string) (*rdapBootstrapFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file rdapBootstrapFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("reading RDAP bootstrap %s: %w", path, err)
+	}
+	return &file, nil
+}
+
+// updateRDAPBootstrap fetches the bootstrap registry from url and saves it
+// at path. Freshness and revalidation are left to the HTTP cache in front of
+// rdapClient, so an unchanged registry costs at most a 304. It reports
+// whether the content changed.
+func updateRDAPBootstrap(ctx context.Context, path, url string) (*rdapBootstrapFile, bool, error) {
+	cached, _ := readRDAPBootstrap(path)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := rdapClient.Do(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("fetching RDAP bootstrap: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return nil, false, fmt.Errorf("fetching RDAP bootstrap: unexpected status %s", resp.Status)
+	}
+
+	file := &rdapBootstrapFile{FetchedAt: time.Now()}
+	if err := json.NewDecoder(resp.Body).Decode(&file.Bootstrap); err != nil {
+		return nil, false, fmt.Errorf("decoding RDAP bootstrap: %w", err)
+	}
+	if len(file.Bootstrap.Services) == 0 {
+		return nil, false, fmt.Errorf("RDAP bootstrap from %s lists no services", url)
+	}
+	changed := cached == nil || cached.Bootstrap.Publication != file.Bootstrap.Publication
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, false, fmt.Errorf("writing RDAP bootstrap: %w", err)
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return nil, false, fmt.Errorf("writing RDAP bootstrap: %w", err)
+	}
+	return file, changed, nil
+}
+
+// loadRDAPBootstrap returns the bootstrap registry, fetched through the HTTP
+// cache. The saved copy is used offline and when the fetch fails; only a
+// missing copy that cannot be fetched is an error.
+func loadRDAPBootstrap(ctx context.Context, path string, offline bool) (*rdapBootstrap, error) {
+	cached, err := readRDAPBootstrap(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if cached != nil && offline {
+		return &cached.Bootstrap, nil
+	}
+	if offline {
+		return nil, fmt.Errorf("no cached RDAP bootstrap at %s (run 'domain-checker rdap update')", path)
+	}
+
+	file, _, err := updateRDAPBootstrap(ctx, path, rdapBootstrapURL)
+	if err != nil {
+		if cached == nil {
+			return nil, err
+		}
+		fmt.Fprintf(os.Stderr, "Warning: using the RDAP bootstrap saved %d days ago, which could not be refreshed: %v\n",
+			int(time.Since(cached.FetchedAt).Hours()/24), err)
+		return &cached.Bootstrap, nil
+	}
+	return &file.Bootstrap, nil
+}
+
+func runRDAP(args []string) int {
+	usage := func() {
+		fmt.Fprintf(os.Stderr, "Domain Checker - Manage the RDAP bootstrap registry\n\n")
+		fmt.Fprintf(os.Stderr, "Usage:\n")
+		fmt.Fprintf(os.Stderr, "  %s rdap update [-url=%s] [-no-http-cache] [-debug]\n", os.Args[0], rdapBootstrapURL)
+		fmt.Fprintf(os.Stderr, "  %s rdap show [-no-http-cache] [-debug] <tld>\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "The registry is cached at %s\n", rdapBootstrapPath(cacheDir()))
+		fmt.Fprintf(os.Stderr, "and RDAP responses, as their Cache-Control allows, under %s\n", httpCacheDir())
+	}
+
+	if len(args) == 0 {
+		usage()
+		return 1
+	}
+
+	fs := flag.NewFlagSet("rdap "+args[0], flag.ExitOnError)
+	noHTTPCache := fs.Bool("no-http-cache", false, "Send every RDAP request to the server instead of answering from the HTTP cache when the response allows it")
+	debugFlag := fs.Bool("debug", false, "Log diagnostics such as HTTP cache hits to stderr")
+	var url *string
+	switch args[0] {
+	case "update":
+		url = fs.String("url", rdapBootstrapURL, "Where to fetch the bootstrap registry from")
+	case "show":
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown rdap command %q\n\n", args[0])
+		usage()
+		return 1
+	}
+	positional := parseInterspersed(fs, args[1:])
+	if *debugFlag {
+		debugLog.SetOutput(os.Stderr)
+	}
+	if !*noHTTPCache && httpCacheDir() != "" {
+		cache := newHTTPCache(httpCacheDir(), rdapClient.Transport)
+		rdapClient.Transport = cache
+		defer func() { debugLog.Print(cache.Summary()) }()
+	}
+
+	path := rdapBootstrapPath(cacheDir())
+	if args[0] == "update" {
+		if len(positional) > 0 {
+			usage()
+			return 1
+		}
+		file, changed, err := updateRDAPBootstrap(context.Background(), path, *url)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		state := "unchanged"
+		if changed {
+			state = "updated"
+		}
+		fmt.Printf("RDAP bootstrap %s: %d services, published %s (%s)\n",
+			state, len(file.Bootstrap.Services), file.Bootstrap.Publication, path)
+		return 0
+	}
+
+	if len(positional) != 1 {
+		usage()
+		return 1
+	}
+	bootstrap, err := loadRDAPBootstrap(context.Background(), path, false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	endpoints := bootstrap.Endpoints(positional[0])
+	if len(endpoints) == 0 {
+		fmt.Fprintf(os.Stderr, "No RDAP server known for .%s\n", strings.TrimPrefix(positional[0], "."))
+		return 1
+	}
+	for _, endpoint := range endpoints {
+		fmt.Println(endpoint)
+	}
+	return 0
+}
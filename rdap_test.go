@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testBootstrap mimics the IANA registry: several TLDs per service, TLDs
+// with more than one server, and a TLD listed in two services.
+const testBootstrap = `{
+  "version": "1.0",
+  "publication": "2026-01-01T00:00:00Z",
+  "services": [
+    [["com", "net"], ["https://rdap.verisign.com/com/v1/"]],
+    [["io", "ac"], ["http://rdap.nic.io/", "https://rdap.nic.io"]],
+    [["de"], ["https://rdap.denic.de/", "https://rdap2.denic.de/"]],
+    [["example"], ["http://rdap.example/"]],
+    [["example", "test"], ["https://rdap.example.net/"]],
+    [["broken"]]
+  ]
+}`
+
+func TestRDAPEndpoints(t *testing.T) {
+	var bootstrap rdapBootstrap
+	if err := json.Unmarshal([]byte(testBootstrap), &bootstrap); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		tld  string
+		want []string
+	}{
+		{"com", []string{"https://rdap.verisign.com/com/v1/"}},
+		{"NET", []string{"https://rdap.verisign.com/com/v1/"}},
+		{".ac", []string{"https://rdap.nic.io/", "http://rdap.nic.io/"}},
+		{"de", []string{"https://rdap.denic.de/", "https://rdap2.denic.de/"}},
+		{"example", []string{"https://rdap.example.net/", "http://rdap.example/"}},
+		{"broken", nil},
+		{"org", nil},
+	} {
+		if got := bootstrap.Endpoints(tt.tld); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Endpoints(%q) = %v, want %v", tt.tld, got, tt.want)
+		}
+	}
+}
+
+// TestUpdateRDAPBootstrapConditional checks that a refresh through the
+// HTTP cache sends the validators of the stored response and keeps the
+// registry on a 304.
+func TestUpdateRDAPBootstrapConditional(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(testBootstrap))
+	}))
+	defer server.Close()
+
+	transport := rdapClient.Transport
+	rdapClient.Transport = newHTTPCache(t.TempDir(), http.DefaultTransport)
+	defer func() { rdapClient.Transport = transport }()
+
+	path := filepath.Join(t.TempDir(), "rdap-dns.json")
+	file, changed, err := updateRDAPBootstrap(context.Background(), path, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed || len(file.Bootstrap.Services) != 6 {
+		t.Errorf("first update: changed = %v, file = %+v", changed, file)
+	}
+
+	file, changed, err = updateRDAPBootstrap(context.Background(), path, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed || len(file.Bootstrap.Services) != 6 {
+		t.Errorf("second update: changed = %v, %d services", changed, len(file.Bootstrap.Services))
+	}
+	if requests != 2 {
+		t.Errorf("%d requests, want 2", requests)
+	}
+
+	bootstrap, err := loadRDAPBootstrap(context.Background(), path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := bootstrap.Endpoints("io"); len(got) != 2 {
+		t.Errorf("Endpoints from the saved copy = %v", got)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// registrarExporter writes available domains in the bulk-import layout of
+// a registrar.
+type registrarExporter func(w io.Writer, results []DomainResult) error
+
+var registrarExporters = map[string]registrarExporter{
+	"namecheap":   writeNamecheapExport,
+	"porkbun":     writePorkbunExport,
+	"generic-csv": writeGenericCSVExport,
+}
+
+func registrarExporterNames() string {
+	names := make([]string, 0, len(registrarExporters))
+	for name := range registrarExporters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// writeNamecheapExport writes one domain per line, as pasted into the
+// Namecheap bulk domain search.
+func writeNamecheapExport(w io.Writer, results []DomainResult) error {
+	for _, result := range results {
+		if _, err := fmt.Fprintln(w, result.Domain); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writePorkbunExport writes a CSV with the name and the TLD in separate
+// columns, so co.uk stays one TLD.
+func writePorkbunExport(w io.Writer, results []DomainResult) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"sld", "tld"})
+	for _, result := range results {
+		name, tld := splitRegistrable(result.Domain)
+		cw.Write([]string{name, tld})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeGenericCSVExport writes the domain split both ways, with the score
+// and price when the run had them, for registrars without a fixed layout.
+func writeGenericCSVExport(w io.Writer, results []DomainResult) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"domain", "name", "tld", "score", "registration_price", "currency"})
+	for _, result := range results {
+		name, tld := splitRegistrable(result.Domain)
+		var score, price, currency string
+		if result.Score != 0 {
+			score = strconv.FormatFloat(result.Score, 'f', 2, 64)
+		}
+		if result.Price != nil {
+			price = strconv.FormatFloat(result.Price.Registration, 'f', 2, 64)
+			currency = result.Price.Currency
+		}
+		cw.Write([]string{result.Domain, name, tld, score, price, currency})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// splitRegistrable splits a domain at its public suffix.
+func splitRegistrable(domain string) (string, string) {
+	suffix, _ := publicsuffix.PublicSuffix(domain)
+	if suffix == domain {
+		return domain, ""
+	}
+	return strings.TrimSuffix(domain, "."+suffix), suffix
+}
+
+// exportAvailableDomains writes the domains -quiet would print to path in
+// the layout of the named registrar. With confidentOnly, verdicts drawn
+// from indirect evidence are left out. It returns how many were written.
+func exportAvailableDomains(path, registrar string, report *Report, opts RenderOptions, confidentOnly bool) (int, error) {
+	var results []DomainResult
+	for _, result := range rankedAvailable(report.Results, opts) {
+		if confidentOnly && result.Confidence == ConfidenceLow {
+			continue
+		}
+		results = append(results, result)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, fmt.Errorf("writing registrar export: %w", err)
+	}
+	if err := registrarExporters[registrar](file, results); err != nil {
+		file.Close()
+		return 0, fmt.Errorf("writing registrar export: %w", err)
+	}
+	return len(results), file.Close()
+}
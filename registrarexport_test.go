@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files under testdata from the current output")
+
+// exportReport mixes what an export must leave out with the available
+// domains it keeps: names under a multi-label suffix, an IDN, and ones with
+// and without a score or a price.
+func exportReport() *Report {
+	return &Report{Results: []DomainResult{
+		{Domain: "superfast.com", Status: StatusAvailable, Score: 8.5, Price: &Price{Registration: 10.28, Renewal: 10.28, Currency: "USD"}},
+		{Domain: "fastcloud.com", Status: StatusTaken},
+		{Domain: "superfast.co.uk", Status: StatusAvailable, Score: 7.25},
+		{Domain: "cloudfast.io", Status: StatusAvailable, Confidence: ConfidenceLow, Price: &Price{Registration: 34.5, Renewal: 39, Currency: "EUR"}},
+		{Domain: "superfast.net", Status: StatusUnknown},
+		{Domain: "xn--schnell-q9a.de", Status: StatusAvailable},
+		{Domain: "superfast.com.au", Status: StatusReserved},
+	}}
+}
+
+func TestRegistrarExportGolden(t *testing.T) {
+	for _, tt := range []struct {
+		registrar, golden string
+		confidentOnly     bool
+		written           int
+	}{
+		{"namecheap", "namecheap.txt", false, 4},
+		{"porkbun", "porkbun.csv", false, 4},
+		{"generic-csv", "generic.csv", false, 4},
+		{"generic-csv", "generic-confident.csv", true, 3},
+	} {
+		t.Run(tt.golden, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "export")
+			written, err := exportAvailableDomains(path, tt.registrar, exportReport(), RenderOptions{}, tt.confidentOnly)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if written != tt.written {
+				t.Errorf("wrote %d domains, want %d", written, tt.written)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			golden := filepath.Join("testdata", "export", tt.golden)
+			if *updateGolden {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("export differs from %s (go test -update rewrites it):\n%s", golden, got)
+			}
+		})
+	}
+}
+
+// TestRegistrarExportSorted checks that the export follows the order of
+// the report, as -quiet prints it.
+func TestRegistrarExportSorted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export")
+	if _, err := exportAvailableDomains(path, "namecheap", exportReport(), RenderOptions{Sort: "name"}, false); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "cloudfast.io\nsuperfast.co.uk\nsuperfast.com\nxn--schnell-q9a.de\n"
+	if string(got) != want {
+		t.Errorf("sorted export = %q, want %q", got, want)
+	}
+}
+
+func TestSplitRegistrable(t *testing.T) {
+	for _, tt := range []struct{ domain, name, tld string }{
+		{"superfast.com", "superfast", "com"},
+		{"superfast.co.uk", "superfast", "co.uk"},
+		{"superfast.com.au", "superfast", "com.au"},
+		{"xn--schnell-q9a.de", "xn--schnell-q9a", "de"},
+		{"com", "com", ""},
+	} {
+		name, tld := splitRegistrable(tt.domain)
+		if name != tt.name || tld != tt.tld {
+			t.Errorf("splitRegistrable(%q) = %q, %q; want %q, %q", tt.domain, name, tld, tt.name, tt.tld)
+		}
+	}
+}
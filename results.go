@@ -0,0 +1,611 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func runResults(args []string) int {
+	usage := func() {
+		fmt.Fprintf(os.Stderr, "Domain Checker - Work with stored results\n\n")
+		fmt.Fprintf(os.Stderr, "Usage:\n")
+		fmt.Fprintf(os.Stderr, "  %s results history -history=checks.db <domain>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s results transitions -history=checks.db -since=7d\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s results diff <old.json> <new.json>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s results merge <a.json> <b.json>... -o merged.json\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s results render <results.json> -format=markdown\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s results info <results.json> | -history=checks.db <run-id>\n\n", os.Args[0])
+	}
+
+	if len(args) == 0 {
+		usage()
+		return 1
+	}
+
+	switch args[0] {
+	case "history":
+		return runResultsHistory(args[1:])
+	case "transitions":
+		return runResultsTransitions(args[1:])
+	case "diff":
+		return runResultsDiff(args[1:])
+	case "merge":
+		return runResultsMerge(args[1:])
+	case "render":
+		return runResultsRender(args[1:])
+	case "info":
+		return runResultsInfo(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown results command %q\n\n", args[0])
+		usage()
+		return 1
+	}
+}
+
+// parseInterspersed parses flags that may appear before, between or after
+// positional arguments and returns the positional arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func runResultsHistory(args []string) int {
+	fs := flag.NewFlagSet("results history", flag.ExitOnError)
+	historyPath := fs.String("history", "", "SQLite history database written by -history (required)")
+	timezone := addTimezoneFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage:\n")
+		fmt.Fprintf(os.Stderr, "  %s results history -history=checks.db <domain>\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	positional := parseInterspersed(fs, args)
+	if err := setDisplayZone(*timezone); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if *historyPath == "" || len(positional) != 1 {
+		fs.Usage()
+		return 1
+	}
+
+	store, err := OpenHistory(*historyPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer store.Close()
+
+	domain := strings.ToLower(positional[0])
+	entries, err := store.Timeline(domain)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if len(entries) == 0 {
+		fmt.Printf("No history for %s\n", domain)
+		return 0
+	}
+
+	fmt.Printf("History for %s (%d checks):\n", domain, len(entries))
+	var prev Status
+	for _, e := range entries {
+		marker := " "
+		if e.Status != prev && e.Status != StatusError {
+			marker = "*"
+			prev = e.Status
+		}
+		line := fmt.Sprintf("%s %s  %-9s", marker, displayTime(e.CheckedAt, "2006-01-02 15:04:05"), describeStatus(e.Status, e.EPPStatus))
+		line += fmt.Sprintf("  %s %s  run %s", e.Method, e.Duration.Round(time.Millisecond), e.RunID)
+		if e.Error != "" {
+			line += "  " + e.Error
+		}
+		fmt.Println(line)
+	}
+	return 0
+}
+
+// parseSince reads a look-back period as a Go duration or a number of days,
+// e.g. 36h or 7d.
+func parseSince(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid period %q (use e.g. 7d or 36h)", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid period %q (use e.g. 7d or 36h)", value)
+	}
+	return d, nil
+}
+
+// runResultsTransitions lists the domains someone registered after a run
+// had found them available: others moving into the same naming space.
+func runResultsTransitions(args []string) int {
+	fs := flag.NewFlagSet("results transitions", flag.ExitOnError)
+	historyPath := fs.String("history", "", "SQLite history database written by -history (required)")
+	since := fs.String("since", "7d", "Only list domains found taken within this period (e.g. 7d or 36h)")
+	timezone := addTimezoneFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage:\n")
+		fmt.Fprintf(os.Stderr, "  %s results transitions -history=checks.db [-since=7d]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if err := setDisplayZone(*timezone); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if *historyPath == "" || fs.NArg() > 0 {
+		fs.Usage()
+		return 1
+	}
+	period, err := parseSince(*since)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: -since: %v\n", err)
+		return 1
+	}
+
+	store, err := OpenHistory(*historyPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer store.Close()
+
+	registrations, err := store.Registrations(time.Now().Add(-period))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if len(registrations) == 0 {
+		fmt.Printf("No domain went from available to taken in the last %s\n", *since)
+		return 0
+	}
+
+	fmt.Printf("Registered by someone else in the last %s (%d):\n", *since, len(registrations))
+	for _, r := range registrations {
+		line := fmt.Sprintf("  %s  %s  available %s taken", displayTime(r.Taken.CheckedAt, "2006-01-02 15:04"), r.Domain, sym.Arrow)
+		var details []string
+		if r.Registrar != "" {
+			details = append(details, "registrar "+r.Registrar)
+		}
+		if !r.CreatedAt.IsZero() {
+			details = append(details, "created "+r.CreatedAt.Format("2006-01-02"))
+		}
+		if len(details) > 0 {
+			line += " (" + strings.Join(details, ", ") + ")"
+		}
+		fmt.Println(line)
+	}
+	return 0
+}
+
+type ResultsDiff struct {
+	NewlyAvailable []StatusChange
+	NewlyTaken     []StatusChange
+	OtherChanges   []StatusChange
+	OnlyInOld      []DomainResult
+	OnlyInNew      []DomainResult
+}
+
+type StatusChange struct {
+	Domain string
+	Old    DomainResult
+	New    DomainResult
+}
+
+func (c StatusChange) String() string {
+	return fmt.Sprintf("%s: %s %s %s", c.Domain, c.Old.Status, sym.Arrow, c.New.Status)
+}
+
+func diffResults(before, after []DomainResult) ResultsDiff {
+	var diff ResultsDiff
+
+	oldByDomain := map[string]DomainResult{}
+	for _, result := range before {
+		oldByDomain[result.Domain] = result
+	}
+	newByDomain := map[string]DomainResult{}
+	for _, result := range after {
+		newByDomain[result.Domain] = result
+	}
+
+	for _, n := range after {
+		o, ok := oldByDomain[n.Domain]
+		if !ok {
+			diff.OnlyInNew = append(diff.OnlyInNew, n)
+			continue
+		}
+		if o.Status == n.Status {
+			continue
+		}
+
+		change := StatusChange{Domain: n.Domain, Old: o, New: n}
+		switch {
+		case n.Status == StatusAvailable:
+			diff.NewlyAvailable = append(diff.NewlyAvailable, change)
+		case o.Status == StatusAvailable && n.Status == StatusTaken:
+			diff.NewlyTaken = append(diff.NewlyTaken, change)
+		default:
+			diff.OtherChanges = append(diff.OtherChanges, change)
+		}
+	}
+
+	for _, o := range before {
+		if _, ok := newByDomain[o.Domain]; !ok {
+			diff.OnlyInOld = append(diff.OnlyInOld, o)
+		}
+	}
+
+	return diff
+}
+
+func runResultsDiff(args []string) int {
+	fs := flag.NewFlagSet("results diff", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage:\n")
+		fmt.Fprintf(os.Stderr, "  %s results diff <old.json> <new.json>\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Compares two json or ndjson results files by domain.\n")
+		fmt.Fprintf(os.Stderr, "Exits with 1 when at least one domain became available, 2 on errors.\n")
+	}
+	positional := parseInterspersed(fs, args)
+
+	if len(positional) != 2 {
+		fs.Usage()
+		return 2
+	}
+
+	before, err := readReportFile(positional[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	after, err := readReportFile(positional[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	diff := diffResults(before.Results, after.Results)
+
+	printChanges := func(title string, changes []StatusChange) {
+		if len(changes) == 0 {
+			return
+		}
+		fmt.Printf("%s (%d):\n", title, len(changes))
+		for _, change := range changes {
+			fmt.Printf("  %s\n", change)
+		}
+		fmt.Println()
+	}
+	printDomains := func(title string, results []DomainResult) {
+		if len(results) == 0 {
+			return
+		}
+		fmt.Printf("%s (%d):\n", title, len(results))
+		for _, result := range results {
+			fmt.Printf("  %s (%s)\n", result.Domain, result.Status)
+		}
+		fmt.Println()
+	}
+
+	printChanges(sym.Check+" NEWLY AVAILABLE", diff.NewlyAvailable)
+	printChanges(sym.Cross+" NEWLY TAKEN", diff.NewlyTaken)
+	printChanges("? OTHER STATUS CHANGES", diff.OtherChanges)
+	printDomains("- ONLY IN "+positional[0], diff.OnlyInOld)
+	printDomains("+ ONLY IN "+positional[1], diff.OnlyInNew)
+
+	fmt.Printf("Summary: %d newly available, %d newly taken, %d other changes, %d only in old, %d only in new\n",
+		len(diff.NewlyAvailable), len(diff.NewlyTaken), len(diff.OtherChanges), len(diff.OnlyInOld), len(diff.OnlyInNew))
+
+	if len(diff.NewlyAvailable) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// MergeConflict lists results for one domain whose verdicts disagree.
+type MergeConflict struct {
+	Domain  string
+	Results []DomainResult
+}
+
+// mergeResults keeps one result per domain, preferring real verdicts over
+// errors and the most recent check among equals. Domains with disagreeing
+// verdicts are reported as conflicts; the most recent verdict is kept.
+func mergeResults(reports []*Report) ([]DomainResult, []MergeConflict) {
+	var order []string
+	byDomain := map[string][]DomainResult{}
+	for _, report := range reports {
+		for _, result := range report.Results {
+			if _, ok := byDomain[result.Domain]; !ok {
+				order = append(order, result.Domain)
+			}
+			byDomain[result.Domain] = append(byDomain[result.Domain], result)
+		}
+	}
+
+	var merged []DomainResult
+	var conflicts []MergeConflict
+	for _, domain := range order {
+		candidates := byDomain[domain]
+
+		best := candidates[0]
+		verdicts := map[Status]bool{}
+		for _, result := range candidates {
+			if result.Status.IsVerdict() {
+				verdicts[result.Status] = true
+			}
+			if betterMergeCandidate(result, best) {
+				best = result
+			}
+		}
+
+		if len(verdicts) > 1 {
+			conflicts = append(conflicts, MergeConflict{Domain: domain, Results: candidates})
+		}
+		merged = append(merged, best)
+	}
+	return merged, conflicts
+}
+
+func betterMergeCandidate(a, b DomainResult) bool {
+	if a.Status.IsVerdict() != b.Status.IsVerdict() {
+		return a.Status.IsVerdict()
+	}
+	return a.CheckedAt.After(b.CheckedAt)
+}
+
+func runResultsMerge(args []string) int {
+	fs := flag.NewFlagSet("results merge", flag.ExitOnError)
+	output := fs.String("o", "", "Write the merged results to this file instead of stdout")
+	format := fs.String("format", "", "Output format: "+rendererNames()+" (default: from -o extension, json on stdout)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage:\n")
+		fmt.Fprintf(os.Stderr, "  %s results merge <a.json> <b.json>... -o merged.json\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	positional := parseInterspersed(fs, args)
+
+	if len(positional) == 0 {
+		fs.Usage()
+		return 1
+	}
+
+	outFormat := *format
+	if outFormat == "" {
+		outFormat = "json"
+		if *output != "" {
+			outFormat = formatForPath(*output)
+		}
+	}
+	render, ok := renderers[outFormat]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown -format %q\n", outFormat)
+		return 1
+	}
+
+	var reports []*Report
+	for _, path := range positional {
+		report, err := readReportFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		reports = append(reports, report)
+	}
+
+	merged := &Report{}
+	for _, report := range reports {
+		if !report.StartedAt.IsZero() && (merged.StartedAt.IsZero() || report.StartedAt.Before(merged.StartedAt)) {
+			merged.StartedAt = report.StartedAt
+		}
+		if report.FinishedAt.After(merged.FinishedAt) {
+			merged.FinishedAt = report.FinishedAt
+		}
+	}
+
+	var conflicts []MergeConflict
+	merged.Results, conflicts = mergeResults(reports)
+	merged.summarize()
+
+	if len(conflicts) > 0 {
+		fmt.Fprintf(os.Stderr, "%s CONFLICTS (%d) %s verdicts disagree, often a sign of throttling on one shard:\n", sym.Warning, len(conflicts), sym.Dash)
+		for _, conflict := range conflicts {
+			var parts []string
+			for _, result := range conflict.Results {
+				parts = append(parts, fmt.Sprintf("%s at %s", result.Status, result.CheckedAt.UTC().Format(time.RFC3339)))
+			}
+			fmt.Fprintf(os.Stderr, "  %s: %s\n", conflict.Domain, strings.Join(parts, ", "))
+		}
+		fmt.Fprintln(os.Stderr)
+	}
+
+	if *output == "" {
+		if err := render(os.Stdout, merged, RenderOptions{}); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	} else {
+		if err := writeReportFileFormat(*output, outFormat, merged); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Merged %d files: %d domains, %d conflicts\n", len(reports), len(merged.Results), len(conflicts))
+	return 0
+}
+
+func runResultsRender(args []string) int {
+	fs := flag.NewFlagSet("results render", flag.ExitOnError)
+	format := fs.String("format", "text", "Output format: "+rendererNames())
+	availableOnly := fs.Bool("available-only", false, "Only render available domains")
+	rank := fs.Bool("rank", false, "List available domains by their stored score, best first")
+	tldSummary := fs.Bool("tld-summary", false, "Only render the per-TLD summary (a json array with -format=json, a table otherwise)")
+	renderFlags := addRenderFlags(fs)
+	timezone := addTimezoneFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage:\n")
+		fmt.Fprintf(os.Stderr, "  %s results render <results.json> [options]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Re-renders a stored json or ndjson results file without checking anything.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	positional := parseInterspersed(fs, args)
+	if err := setDisplayZone(*timezone); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if len(positional) != 1 {
+		fs.Usage()
+		return 1
+	}
+	render, ok := renderers[*format]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown -format %q\n", *format)
+		return 1
+	}
+
+	renderOpts, err := renderFlags.Options()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	renderOpts.Rank = *rank
+	renderOpts.FullListPath = positional[0]
+
+	report, err := readReportFile(positional[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if *availableOnly {
+		var available []DomainResult
+		for _, result := range report.Results {
+			if result.Status == StatusAvailable {
+				available = append(available, result)
+			}
+		}
+		report.Results = available
+		report.summarize()
+	}
+
+	if *tldSummary {
+		if *format == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			err = enc.Encode(report.TLDSummary)
+		} else {
+			err = writeTLDSummaryTable(os.Stdout, report.TLDSummary)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	if *format == "text" {
+		if checkedAt := reportCheckedAt(report); !checkedAt.IsZero() {
+			fmt.Printf("Results from %s (%s ago)\n\n",
+				displayTime(checkedAt, "2006-01-02 15:04"), formatAge(time.Since(checkedAt)))
+		}
+	}
+
+	if err := render(os.Stdout, report, renderOpts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// reportCheckedAt is the start of the run, or the oldest check when the file
+// carries no run timestamps (ndjson).
+func reportCheckedAt(report *Report) time.Time {
+	if !report.StartedAt.IsZero() {
+		return report.StartedAt
+	}
+	var oldest time.Time
+	for _, result := range report.Results {
+		if !result.CheckedAt.IsZero() && (oldest.IsZero() || result.CheckedAt.Before(oldest)) {
+			oldest = result.CheckedAt
+		}
+	}
+	return oldest
+}
+
+// runResultsInfo prints the manifest of a run, read from a json results
+// file or, with -history, from the history database.
+func runResultsInfo(args []string) int {
+	fs := flag.NewFlagSet("results info", flag.ExitOnError)
+	historyPath := fs.String("history", "", "Read the run from this SQLite history database; the argument is then a run ID")
+	timezone := addTimezoneFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage:\n")
+		fmt.Fprintf(os.Stderr, "  %s results info <results.json>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  %s results info -history=checks.db <run-id>\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	positional := parseInterspersed(fs, args)
+	if err := setDisplayZone(*timezone); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return 1
+	}
+
+	var run RunInfo
+	if *historyPath != "" {
+		store, err := OpenHistory(*historyPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		defer store.Close()
+		if run, err = store.Run(positional[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	} else {
+		report, err := readReportFile(positional[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		run = report.RunInfo
+	}
+
+	if run.Manifest == nil {
+		fmt.Fprintf(os.Stderr, "Error: %s has no manifest (written by an older version, merged, or not a check run)\n", positional[0])
+		return 1
+	}
+	writeManifest(os.Stdout, run.Manifest, run)
+	return 0
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a standard five-field cron expression
+// (minute hour day-of-month month day-of-week).
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+func parseCron(expr string) (*cronSchedule, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("cron schedule %q: expected %d fields, got %d", expr, len(cronFields), len(parts))
+	}
+
+	var bits [5]uint64
+	for i, part := range parts {
+		b, err := parseCronField(part, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("cron schedule %q: %w", expr, err)
+		}
+		bits[i] = b
+	}
+
+	// Sunday may be written as 7.
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+
+	return &cronSchedule{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		domAny: parts[2] == "*",
+		dowAny: parts[4] == "*",
+	}, nil
+}
+
+func parseCronField(field string, f cronField) (uint64, error) {
+	max := f.max
+	if f.name == "day of week" {
+		max = 7
+	}
+
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q in %s field", stepPart, f.name)
+			}
+			step = n
+		}
+
+		lo, hi := f.min, max
+		if rangePart != "*" {
+			loPart, hiPart, isRange := strings.Cut(rangePart, "-")
+			n, err := strconv.Atoi(loPart)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q in %s field", loPart, f.name)
+			}
+			lo, hi = n, n
+			if isRange {
+				n, err := strconv.Atoi(hiPart)
+				if err != nil {
+					return 0, fmt.Errorf("invalid value %q in %s field", hiPart, f.name)
+				}
+				hi = n
+			} else if hasStep {
+				hi = max
+			}
+		}
+
+		if lo < f.min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%s field value %q out of range %d-%d", f.name, item, f.min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// Next returns the first matching time strictly after t, reading the
+// schedule in the zone of t. Wall-clock times are walked rather than
+// instants, so daylight saving transitions neither skip nor repeat a run:
+// a time the clocks jump over runs at the instant after the jump, and a
+// time the clocks go through twice runs the first time only.
+func (c *cronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	// wall holds the wall-clock time of loc in UTC, which has no
+	// transitions to step over.
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC).Add(time.Minute)
+	// Every valid expression matches at least once within a few years.
+	limit := wall.AddDate(5, 0, 0)
+	for wall.Before(limit) {
+		if c.month&(1<<uint(wall.Month())) == 0 {
+			wall = time.Date(wall.Year(), wall.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !c.dayMatches(wall) {
+			wall = time.Date(wall.Year(), wall.Month(), wall.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if c.hour&(1<<uint(wall.Hour())) == 0 {
+			wall = time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour()+1, 0, 0, 0, time.UTC)
+			continue
+		}
+		if c.minute&(1<<uint(wall.Minute())) == 0 {
+			wall = wall.Add(time.Minute)
+			continue
+		}
+		next := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), 0, 0, loc)
+		if local := next.In(loc); local.Hour() != wall.Hour() || local.Minute() != wall.Minute() {
+			// The clocks jumped over this wall-clock time, and time.Date
+			// moved it to one side of the jump or the other; run at the
+			// jump itself.
+			start, end := next.ZoneBounds()
+			shown := time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), local.Minute(), 0, 0, time.UTC)
+			if shown.After(wall) {
+				next = start
+			} else {
+				next = end
+			}
+		}
+		// time.Date may pick the second of two instants with this wall
+		// clock; the clocks went back by the drop in offset since the day
+		// before.
+		_, before := next.Add(-24 * time.Hour).Zone()
+		if _, offset := next.Zone(); before > offset {
+			first := next.Add(-time.Duration(before-offset) * time.Second)
+			if first.In(loc).Hour() == wall.Hour() && first.In(loc).Minute() == wall.Minute() && first.After(t) {
+				next = first
+			}
+		}
+		if next.After(t) {
+			return next
+		}
+		// A repeated wall-clock time whose first occurrence has passed.
+		wall = wall.Add(time.Minute)
+	}
+	return time.Time{}
+}
+
+// matches reports whether the minute of t is one the schedule names.
+func (c *cronSchedule) matches(t time.Time) bool {
+	return c.month&(1<<uint(t.Month())) != 0 && c.dayMatches(t) &&
+		c.hour&(1<<uint(t.Hour())) != 0 && c.minute&(1<<uint(t.Minute())) != 0
+}
+
+func (c *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := c.dom&(1<<uint(t.Day())) != 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+	// As in classic cron, when both day fields are restricted either may match.
+	if !c.domAny && !c.dowAny {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+// sweeps runs the monitor's scheduling on a fake clock from start to end
+// and returns when each sweep would begin, in the display zone.
+func sweeps(t *testing.T, expr string, start, end time.Time) []time.Time {
+	t.Helper()
+	cron, err := parseCron(expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := MonitorConfig{Schedule: cron}
+	var times []time.Time
+	for now := nextSweep(config, start); now.Before(end); now = nextSweep(config, now) {
+		if len(times) > 0 && !now.After(times[len(times)-1]) {
+			t.Fatalf("sweep at %v does not follow the one at %v", now, times[len(times)-1])
+		}
+		times = append(times, now.In(displayZone))
+	}
+	return times
+}
+
+// TestScheduleAcrossDST checks that daily sweeps in a -timezone with
+// daylight saving neither repeat nor skip a day at either transition.
+func TestScheduleAcrossDST(t *testing.T) {
+	for _, tt := range []struct {
+		name, zone, expr string
+		// day is the local date of the transition.
+		day  time.Time
+		want []string
+	}{
+		{
+			name: "berlin spring forward runs the missing 02:30 at 03:00",
+			zone: "Europe/Berlin", expr: "30 2 * * *",
+			day:  time.Date(2026, 3, 29, 0, 0, 0, 0, time.UTC),
+			want: []string{"2026-03-28 02:30 CET", "2026-03-29 03:00 CEST", "2026-03-30 02:30 CEST"},
+		},
+		{
+			name: "berlin fall back runs the repeated 02:30 once",
+			zone: "Europe/Berlin", expr: "30 2 * * *",
+			day:  time.Date(2026, 10, 25, 0, 0, 0, 0, time.UTC),
+			want: []string{"2026-10-24 02:30 CEST", "2026-10-25 02:30 CEST", "2026-10-26 02:30 CET"},
+		},
+		{
+			name: "berlin hour unaffected",
+			zone: "Europe/Berlin", expr: "0 9 * * *",
+			day:  time.Date(2026, 10, 25, 0, 0, 0, 0, time.UTC),
+			want: []string{"2026-10-24 09:00 CEST", "2026-10-25 09:00 CET", "2026-10-26 09:00 CET"},
+		},
+		{
+			name: "new york spring forward runs the missing 02:15 at 03:00",
+			zone: "America/New_York", expr: "15 2 * * *",
+			day:  time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC),
+			want: []string{"2026-03-07 02:15 EST", "2026-03-08 03:00 EDT", "2026-03-09 02:15 EDT"},
+		},
+		{
+			name: "new york fall back runs the repeated 01:45 once",
+			zone: "America/New_York", expr: "45 1 * * *",
+			day:  time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC),
+			want: []string{"2026-10-31 01:45 EDT", "2026-11-01 01:45 EDT", "2026-11-02 01:45 EST"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func(zone *time.Location) { displayZone = zone }(displayZone)
+			if err := setDisplayZone(tt.zone); err != nil {
+				t.Fatal(err)
+			}
+			loc := displayZone
+			start := time.Date(tt.day.Year(), tt.day.Month(), tt.day.Day()-1, 0, 0, 0, 0, loc)
+			end := time.Date(tt.day.Year(), tt.day.Month(), tt.day.Day()+2, 0, 0, 0, 0, loc)
+
+			var got []string
+			for _, sweep := range sweeps(t, tt.expr, start, end) {
+				got = append(got, displayTime(sweep, "2006-01-02 15:04 MST"))
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("sweeps = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("sweep %d = %s, want %s", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// TestScheduleRepeatedHour runs a half-hourly schedule through the day the
+// clocks go back: the repeated hour is swept once, so the day has as many
+// sweeps as any other.
+func TestScheduleRepeatedHour(t *testing.T) {
+	defer func(zone *time.Location) { displayZone = zone }(displayZone)
+	displayZone = mustLoadLocation(t, "Europe/Berlin")
+
+	for _, day := range []int{24, 25, 26} {
+		start := time.Date(2026, 10, day, 0, 0, 0, 0, displayZone).Add(-time.Second)
+		end := time.Date(2026, 10, day+1, 0, 0, 0, 0, displayZone)
+		if n := len(sweeps(t, "*/30 * * * *", start, end)); n != 48 {
+			t.Errorf("2026-10-%d: %d sweeps, want 48", day, n)
+		}
+	}
+
+	// The day the clocks go forward, the missing 02:00 and 02:30 run at the
+	// jump to 03:00, together with 03:00 itself: one sweep for three.
+	start := time.Date(2026, 3, 29, 0, 0, 0, 0, displayZone).Add(-time.Second)
+	end := time.Date(2026, 3, 30, 0, 0, 0, 0, displayZone)
+	got := sweeps(t, "*/30 * * * *", start, end)
+	if len(got) != 46 || got[4].Format("15:04 MST") != "03:00 CEST" || got[5].Format("15:04 MST") != "03:30 CEST" {
+		t.Errorf("2026-03-29: %d sweeps, the fifth and sixth at %v and %v; want 46, at 03:00 and 03:30", len(got), got[4], got[5])
+	}
+}
+
+// TestDisplayTimeKeepsUTCInstant checks that -timezone changes how a time
+// is shown, not the instant machine outputs record.
+func TestDisplayTimeKeepsUTCInstant(t *testing.T) {
+	defer func(zone *time.Location) { displayZone = zone }(displayZone)
+	if err := setDisplayZone("America/New_York"); err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2026, 11, 1, 5, 45, 0, 0, time.UTC)
+	if got := displayTime(at, "15:04 MST"); got != "01:45 EDT" {
+		t.Errorf("displayTime = %s, want 01:45 EDT", got)
+	}
+	if got := displayTime(at.Add(time.Hour), "15:04 MST"); got != "01:45 EST" {
+		t.Errorf("displayTime an hour later = %s, want 01:45 EST", got)
+	}
+	if err := setDisplayZone("Mars/Olympus"); err == nil {
+		t.Error("setDisplayZone accepted an unknown zone")
+	}
+	result := DomainResult{Domain: "example.com", Status: StatusTaken, CheckedAt: at.In(displayZone)}
+	data, err := result.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `"checkedAt":"2026-11-01T05:45:00Z"`; !strings.Contains(string(data), want) {
+		t.Errorf("json = %s, want it to hold %s", data, want)
+	}
+}
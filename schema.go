@@ -0,0 +1,113 @@
+package main
+
+//go:generate sh -c "go run . -print-schema > schema.json"
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// outputSchemaVersion is the schemaVersion of json reports. Bump it when a
+// field is renamed, removed or changes meaning; adding a field does not
+// break readers and keeps the version. Regenerate schema.json with
+// go generate after any change to the report types.
+const outputSchemaVersion = 1
+
+// statuses are all values of the status field.
+var statuses = []Status{
+	StatusAvailable, StatusTaken, StatusError, StatusReserved, StatusUnknown,
+	StatusDeferred, StatusUnchecked, StatusExists, StatusAbsent,
+}
+
+// wireTypes are the types written in place of types with a MarshalJSON
+// method.
+var wireTypes = map[reflect.Type]reflect.Type{
+	reflect.TypeOf(DomainResult{}): reflect.TypeOf(jsonDomainResult{}),
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// writeSchema writes the JSON Schema of the json report, derived from the
+// report types so it cannot drift from what is written.
+func writeSchema(w io.Writer) error {
+	schema := jsonSchema(reflect.TypeOf(Report{}))
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["$id"] = "https://github.com/botsman/domain-checker/schema.json"
+	schema["title"] = "domain-checker json report"
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(schema)
+}
+
+func jsonSchema(t reflect.Type) map[string]any {
+	if wire, ok := wireTypes[t]; ok {
+		t = wire
+	}
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t == reflect.TypeOf(Status("")):
+		return map[string]any{"type": "string", "enum": statuses}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return jsonSchema(t.Elem())
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": jsonSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": jsonSchema(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]any{}
+		required := []string{}
+		addStructFields(t, properties, &required)
+		schema := map[string]any{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	}
+	return map[string]any{}
+}
+
+// addStructFields adds the json fields of t, flattening embedded structs
+// as encoding/json does. Fields without omitempty are required.
+func addStructFields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			addStructFields(field.Type, properties, required)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema := jsonSchema(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			*required = append(*required, name)
+			// encoding/json writes nil pointers, slices and maps as null.
+			switch field.Type.Kind() {
+			case reflect.Pointer, reflect.Slice, reflect.Map:
+				schema["type"] = []any{schema["type"], "null"}
+			}
+		}
+		properties[name] = schema
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+)
+
+// validate checks value, as decoded by encoding/json, against the subset of
+// JSON Schema writeSchema produces. Properties the schema does not list are
+// reported too, so a field added without regenerating the schema fails.
+func validate(schema map[string]any, value any, path string) []string {
+	var problems []string
+	if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, value) {
+		problems = append(problems, fmt.Sprintf("%s: %v is not one of %v", path, value, enum))
+	}
+	types := []any{schema["type"]}
+	if list, ok := schema["type"].([]any); ok {
+		types = list
+	}
+	if schema["type"] != nil && !slices.ContainsFunc(types, func(t any) bool { return hasJSONType(value, t.(string)) }) {
+		return append(problems, fmt.Sprintf("%s: %T is not of type %v", path, value, schema["type"]))
+	}
+
+	switch v := value.(type) {
+	case string:
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, v); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", path, err))
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				problems = append(problems, validate(items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	case map[string]any:
+		for _, name := range asStrings(schema["required"]) {
+			if _, ok := v[name]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: missing required %q", path, name))
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		additional, _ := schema["additionalProperties"].(map[string]any)
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			switch {
+			case properties[name] != nil:
+				problems = append(problems, validate(properties[name].(map[string]any), v[name], path+"."+name)...)
+			case additional != nil:
+				problems = append(problems, validate(additional, v[name], path+"."+name)...)
+			default:
+				problems = append(problems, fmt.Sprintf("%s: %q is not in the schema", path, name))
+			}
+		}
+	}
+	return problems
+}
+
+func hasJSONType(value any, t string) bool {
+	switch v := value.(type) {
+	case nil:
+		return t == "null"
+	case bool:
+		return t == "boolean"
+	case float64:
+		return t == "number" || t == "integer" && v == float64(int64(v))
+	case string:
+		return t == "string"
+	case []any:
+		return t == "array"
+	case map[string]any:
+		return t == "object"
+	}
+	return false
+}
+
+func asStrings(value any) []string {
+	var strs []string
+	list, _ := value.([]any)
+	for _, s := range list {
+		strs = append(strs, s.(string))
+	}
+	return strs
+}
+
+func readSchema(t *testing.T) map[string]any {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := writeSchema(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &schema); err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+// TestSchemaFileUpToDate fails when the report types changed without
+// schema.json being regenerated.
+func TestSchemaFileUpToDate(t *testing.T) {
+	committed, err := os.ReadFile("schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := writeSchema(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(committed, buf.Bytes()) {
+		t.Error("schema.json is out of date; run go generate")
+	}
+}
+
+// TestJSONReportRoundTrip writes sample results as a json report, checks
+// the report against the schema, and reads the results back unchanged.
+func TestJSONReportRoundTrip(t *testing.T) {
+	checkedAt := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	results := []DomainResult{
+		{
+			Domain:     "superfast.io",
+			Status:     StatusTaken,
+			EPPStatus:  []string{"clientTransferProhibited"},
+			Registrar:  "Example Registrar",
+			CreatedAt:  time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+			ExpiresAt:  time.Date(2027, 1, 2, 0, 0, 0, 0, time.UTC),
+			Method:     "whois",
+			CheckedAt:  checkedAt,
+			Duration:   1250 * time.Millisecond,
+			Score:      0.75,
+			Handles:    map[string]HandleStatus{"github": HandleTaken},
+			Links:      []PurchaseLink{{Registrar: "porkbun", URL: "https://porkbun.com/checkout/search?q=superfast.io"}},
+			Price:      &Price{Registration: 30, Renewal: 45, Currency: "USD"},
+			RunID:      "run-1",
+			Server:     "whois.nic.io",
+			Confidence: "high",
+			Candidate: &Candidate{
+				FQDN: "superfast.io", BaseName: "superfast", TLD: "io", Keywords: []string{"super", "fast"},
+				Combinations: [][]string{{"super", "fast"}, {"superfast"}},
+			},
+			Attempts: []Attempt{{Method: "whois", Server: "whois.nic.io", Outcome: "taken", DurationMs: 1250}},
+		},
+		{Domain: "superfast.com", Status: StatusError, CheckedAt: checkedAt, Error: errors.New("whois.verisign-grs.com: timeout")},
+		{Domain: "fast.dev", Status: StatusAvailable, Method: "dns", CheckedAt: checkedAt, Suggested: true, Cached: true, Confidence: "low"},
+	}
+	report := &Report{
+		RunInfo: RunInfo{ID: "run-1", StartedAt: checkedAt, FinishedAt: checkedAt.Add(time.Minute)},
+		Results: results,
+	}
+	report.summarize()
+
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, report, RenderOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	var doc any
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	schema := readSchema(t)
+	for _, problem := range validate(schema, doc, "$") {
+		t.Error(problem)
+	}
+	if version := doc.(map[string]any)["schemaVersion"]; version != float64(outputSchemaVersion) {
+		t.Errorf("schemaVersion = %v, want %d", version, outputSchemaVersion)
+	}
+
+	var read Report
+	if err := json.Unmarshal(buf.Bytes(), &read); err != nil {
+		t.Fatal(err)
+	}
+	if len(read.Results) != len(results) {
+		t.Fatalf("read %d results, want %d", len(read.Results), len(results))
+	}
+	for i, got := range read.Results {
+		want := results[i]
+		if fmt.Sprint(got.Error) != fmt.Sprint(want.Error) {
+			t.Errorf("%s: error = %v, want %v", want.Domain, got.Error, want.Error)
+		}
+		got.Error, want.Error = nil, nil
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("round trip of %s:\n got %+v\nwant %+v", want.Domain, got, want)
+		}
+	}
+}
+
+// TestSchemaRejects makes sure the validation above does catch drift.
+func TestSchemaRejects(t *testing.T) {
+	schema := readSchema(t)
+	for _, tt := range []struct {
+		name, doc string
+	}{
+		{"unknown status", `{"schemaVersion":1,"startedAt":"2026-01-01T00:00:00Z","finishedAt":"2026-01-01T00:00:00Z","summary":{},"results":[{"domain":"a.com","status":"free","checkedAt":"2026-01-01T00:00:00Z","durationMs":0}]}`},
+		{"renamed field", `{"schemaVersion":1,"startedAt":"2026-01-01T00:00:00Z","finishedAt":"2026-01-01T00:00:00Z","summary":{},"results":[{"name":"a.com","status":"taken","checkedAt":"2026-01-01T00:00:00Z","durationMs":0}]}`},
+		{"bad time", `{"schemaVersion":1,"startedAt":"yesterday","finishedAt":"2026-01-01T00:00:00Z","summary":{},"results":[]}`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc any
+			if err := json.Unmarshal([]byte(tt.doc), &doc); err != nil {
+				t.Fatal(err)
+			}
+			if len(validate(schema, doc, "$")) == 0 {
+				t.Error("document passed validation")
+			}
+		})
+	}
+}
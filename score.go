@@ -0,0 +1,184 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+//go:embed dictionary.txt
+var dictionaryData string
+
+var dictionary = func() map[string]bool {
+	words := map[string]bool{}
+	for _, word := range strings.Fields(dictionaryData) {
+		words[word] = true
+	}
+	return words
+}()
+
+// RankWeights weigh the scoring factors; each factor is normalized to 0..1.
+type RankWeights struct {
+	Length     float64
+	Keywords   float64
+	Hyphen     float64
+	Com        float64
+	Pronounce  float64
+	Dictionary float64
+}
+
+var defaultRankWeights = RankWeights{
+	Length:     3,
+	Keywords:   1,
+	Hyphen:     1,
+	Com:        2,
+	Pronounce:  1,
+	Dictionary: 1,
+}
+
+// parseRankWeights overrides the defaults with "factor=weight" pairs.
+func parseRankWeights(input string) (RankWeights, error) {
+	weights := defaultRankWeights
+	fields := map[string]*float64{
+		"length":     &weights.Length,
+		"keywords":   &weights.Keywords,
+		"hyphen":     &weights.Hyphen,
+		"com":        &weights.Com,
+		"pronounce":  &weights.Pronounce,
+		"dictionary": &weights.Dictionary,
+	}
+	for _, pair := range parseKeywords(input) {
+		name, value, ok := strings.Cut(pair, "=")
+		field, known := fields[strings.TrimSpace(name)]
+		if !ok || !known {
+			return weights, fmt.Errorf("invalid rank weight %q (use factor=weight with factors length, keywords, hyphen, com, pronounce, dictionary)", pair)
+		}
+		w, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || w < 0 {
+			return weights, fmt.Errorf("invalid rank weight %q", pair)
+		}
+		*field = w
+	}
+	return weights, nil
+}
+
+type Scorer struct {
+	Weights  RankWeights
+	Keywords map[string]bool
+}
+
+func NewScorer(weights RankWeights, keywordLists [][]string) *Scorer {
+	keywords := map[string]bool{}
+	for _, list := range keywordLists {
+		for _, keyword := range list {
+			keywords[strings.ToLower(keyword)] = true
+		}
+	}
+	return &Scorer{Weights: weights, Keywords: keywords}
+}
+
+// Score rates a domain from 0 to 100; higher is more brandable.
+func (s *Scorer) Score(domain string) float64 {
+	label, tld, _ := strings.Cut(domain, ".")
+	plain := strings.ReplaceAll(label, "-", "")
+	w := s.Weights
+
+	factors := []struct{ weight, value float64 }{
+		{w.Length, 1 - clamp(float64(len(plain)-4)/16)},
+		{w.Keywords, 1 / float64(max(s.countKeywords(label), 1))},
+		{w.Hyphen, boolFactor(!strings.Contains(label, "-"))},
+		{w.Com, boolFactor(tld == "com")},
+		{w.Pronounce, pronounceability(plain)},
+		{w.Dictionary, dictionaryCoverage(plain)},
+	}
+
+	var total, weightSum float64
+	for _, f := range factors {
+		total += f.weight * f.value
+		weightSum += f.weight
+	}
+	if weightSum == 0 {
+		return 0
+	}
+	return math.Round(total/weightSum*1000) / 10
+}
+
+// countKeywords greedily splits the label into the known input keywords.
+func (s *Scorer) countKeywords(label string) int {
+	count := 0
+	for _, part := range strings.Split(label, "-") {
+		for len(part) > 0 {
+			matched := 0
+			for n := len(part); n > 0; n-- {
+				if s.Keywords[part[:n]] {
+					matched = n
+					break
+				}
+			}
+			if matched == 0 {
+				count++
+				break
+			}
+			count++
+			part = part[matched:]
+		}
+	}
+	return count
+}
+
+func isVowel(r byte) bool {
+	return strings.IndexByte("aeiouy", r) >= 0
+}
+
+// pronounceability is the share of adjacent letters that alternate between
+// vowels and consonants.
+func pronounceability(s string) float64 {
+	if len(s) < 2 {
+		return 1
+	}
+	alternations := 0
+	for i := 1; i < len(s); i++ {
+		if isVowel(s[i]) != isVowel(s[i-1]) {
+			alternations++
+		}
+	}
+	return float64(alternations) / float64(len(s)-1)
+}
+
+// dictionaryCoverage is the share of characters covered by dictionary words
+// when the label is split greedily from the left.
+func dictionaryCoverage(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	covered := 0
+	for i := 0; i < len(s); {
+		matched := 0
+		for n := len(s) - i; n >= 3; n-- {
+			if dictionary[s[i:i+n]] {
+				matched = n
+				break
+			}
+		}
+		if matched == 0 {
+			i++
+			continue
+		}
+		covered += matched
+		i += matched
+	}
+	return float64(covered) / float64(len(s))
+}
+
+func clamp(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+func boolFactor(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
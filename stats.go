@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// NameStats describes which shapes of name were still available, to tune
+// the keywords and generator flags of later runs. It is computed from the
+// results alone.
+type NameStats struct {
+	Lengths []LengthStat `json:"lengths"`
+	// Hyphenated and Concatenated count the available names joined from
+	// several words, by whether the words are separated by a dash.
+	Hyphenated   int        `json:"hyphenated"`
+	Concatenated int        `json:"concatenated"`
+	Sizes        []SizeStat `json:"combinationSizes,omitempty"`
+}
+
+// LengthStat counts the available and taken names of one length, TLD
+// excluded.
+type LengthStat struct {
+	Length    int `json:"length"`
+	Available int `json:"available"`
+	Taken     int `json:"taken"`
+}
+
+// SizeStat tells how often names combining Keywords keywords were
+// available. Percent is of the checked names, as for KeywordStat.
+type SizeStat struct {
+	Keywords  int     `json:"keywords"`
+	Checked   int     `json:"checked"`
+	Available int     `json:"available"`
+	Percent   float64 `json:"percent"`
+}
+
+// nameStats returns nil when no result was available or taken.
+func nameStats(results []DomainResult) *NameStats {
+	lengths := map[int]*LengthStat{}
+	sizes := map[int]*SizeStat{}
+	stats := &NameStats{}
+	for _, result := range results {
+		if result.Status != StatusAvailable && result.Status != StatusTaken {
+			continue
+		}
+		available := result.Status == StatusAvailable
+
+		n := nameLength(result)
+		l, ok := lengths[n]
+		if !ok {
+			l = &LengthStat{Length: n}
+			lengths[n] = l
+		}
+		if available {
+			l.Available++
+		} else {
+			l.Taken++
+		}
+
+		if result.Candidate == nil || len(result.Candidate.Keywords) == 0 {
+			continue
+		}
+		size := len(result.Candidate.Keywords)
+		s, ok := sizes[size]
+		if !ok {
+			s = &SizeStat{Keywords: size}
+			sizes[size] = s
+		}
+		s.Checked++
+		if !available {
+			continue
+		}
+		s.Available++
+		if len(result.Candidate.parts()) > 1 {
+			if strings.Contains(result.Candidate.BaseName, "-") {
+				stats.Hyphenated++
+			} else {
+				stats.Concatenated++
+			}
+		}
+	}
+	if len(lengths) == 0 {
+		return nil
+	}
+
+	for _, l := range lengths {
+		stats.Lengths = append(stats.Lengths, *l)
+	}
+	sort.Slice(stats.Lengths, func(i, j int) bool { return stats.Lengths[i].Length < stats.Lengths[j].Length })
+	for _, s := range sizes {
+		s.Percent = float64(s.Available) * 100 / float64(s.Checked)
+		stats.Sizes = append(stats.Sizes, *s)
+	}
+	sort.Slice(stats.Sizes, func(i, j int) bool { return stats.Sizes[i].Keywords < stats.Sizes[j].Keywords })
+	return stats
+}
+
+// writeNameStats prints the statistics compactly, one line per length.
+func writeNameStats(w io.Writer, stats *NameStats) {
+	fmt.Fprintln(w, "NAME STATISTICS:")
+	fmt.Fprintln(w, "  length  available  taken")
+	for _, l := range stats.Lengths {
+		fmt.Fprintf(w, "  %6d  %9d  %5d\n", l.Length, l.Available, l.Taken)
+	}
+	if stats.Hyphenated+stats.Concatenated > 0 {
+		fmt.Fprintf(w, "  Available multi-word names: %d hyphenated, %d concatenated\n", stats.Hyphenated, stats.Concatenated)
+	}
+	if len(stats.Sizes) > 0 {
+		var sizes []string
+		for _, s := range stats.Sizes {
+			sizes = append(sizes, fmt.Sprintf("%d: %d/%d (%.0f%%)", s.Keywords, s.Available, s.Checked, s.Percent))
+		}
+		fmt.Fprintf(w, "  Available by keywords combined: %s\n", strings.Join(sizes, ", "))
+	}
+	fmt.Fprintln(w)
+}
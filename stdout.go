@@ -0,0 +1,35 @@
+package main
+
+import (
+	"io"
+	"sync"
+)
+
+// closableStdout is where a check run writes everything meant for stdout.
+// Once a write fails, typically because the reader of a pipe such as
+// "| head -5" exited, it discards the rest instead of failing, so the run
+// still finishes and writes -output and -history.
+type closableStdout struct {
+	mu  sync.Mutex
+	w   io.Writer
+	err error
+}
+
+func (s *closableStdout) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return len(p), nil
+	}
+	if _, err := s.w.Write(p); err != nil {
+		s.err = err
+	}
+	return len(p), nil
+}
+
+// Err returns the write error that closed stdout, or nil.
+func (s *closableStdout) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestRunClosedStdout runs a check as in "domain-checker ... | head -1"
+// once head has exited: the run finishes, still writes -output, and exits
+// with exitStdoutClosed.
+func TestRunClosedStdout(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CACHE_HOME", dir)
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	defer w.Close()
+
+	stdout, stderr := os.Stdout, os.Stderr
+	devnull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devnull.Close()
+	os.Stdout, os.Stderr = w, devnull
+	defer func() { os.Stdout, os.Stderr = stdout, stderr }()
+
+	commandLine := flag.CommandLine
+	flag.CommandLine = flag.NewFlagSet("domain-checker", flag.ContinueOnError)
+	defer func() { flag.CommandLine = commandLine }()
+
+	output := filepath.Join(dir, "results.json")
+	code := run([]string{"-backend=fake", "-keywords=super,fast,cloud", "-tlds=com,io", "-output=" + output}, false)
+
+	if code != exitStdoutClosed {
+		t.Errorf("exit code = %d, want %d", code, exitStdoutClosed)
+	}
+	if _, err := os.Stat(output); err != nil {
+		t.Errorf("-output not written: %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import "strings"
+
+// stem reduces a keyword to a crude root by stripping plural, gerund and
+// agent endings, so run, running and runner all become "run". It only needs
+// to be good enough to spot near-duplicates in keyword lists.
+func stem(word string) string {
+	switch {
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		word = word[:len(word)-3] + "y"
+	case strings.HasSuffix(word, "sses"):
+		word = word[:len(word)-2]
+	case strings.HasSuffix(word, "s") && len(word) > 3 &&
+		!strings.HasSuffix(word, "ss") && !strings.HasSuffix(word, "us") && !strings.HasSuffix(word, "is"):
+		word = word[:len(word)-1]
+	}
+
+	for _, ending := range []string{"ing", "er", "ed"} {
+		root, ok := strings.CutSuffix(word, ending)
+		if !ok || len(root) < 3 || !hasVowel(root) || strings.IndexByte("aeiou", root[len(root)-1]) >= 0 {
+			continue
+		}
+		word = root
+		if n := len(word); n >= 2 && word[n-1] == word[n-2] && strings.IndexByte("aeiouylsz", word[n-1]) < 0 {
+			word = word[:n-1]
+		}
+		break
+	}
+	return strings.TrimSuffix(word, "e")
+}
+
+func hasVowel(s string) bool {
+	return strings.ContainsAny(s, "aeiouy")
+}
+
+// stemMerge records the keywords dropped in favour of one sharing its stem.
+type stemMerge struct {
+	Kept    string
+	Dropped []string
+}
+
+// stemKeywords keeps the first keyword of each stem. Keywords in keep are
+// always kept and never absorb others.
+func stemKeywords(keywords []string, keep map[string]bool) ([]string, []stemMerge) {
+	var kept []string
+	var merges []stemMerge
+	byStem := map[string]int{}
+	for _, keyword := range keywords {
+		if keep[strings.ToLower(keyword)] {
+			kept = append(kept, keyword)
+			continue
+		}
+		root := stem(strings.ToLower(keyword))
+		if i, ok := byStem[root]; ok {
+			merges[i].Dropped = append(merges[i].Dropped, keyword)
+			continue
+		}
+		byStem[root] = len(merges)
+		merges = append(merges, stemMerge{Kept: keyword})
+		kept = append(kept, keyword)
+	}
+
+	n := 0
+	for _, merge := range merges {
+		if len(merge.Dropped) > 0 {
+			merges[n] = merge
+			n++
+		}
+	}
+	return kept, merges[:n]
+}
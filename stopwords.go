@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+)
+
+// defaultStopwords are the English function words -strip-stopwords removes
+// from keyword lists. Words such as "my" or "get" are left out on purpose:
+// they make good names, and belong in -prefixes when wanted.
+var defaultStopwords = []string{
+	"a", "an", "the",
+	"and", "or", "but", "nor",
+	"of", "to", "in", "on", "at", "by", "for", "with", "from", "into", "onto", "as",
+	"is", "are", "was", "were", "be", "been",
+	"it", "its", "this", "that", "these", "those",
+}
+
+func stopwordSet(words []string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, word := range words {
+		set[strings.ToLower(word)] = true
+	}
+	return set
+}
+
+// readStopwordsFile reads one stopword per line; text after # is a
+// comment.
+func readStopwordsFile(path string) ([]string, error) {
+	file, err := openInput(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading stopwords file: %w", err)
+	}
+	defer file.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		words = append(words, strings.Fields(text)...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading stopwords file: %w", err)
+	}
+	return words, nil
+}
+
+// stripStopwords removes the stopwords from a keyword list. A list made of
+// stopwords only is returned unchanged with removed nil, since an empty
+// list would leave nothing to combine.
+func stripStopwords(keywords []string, stop map[string]bool) (kept, removed []string) {
+	for _, keyword := range keywords {
+		if stop[strings.ToLower(keyword)] {
+			removed = append(removed, keyword)
+		} else {
+			kept = append(kept, keyword)
+		}
+	}
+	if len(kept) == 0 {
+		return keywords, nil
+	}
+	return kept, removed
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestStripStopwords(t *testing.T) {
+	stop := stopwordSet(defaultStopwords)
+	for _, tt := range []struct {
+		keywords, kept, removed []string
+	}{
+		{[]string{"the", "cloud", "of", "stack"}, []string{"cloud", "stack"}, []string{"the", "of"}},
+		{[]string{"The", "Cloud", "AND"}, []string{"Cloud"}, []string{"The", "AND"}},
+		{[]string{"cloud", "stack"}, []string{"cloud", "stack"}, nil},
+		// Never an empty list.
+		{[]string{"the", "of"}, []string{"the", "of"}, nil},
+		// Only whole keywords are stopwords.
+		{[]string{"theory", "often", "android"}, []string{"theory", "often", "android"}, nil},
+	} {
+		kept, removed := stripStopwords(tt.keywords, stop)
+		if !reflect.DeepEqual(kept, tt.kept) || !reflect.DeepEqual(removed, tt.removed) {
+			t.Errorf("stripStopwords(%q) = %q, %q; want %q, %q", tt.keywords, kept, removed, tt.kept, tt.removed)
+		}
+	}
+}
+
+func TestReadStopwordsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stopwords.txt")
+	if err := os.WriteFile(path, []byte("# German\nder\r\ndie das  # articles\n\nUND\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	words, err := readStopwordsFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"der", "die", "das", "UND"}; !reflect.DeepEqual(words, want) {
+		t.Errorf("words = %q, want %q", words, want)
+	}
+	if stop := stopwordSet(words); !stop["und"] || stop["the"] {
+		t.Errorf("stopwordSet(%q) = %v", words, stop)
+	}
+}
+
+// TestStopwordsOrder runs keyword lists through the whole normalization:
+// invisible characters and TLDs are removed before stopwords are looked
+// up, stopwords are removed before stemming, and the prefix and suffix
+// words are never stripped.
+func TestStopwordsOrder(t *testing.T) {
+	stopwords := filepath.Join(t.TempDir(), "stopwords.txt")
+	if err := os.WriteFile(stopwords, []byte("cloud\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name    string
+		args    []string
+		domains []string
+		logged  []string
+	}{
+		{
+			name:    "cleaned first",
+			args:    []string{"-keywords=\u00a0the\u200b,cloud,stack"},
+			domains: []string{"cloudstack.io"},
+			logged:  []string{"Removed stopwords the (-strip-stopwords)"},
+		},
+		{
+			name:    "tld stripped first",
+			args:    []string{"-keywords=the.com,cloud,stack"},
+			domains: []string{"cloudstack.io"},
+			logged:  []string{"treated 'the.com' as 'the'", "Removed stopwords the"},
+		},
+		{
+			// inner stems to in, a stopword, but stopwords are matched
+			// on the keywords as given.
+			name:    "before stemming",
+			args:    []string{"-keywords=inner,in,cloud", "-stem"},
+			domains: []string{"innercloud.io"},
+			logged:  []string{"Removed stopwords in"},
+		},
+		{
+			name:    "stemmed after stripping",
+			args:    []string{"-keywords=the,runner,running,cloud", "-stem"},
+			domains: []string{"runnercloud.io"},
+			logged:  []string{"Removed stopwords the", "Merged running into runner (-stem)"},
+		},
+		{
+			name:    "only stopwords",
+			args:    []string{"-lists=the,a;cloud"},
+			domains: []string{"acloud.io", "thecloud.io"},
+			logged:  []string{"kept stopwords the, a"},
+		},
+		{
+			name:    "affixes kept",
+			args:    []string{"-keywords=the,cloud,stack", "-prefixes=the", "-suffixes=of"},
+			domains: []string{"cloudstack.io", "cloudstackof.io", "thecloudstack.io"},
+			logged:  []string{"Removed stopwords the"},
+		},
+		{
+			name:    "stopwords file",
+			args:    []string{"-keywords=the,cloud,stack", "-stopwords-file=" + stopwords},
+			domains: []string{"thestack.io"},
+			logged:  []string{"Removed stopwords cloud"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"-backend=fake", "-tlds=io", "-strip-stopwords"}, tt.args...)
+			code, logged, domains := runCaptured(t, args...)
+			if code != exitOK {
+				t.Fatalf("exit code = %d\n%s", code, logged)
+			}
+			if !slices.Equal(domains, tt.domains) {
+				t.Errorf("checked %q, want %q", domains, tt.domains)
+			}
+			for _, want := range tt.logged {
+				if !strings.Contains(logged, want) {
+					t.Errorf("stderr %q does not mention %q", logged, want)
+				}
+			}
+		})
+	}
+}
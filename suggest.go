@@ -0,0 +1,84 @@
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+var suggestPrefixes = []string{"get", "try", "use"}
+
+// suggestDomains builds fallback variants for taken domains: the same name on
+// alternative TLDs, with common prefixes, and hyphenated. Variants are taken
+// round-robin across the taken names so a small limit still covers all of
+// them, and anything already checked is skipped.
+func suggestDomains(taken []string, hyphenated map[string]string, checked map[string]bool, limit int) []string {
+	var perDomain [][]string
+	for _, domain := range taken {
+		label, tld, ok := strings.Cut(domain, ".")
+		if !ok {
+			continue
+		}
+
+		var variants []string
+		for _, alt := range tldPresets["suggest"] {
+			if alt != tld {
+				variants = append(variants, label+"."+alt)
+			}
+		}
+		for _, prefix := range suggestPrefixes {
+			variants = append(variants, prefix+label+"."+tld)
+		}
+		if h, ok := hyphenated[domain]; ok && h != domain {
+			variants = append(variants, h)
+		}
+		perDomain = append(perDomain, variants)
+	}
+
+	var suggestions []string
+	seen := map[string]bool{}
+	for i := 0; len(suggestions) < limit; i++ {
+		added := false
+		for _, variants := range perDomain {
+			if i >= len(variants) {
+				continue
+			}
+			added = true
+			v := variants[i]
+			if checked[v] || seen[v] {
+				continue
+			}
+			seen[v] = true
+			suggestions = append(suggestions, v)
+			if len(suggestions) == limit {
+				break
+			}
+		}
+		if !added {
+			break
+		}
+	}
+	return suggestions
+}
+
+// hyphenatedVariants maps each generated domain to the same combination
+// joined with a dash. Generation is deterministic, so both runs line up.
+// Several separators already produce their own variants and would not.
+func hyphenatedVariants(config Config) map[string]string {
+	if len(config.Separators) > 1 || slices.Contains(config.Separators, "-") || len(config.JoinTemplates) > 0 || len(config.Keywords) == 0 {
+		return nil
+	}
+	// Anagrams have no hyphenated form, and would shift the alignment.
+	config.Anagrams = false
+	plain := generateDomains(config)
+	config.Separators = []string{"-"}
+	dashed := generateDomains(config)
+
+	variants := make(map[string]string, len(plain))
+	for i := range plain {
+		if labelProblem(dashed[i].BaseName) != "" {
+			continue
+		}
+		variants[plain[i].FQDN] = dashed[i].FQDN
+	}
+	return variants
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// tldSyntax is what a registry accepts as a name under its TLD, beyond
+// the DNS rules every label follows.
+type tldSyntax struct {
+	MinLength int
+	// MaxLength counts the encoded xn-- form; zero means the DNS limit.
+	MaxLength int
+	// IDN says whether the registry takes internationalized names. With
+	// IDNChars set, only those characters may appear beyond letters,
+	// digits and hyphens.
+	IDN      bool
+	IDNChars string
+	// ReservedUpTo is the length up to which the registry keeps names for
+	// itself even though its syntax allows them.
+	ReservedUpTo int
+	// NoDigitStart refuses names starting with a digit.
+	NoDigitStart bool
+}
+
+// defaultTLDSyntax holds the rules of common registries whose names a
+// check would otherwise waste a query on. TLDs missing here only get the
+// DNS rules. The whois servers file can override any entry with a
+// "syntax" line.
+func defaultTLDSyntax() map[string]tldSyntax {
+	verisign := tldSyntax{MinLength: 1, IDN: true, ReservedUpTo: 1}
+	nominet := tldSyntax{MinLength: 3}
+	return map[string]tldSyntax{
+		"com":    verisign,
+		"net":    verisign,
+		"de":     {MinLength: 1, IDN: true},
+		"eu":     {MinLength: 2, IDN: true},
+		"nl":     {MinLength: 2},
+		"us":     {MinLength: 1},
+		"ca":     {MinLength: 2, IDN: true, IDNChars: "éëêèàâæçîïôœùûüÿ"},
+		"co.uk":  nominet,
+		"org.uk": nominet,
+		"me.uk":  nominet,
+	}
+}
+
+// parseTLDSyntax reads the rules of a "syntax" line of the whois servers
+// file, as key=value fields: min, max, idn (yes, no, or the characters
+// allowed), reserved and digit-start (yes or no).
+func parseTLDSyntax(fields []string) (tldSyntax, error) {
+	var syntax tldSyntax
+	syntax.IDN = true
+	for _, field := range fields {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok || value == "" {
+			return syntax, fmt.Errorf("%q is not a rule (use min=, max=, idn=, reserved= or digit-start=)", field)
+		}
+		switch strings.ToLower(key) {
+		case "min", "max", "reserved":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 || n > maxLabelLength {
+				return syntax, fmt.Errorf("%s must be a length from 0 to %d, got %q", key, maxLabelLength, value)
+			}
+			switch strings.ToLower(key) {
+			case "min":
+				syntax.MinLength = n
+			case "max":
+				syntax.MaxLength = n
+			default:
+				syntax.ReservedUpTo = n
+			}
+		case "idn":
+			switch strings.ToLower(value) {
+			case "yes":
+				syntax.IDN, syntax.IDNChars = true, ""
+			case "no":
+				syntax.IDN, syntax.IDNChars = false, ""
+			default:
+				syntax.IDN, syntax.IDNChars = true, strings.ToLower(value)
+			}
+		case "digit-start":
+			switch strings.ToLower(value) {
+			case "yes":
+				syntax.NoDigitStart = false
+			case "no":
+				syntax.NoDigitStart = true
+			default:
+				return syntax, fmt.Errorf("digit-start must be yes or no, got %q", value)
+			}
+		default:
+			return syntax, fmt.Errorf("unknown rule %q (use min, max, idn, reserved or digit-start)", key)
+		}
+	}
+	if syntax.MaxLength > 0 && syntax.MinLength > syntax.MaxLength {
+		return syntax, fmt.Errorf("min=%d is above max=%d", syntax.MinLength, syntax.MaxLength)
+	}
+	return syntax, nil
+}
+
+// problem returns the rule of the registry of tld that name breaks, or ""
+// when the registry would take it.
+func (s tldSyntax) problem(name, tld string) string {
+	length := utf8.RuneCountInString(name)
+	switch {
+	case length < s.MinLength:
+		return fmt.Sprintf("shorter than the .%s minimum of %d characters", tld, s.MinLength)
+	case length <= s.ReservedUpTo:
+		return fmt.Sprintf("%d-character .%s names are reserved by the registry", length, tld)
+	case s.NoDigitStart && name[0] >= '0' && name[0] <= '9':
+		return fmt.Sprintf(".%s names cannot start with a digit", tld)
+	}
+	ascii := name
+	if strings.IndexFunc(name, func(r rune) bool { return r > 0x7f }) >= 0 {
+		if !s.IDN {
+			return fmt.Sprintf("internationalized, which .%s does not take", tld)
+		}
+		if s.IDNChars != "" {
+			for _, r := range strings.ToLower(name) {
+				if r > 0x7f && !strings.ContainsRune(s.IDNChars, r) {
+					return fmt.Sprintf("contains characters .%s does not take", tld)
+				}
+			}
+		}
+		if encoded, err := idna.Registration.ToASCII(name); err == nil {
+			ascii = encoded
+		}
+	}
+	if s.MaxLength > 0 && len(ascii) > s.MaxLength {
+		return fmt.Sprintf("longer than the .%s maximum of %d characters", tld, s.MaxLength)
+	}
+	return ""
+}
+
+// syntaxSkip counts the names dropped for one registry rule.
+type syntaxSkip struct {
+	Rule  string
+	Count int
+}
+
+// dropRefusedNames removes the candidates the registry of their TLD would
+// refuse, and counts them by rule, most frequent first.
+func dropRefusedNames(candidates []Candidate, syntax map[string]tldSyntax) (kept []Candidate, dropped []InvalidName, skips []syntaxSkip) {
+	counts := map[string]int{}
+	for _, candidate := range candidates {
+		rules, ok := syntax[candidate.TLD]
+		if !ok {
+			kept = append(kept, candidate)
+			continue
+		}
+		if rule := rules.problem(candidate.BaseName, candidate.TLD); rule != "" {
+			dropped = append(dropped, InvalidName{Domain: candidate.FQDN, Rule: rule})
+			counts[rule]++
+			continue
+		}
+		kept = append(kept, candidate)
+	}
+	for rule, count := range counts {
+		skips = append(skips, syntaxSkip{Rule: rule, Count: count})
+	}
+	sort.Slice(skips, func(i, j int) bool {
+		if skips[i].Count != skips[j].Count {
+			return skips[i].Count > skips[j].Count
+		}
+		return skips[i].Rule < skips[j].Rule
+	})
+	return kept, dropped, skips
+}
+
+// formatSyntaxSkips describes the names skipped for registry rules, for the
+// banner.
+func formatSyntaxSkips(skips []syntaxSkip) string {
+	total := 0
+	parts := make([]string, len(skips))
+	for i, skip := range skips {
+		total += skip.Count
+		parts[i] = fmt.Sprintf("%s (%d)", skip.Rule, skip.Count)
+	}
+	return fmt.Sprintf("Skipped %d names the registries would refuse: %s", total, strings.Join(parts, "; "))
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTLDSyntax(t *testing.T) {
+	syntax := defaultTLDSyntax()
+	for _, tt := range []struct {
+		name, tld string
+		// want is a part of the rule broken, or "" when the name is kept.
+		want string
+	}{
+		// DENIC takes single characters and digits-only names.
+		{"x", "de", ""},
+		{"1", "de", ""},
+		{"müller", "de", ""},
+		// Verisign's syntax allows one character, but those names are
+		// reserved.
+		{"x", "com", "1-character .com names are reserved"},
+		{"xy", "com", ""},
+		{"café", "com", ""},
+		{"x", "net", "1-character .net names are reserved"},
+		// Nominet wants at least three characters under its second levels.
+		{"x", "co.uk", "shorter than the .co.uk minimum of 3"},
+		{"ab", "co.uk", "shorter than the .co.uk minimum of 3"},
+		{"abc", "co.uk", ""},
+		{"ab", "org.uk", "shorter than the .org.uk minimum of 3"},
+		{"x", "eu", "shorter than the .eu minimum of 2"},
+		{"café", "nl", "internationalized, which .nl does not take"},
+		{"été", "ca", ""},
+		{"straße", "ca", "contains characters .ca does not take"},
+	} {
+		rules, ok := syntax[tt.tld]
+		if !ok {
+			t.Fatalf("no built-in rules for .%s", tt.tld)
+		}
+		got := rules.problem(tt.name, tt.tld)
+		if tt.want == "" && got != "" || tt.want != "" && !strings.Contains(got, tt.want) {
+			t.Errorf("%s.%s: problem = %q, want %q", tt.name, tt.tld, got, tt.want)
+		}
+	}
+}
+
+// TestTLDSyntaxOverride checks that "syntax" lines of the whois servers
+// file replace the built-in rules of their TLD and add rules for others.
+func TestTLDSyntaxOverride(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "servers.txt")
+	servers := strings.Join([]string{
+		"# registries that changed their minds",
+		"syntax  co.uk  min=2 idn=no",
+		"syntax  .com   min=1 reserved=0",
+		"syntax  io     min=3 max=10 digit-start=no",
+		"de  whois.denic.de",
+	}, "\n")
+	if err := os.WriteFile(path, []byte(servers), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	whoisFlags := addWhoisFlags(fs)
+	if err := fs.Parse([]string{"-whois-servers=" + path}); err != nil {
+		t.Fatal(err)
+	}
+	checker, err := whoisFlags.Checker()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var candidates []Candidate
+	for _, domain := range []string{"x.com", "ab.co.uk", "x.co.uk", "café.co.uk", "x.de", "ab.io", "1abc.io", "abcdefghijk.io", "abcd.io", "x.eu"} {
+		name, tld, _ := strings.Cut(domain, ".")
+		candidates = append(candidates, Candidate{FQDN: domain, BaseName: name, TLD: tld})
+	}
+	kept, dropped, skips := dropRefusedNames(candidates, checker.Syntax)
+
+	if want := []string{"x.com", "ab.co.uk", "x.de", "abcd.io"}; !reflect.DeepEqual(candidateDomains(kept), want) {
+		t.Errorf("kept %v, want %v", candidateDomains(kept), want)
+	}
+	rules := map[string]string{}
+	for _, d := range dropped {
+		rules[d.Domain] = d.Rule
+	}
+	for domain, want := range map[string]string{
+		"x.co.uk":        "shorter than the .co.uk minimum of 2",
+		"café.co.uk":     "internationalized, which .co.uk does not take",
+		"ab.io":          "shorter than the .io minimum of 3",
+		"1abc.io":        ".io names cannot start with a digit",
+		"abcdefghijk.io": "longer than the .io maximum of 10",
+		"x.eu":           "shorter than the .eu minimum of 2",
+	} {
+		if !strings.HasPrefix(rules[domain], want) {
+			t.Errorf("%s: rule = %q, want %q", domain, rules[domain], want)
+		}
+	}
+	total := 0
+	for _, skip := range skips {
+		total += skip.Count
+	}
+	if total != len(dropped) {
+		t.Errorf("skips count %d names, %d were dropped", total, len(dropped))
+	}
+}
+
+func TestParseTLDSyntaxErrors(t *testing.T) {
+	for _, fields := range [][]string{
+		{"min"},
+		{"min=x"},
+		{"max=64"},
+		{"min=5", "max=3"},
+		{"digit-start=maybe"},
+		{"colour=blue"},
+	} {
+		if _, err := parseTLDSyntax(fields); err == nil {
+			t.Errorf("parseTLDSyntax(%q) accepted the rules", fields)
+		}
+	}
+}
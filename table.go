@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+type tableColumn struct {
+	Header string
+	// Width is the maximum number of characters shown without -wide.
+	Width int
+	Value func(DomainResult) string
+}
+
+var tableColumns = map[string]tableColumn{
+	"domain": {"DOMAIN", 40, func(r DomainResult) string { return r.Domain }},
+	"status": {"STATUS", 12, func(r DomainResult) string { return string(r.Status) }},
+	"method": {"METHOD", 8, func(r DomainResult) string { return r.Method }},
+	"latency": {"LATENCY", 10, func(r DomainResult) string {
+		if r.Duration == 0 {
+			return ""
+		}
+		return r.Duration.Round(time.Millisecond).String()
+	}},
+	"registrar": {"REGISTRAR", 28, func(r DomainResult) string { return r.Registrar }},
+	"created":   {"CREATED", 10, func(r DomainResult) string { return formatDate(r.CreatedAt) }},
+	"expires":   {"EXPIRES", 10, func(r DomainResult) string { return formatDate(r.ExpiresAt) }},
+	"server":    {"SERVER", 28, serverPath},
+	"epp":       {"EPP STATUS", 32, func(r DomainResult) string { return strings.Join(r.EPPStatus, " ") }},
+	"score": {"SCORE", 6, func(r DomainResult) string {
+		if r.Score == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%.1f", r.Score)
+	}},
+	"price": {"PRICE", 28, func(r DomainResult) string {
+		if r.Price == nil {
+			return ""
+		}
+		return r.Price.String()
+	}},
+	"error": {"ERROR", 48, func(r DomainResult) string {
+		if r.Error == nil {
+			return ""
+		}
+		return r.Error.Error()
+	}},
+}
+
+var defaultTableColumns = []string{"domain", "status", "method", "latency", "registrar", "expires"}
+
+func tableColumnNames() string {
+	return "domain, status, method, latency, registrar, created, expires, server, epp, score, price, error"
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format("2006-01-02")
+}
+
+// truncate shortens s to width characters, marking the cut with an ellipsis.
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	return string(runes[:width-ellipsisWidth()]) + sym.Ellipsis
+}
+
+// writeTable prints one aligned row per domain, available domains first.
+func writeTable(w io.Writer, report *Report, opts RenderOptions) error {
+	columns := opts.Columns
+	if len(columns) == 0 {
+		columns = defaultTableColumns
+	}
+
+	var rows []DomainResult
+	if opts.showSection(StatusAvailable) {
+		rows = rankedAvailable(report.Results, opts)
+	}
+	for _, result := range report.Results {
+		if result.Status != StatusAvailable && opts.showSection(result.Status) {
+			rows = append(rows, result)
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	headers := make([]string, len(columns))
+	for i, name := range columns {
+		headers[i] = tableColumns[name].Header
+	}
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+
+	cells := make([]string, len(columns))
+	for _, result := range rows {
+		for i, name := range columns {
+			column := tableColumns[name]
+			value := strings.ReplaceAll(column.Value(result), "\t", " ")
+			if !opts.Wide {
+				value = truncate(value, column.Width)
+			}
+			cells[i] = value
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if opts.NoSummary {
+		return nil
+	}
+	_, err := fmt.Fprintf(w, "\nSummary: %s\n", summarize(report.Results))
+	return err
+}
@@ -0,0 +1,14 @@
+#!/bin/sh
+# Test classifier for classifier_test.go: the domain picks the behaviour,
+# otherwise the reply decides.
+case "$1" in
+slow.*) sleep 5 ;;
+fail.*) echo "registry unreachable" >&2; exit 3 ;;
+junk.*) echo "maybe"; exit 0 ;;
+pause.*) sleep 0.2 ;;
+esac
+if grep -q "NOT FOUND" ; then
+	echo available
+else
+	echo "  TAKEN"
+fi
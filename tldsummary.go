@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// TLDSummary counts the results of one TLD. The field names are a stable
+// interface for dashboards tracking how crowded each TLD is.
+type TLDSummary struct {
+	TLD             string `json:"tld"`
+	Available       int    `json:"available"`
+	Taken           int    `json:"taken"`
+	Unknown         int    `json:"unknown"`
+	Reserved        int    `json:"reserved"`
+	Errors          int    `json:"errors"`
+	Deferred        int    `json:"deferred,omitempty"`
+	MedianLatencyMs int64  `json:"medianLatencyMs"`
+}
+
+// summarizeTLDs returns one summary per TLD, sorted by TLD. The median
+// latency only counts checks made in this run, not cached answers.
+func summarizeTLDs(results []DomainResult) []TLDSummary {
+	byTLD := map[string]*TLDSummary{}
+	latencies := map[string][]time.Duration{}
+	for _, result := range results {
+		if result.Status == StatusExists || result.Status == StatusAbsent {
+			continue
+		}
+		tld := domainTLD(result.Domain)
+		s, ok := byTLD[tld]
+		if !ok {
+			s = &TLDSummary{TLD: tld}
+			byTLD[tld] = s
+		}
+		switch result.Status {
+		case StatusAvailable:
+			s.Available++
+		case StatusTaken:
+			s.Taken++
+		case StatusUnknown:
+			s.Unknown++
+		case StatusReserved:
+			s.Reserved++
+		case StatusError:
+			s.Errors++
+		case StatusDeferred:
+			s.Deferred++
+		}
+		if !result.Cached && result.Duration > 0 {
+			latencies[tld] = append(latencies[tld], result.Duration)
+		}
+	}
+
+	summaries := make([]TLDSummary, 0, len(byTLD))
+	for tld, s := range byTLD {
+		if l := latencies[tld]; len(l) > 0 {
+			sort.Slice(l, func(i, j int) bool { return l[i] < l[j] })
+			s.MedianLatencyMs = percentile(l, 0.5).Milliseconds()
+		}
+		summaries = append(summaries, *s)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].TLD < summaries[j].TLD })
+	return summaries
+}
+
+// tldLine is the one-line text form with the available share of each TLD,
+// e.g. ".com 2/10, .io 7/10".
+func tldLine(summaries []TLDSummary) string {
+	parts := make([]string, len(summaries))
+	for i, s := range summaries {
+		parts[i] = fmt.Sprintf(".%s %d/%d", s.TLD, s.Available, s.Available+s.Taken+s.Unknown+s.Reserved+s.Errors+s.Deferred)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func writeTLDSummaryTable(w io.Writer, summaries []TLDSummary) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TLD\tAVAILABLE\tTAKEN\tUNKNOWN\tRESERVED\tERRORS\tMEDIAN LATENCY")
+	for _, s := range summaries {
+		fmt.Fprintf(tw, ".%s\t%d\t%d\t%d\t%d\t%d\t%dms\n", s.TLD, s.Available, s.Taken, s.Unknown, s.Reserved, s.Errors, s.MedianLatencyMs)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// translation is a word a keyword stands for in another language.
+type translation struct {
+	Word     string
+	Language string
+}
+
+// readTranslations reads a translations file for -translations. Each line
+// maps a keyword to its translations, each tagged with its language:
+//
+//	fast  = de:schnell, es:rápido, es:veloz
+//	cloud = de:wolke, es:nube   # text after # is a comment
+//
+// Keywords are matched case-insensitively. The file is written by people;
+// nothing is translated by the tool itself.
+func readTranslations(path string) (map[string][]translation, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading translations file: %w", err)
+	}
+	defer file.Close()
+
+	translations := map[string][]translation{}
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		keyword, list, ok := strings.Cut(text, "=")
+		keyword = strings.ToLower(strings.TrimSpace(keyword))
+		if !ok || keyword == "" || strings.ContainsAny(keyword, " \t") {
+			return nil, fmt.Errorf("translations file %s line %d: want a keyword, = and its translations", path, line)
+		}
+		if _, seen := translations[keyword]; seen {
+			return nil, fmt.Errorf("translations file %s line %d: %s is listed twice", path, line, keyword)
+		}
+		for _, item := range parseKeywords(list) {
+			language, word, ok := strings.Cut(item, ":")
+			language, word = strings.ToLower(strings.TrimSpace(language)), strings.TrimSpace(word)
+			if !ok || language == "" || word == "" {
+				return nil, fmt.Errorf("translations file %s line %d: %q is not tagged with its language (use language:word, e.g. de:schnell)", path, line, item)
+			}
+			translations[keyword] = append(translations[keyword], translation{Word: word, Language: language})
+		}
+		if len(translations[keyword]) == 0 {
+			return nil, fmt.Errorf("translations file %s line %d: %s has no translations", path, line, keyword)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading translations file: %w", err)
+	}
+	return translations, nil
+}
+
+// translated records what a keyword added by -translations stands for.
+type translated struct {
+	Keyword  string
+	Language string
+}
+
+// translationLanguages returns the languages the translations are tagged
+// with.
+func translationLanguages(translations map[string][]translation) map[string]bool {
+	languages := map[string]bool{}
+	for _, list := range translations {
+		for _, t := range list {
+			languages[t.Language] = true
+		}
+	}
+	return languages
+}
+
+// expandTranslations adds the translations of each keyword in languages
+// right after it. A translation already in the list is not added again.
+// added maps every word added to the keyword it translates.
+func expandTranslations(keywords []string, translations map[string][]translation, languages map[string]bool) (expanded []string, added map[string]translated) {
+	seen := map[string]bool{}
+	for _, keyword := range keywords {
+		seen[strings.ToLower(keyword)] = true
+	}
+	added = map[string]translated{}
+	for _, keyword := range keywords {
+		expanded = append(expanded, keyword)
+		for _, t := range translations[strings.ToLower(keyword)] {
+			if !languages[t.Language] || seen[strings.ToLower(t.Word)] {
+				continue
+			}
+			seen[strings.ToLower(t.Word)] = true
+			expanded = append(expanded, t.Word)
+			added[t.Word] = translated{Keyword: keyword, Language: t.Language}
+		}
+	}
+	return expanded, added
+}
+
+// nameLanguage returns the languages of the translated keywords of a name,
+// joined with + in keyword order, or "" when it has none.
+func nameLanguage(keywords []string, translations map[string]translated) string {
+	var tags []string
+	for _, keyword := range keywords {
+		t, ok := translations[keyword]
+		if !ok || containsKey(tags, t.Language) {
+			continue
+		}
+		tags = append(tags, t.Language)
+	}
+	return strings.Join(tags, "+")
+}
+
+// repeatsMeaning reports whether keywords hold a keyword together with one
+// of its translations, or two translations of the same keyword, which
+// would say the same thing twice.
+func repeatsMeaning(keywords []string, translations map[string]translated) bool {
+	seen := map[string]bool{}
+	for _, keyword := range keywords {
+		meaning := strings.ToLower(keyword)
+		if t, ok := translations[keyword]; ok {
+			meaning = strings.ToLower(t.Keyword)
+		}
+		if seen[meaning] {
+			return true
+		}
+		seen[meaning] = true
+	}
+	return false
+}
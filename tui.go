@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/term"
+)
+
+var tuiSortColumns = []string{"domain", "status", "latency", "tld"}
+
+type tuiModel struct {
+	out           io.Writer
+	results       []DomainResult
+	total         int
+	done          bool
+	availableOnly bool
+	sortColumn    int
+	selected      int
+	offset        int
+	message       string
+}
+
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// runTUI shows a live table of results as they arrive. Quitting early cancels
+// the remaining checks; the results collected so far are returned. The
+// terminal is restored however it returns, a SIGTERM included. The table is
+// drawn on out, the run's stdout.
+func runTUI(ctx context.Context, out io.Writer, domains []Candidate, pacing Pacing, check checkFunc) ([]DomainResult, error) {
+	stdin := int(os.Stdin.Fd())
+	oldState, err := term.MakeRaw(stdin)
+	if err != nil {
+		return nil, fmt.Errorf("starting TUI: %w", err)
+	}
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l")
+	defer func() {
+		fmt.Fprint(out, "\x1b[?25h\x1b[?1049l")
+		term.Restore(stdin, oldState)
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	ctx, cancel := context.WithCancel(ctx)
+	results := checkDomainsStream(ctx, domains, pacing, check)
+	keys := make(chan string)
+	readerDone := make(chan struct{})
+	go func() {
+		defer close(readerDone)
+		readKeys(ctx, keys)
+	}()
+	// The key reader must have stopped reading stdin before the terminal
+	// leaves raw mode.
+	defer func() {
+		cancel()
+		<-readerDone
+	}()
+
+	m := &tuiModel{out: out, total: len(domains)}
+	m.draw()
+
+	for {
+		select {
+		case result, ok := <-results:
+			if !ok {
+				results = nil
+				m.done = true
+				m.message = "All checks finished " + sym.Dash + " press q to exit"
+			} else {
+				m.results = append(m.results, result)
+			}
+		case key := <-keys:
+			if key == "q" || key == "\x03" || key == "\x1b" {
+				return m.results, nil
+			}
+			m.handleKey(key)
+		case <-signals:
+			return m.results, nil
+		}
+		m.draw()
+	}
+}
+
+// readKeys sends the keys pressed until ctx is done. It waits for input in
+// short polls rather than blocking in Read, so that it returns, and leaves
+// stdin alone, once the TUI has quit.
+func readKeys(ctx context.Context, keys chan<- string) {
+	buf := make([]byte, 8)
+	for ctx.Err() == nil {
+		if !waitForInput(os.Stdin, 100*time.Millisecond) {
+			continue
+		}
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return
+		}
+		select {
+		case keys <- string(buf[:n]):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (m *tuiModel) handleKey(key string) {
+	rows := m.visibleRows()
+	switch key {
+	case "a":
+		m.availableOnly = !m.availableOnly
+		m.selected, m.offset = 0, 0
+	case "s":
+		m.sortColumn = (m.sortColumn + 1) % len(tuiSortColumns)
+	case "j", "\x1b[B":
+		if m.selected < len(rows)-1 {
+			m.selected++
+		}
+	case "k", "\x1b[A":
+		if m.selected > 0 {
+			m.selected--
+		}
+	case "c":
+		if m.selected < len(rows) {
+			domain := rows[m.selected].Domain
+			if err := copyToClipboard(domain); err != nil {
+				m.message = "Copy failed: " + err.Error()
+			} else {
+				m.message = "Copied " + domain
+			}
+		}
+	}
+}
+
+func (m *tuiModel) visibleRows() []DomainResult {
+	var rows []DomainResult
+	for _, result := range m.results {
+		if m.availableOnly && result.Status != StatusAvailable {
+			continue
+		}
+		rows = append(rows, result)
+	}
+
+	column := tuiSortColumns[m.sortColumn]
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		switch column {
+		case "status":
+			if a.Status != b.Status {
+				return a.Status < b.Status
+			}
+		case "latency":
+			if a.Duration != b.Duration {
+				return a.Duration < b.Duration
+			}
+		case "tld":
+			if ta, tb := domainTLD(a.Domain), domainTLD(b.Domain); ta != tb {
+				return ta < tb
+			}
+		}
+		return a.Domain < b.Domain
+	})
+	return rows
+}
+
+func domainTLD(domain string) string {
+	if i := strings.LastIndex(domain, "."); i >= 0 {
+		return domain[i+1:]
+	}
+	return ""
+}
+
+func (m *tuiModel) draw() {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		width, height = 80, 24
+	}
+
+	rows := m.visibleRows()
+	if m.selected >= len(rows) {
+		m.selected = max(len(rows)-1, 0)
+	}
+	pageSize := max(height-5, 1)
+	if m.selected < m.offset {
+		m.offset = m.selected
+	}
+	if m.selected >= m.offset+pageSize {
+		m.offset = m.selected - pageSize + 1
+	}
+
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+
+	state := "checking"
+	if m.done {
+		state = "done"
+	}
+	filter := "all"
+	if m.availableOnly {
+		filter = "available"
+	}
+	header := fmt.Sprintf("Domain Checker %s %d/%d checked (%s)  filter: %s  sort: %s",
+		sym.Dash, len(m.results), m.total, state, filter, tuiSortColumns[m.sortColumn])
+	b.WriteString(fitWidth(header, width) + "\r\n\r\n")
+	b.WriteString(fitWidth(fmt.Sprintf("  %-40s %-10s %9s  %s", "DOMAIN", "STATUS", "LATENCY", "TLD"), width) + "\r\n")
+
+	for i := m.offset; i < len(rows) && i < m.offset+pageSize; i++ {
+		r := rows[i]
+		line := fmt.Sprintf("  %-40s %-10s %9s  %s", r.Domain, r.Status, r.Duration.Round(time.Millisecond), domainTLD(r.Domain))
+		line = fitWidth(line, width)
+		if i == m.selected {
+			line = "\x1b[7m" + line + "\x1b[0m"
+		}
+		b.WriteString(line + "\r\n")
+	}
+
+	b.WriteString(fmt.Sprintf("\x1b[%d;1H", height))
+	footer := "[" + sym.UpDown + "] select  [a] available only  [s] sort  [c] copy  [q] quit"
+	if m.message != "" {
+		footer = m.message + "  " + footer
+	}
+	b.WriteString(fitWidth(footer, width))
+
+	io.WriteString(m.out, b.String())
+}
+
+func fitWidth(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) > width {
+		return string(runes[:width])
+	}
+	return s
+}
@@ -0,0 +1,14 @@
+//go:build !unix && !windows
+
+package main
+
+import (
+	"os"
+	"time"
+)
+
+// waitForInput cannot wait with a timeout here; the read that follows
+// blocks until a key is pressed.
+func waitForInput(*os.File, time.Duration) bool {
+	return true
+}
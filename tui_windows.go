@@ -0,0 +1,17 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// waitForInput reports whether the console f has input to read within
+// timeout. Errors report true, for the read to surface them.
+func waitForInput(f *os.File, timeout time.Duration) bool {
+	event, err := windows.WaitForSingleObject(windows.Handle(f.Fd()), uint32(timeout/time.Millisecond))
+	return err != nil || event == windows.WAIT_OBJECT_0
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// patternRule is a user rule from -patterns-file: a reply of the TLD that
+// matches any of the regexes has the rule's status.
+type patternRule struct {
+	TLD     string
+	Regexes []*regexp.Regexp
+	Status  Status
+	// Line is where the rule starts in the file, for messages.
+	Line int
+}
+
+// appliesTo reports whether the rule covers the TLD; "*" covers all.
+func (r patternRule) appliesTo(tld string) bool {
+	return r.TLD == "*" || r.TLD == tld
+}
+
+// match returns the first regex matching reply.
+func (r patternRule) match(reply string) (*regexp.Regexp, bool) {
+	for _, re := range r.Regexes {
+		if re.MatchString(reply) {
+			return re, true
+		}
+	}
+	return nil, false
+}
+
+// readPatternRules parses a rules file:
+//
+//   - tld: de
+//     patterns: ['(?i)status:\s*free']
+//     status: available
+func readPatternRules(path string) ([]patternRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading patterns file: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing patterns file %s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	list := doc.Content[0]
+	if list.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("%s:%d: expected a list of rules", path, list.Line)
+	}
+
+	rules := make([]patternRule, 0, len(list.Content))
+	for _, node := range list.Content {
+		var raw struct {
+			TLD      string   `yaml:"tld"`
+			Patterns []string `yaml:"patterns"`
+			Status   string   `yaml:"status"`
+		}
+		if err := node.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, node.Line, err)
+		}
+
+		rule := patternRule{TLD: strings.ToLower(strings.TrimPrefix(raw.TLD, ".")), Status: Status(raw.Status), Line: node.Line}
+		switch {
+		case rule.TLD == "":
+			return nil, fmt.Errorf("%s:%d: rule has no tld (use \"*\" for all)", path, node.Line)
+		case len(raw.Patterns) == 0:
+			return nil, fmt.Errorf("%s:%d: rule has no patterns", path, node.Line)
+		}
+		switch rule.Status {
+		case StatusAvailable, StatusTaken, StatusReserved, StatusUnknown, StatusDeferred:
+		default:
+			return nil, fmt.Errorf("%s:%d: unknown status %q (use available, taken, reserved, unknown or deferred)", path, node.Line, raw.Status)
+		}
+		for _, pattern := range raw.Patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, node.Line, err)
+			}
+			rule.Regexes = append(rule.Regexes, re)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// matchPatternRules returns the first rule of the TLD matching reply and
+// the regex that matched.
+func matchPatternRules(rules []patternRule, tld, reply string) (*patternRule, *regexp.Regexp) {
+	for i := range rules {
+		if !rules[i].appliesTo(tld) {
+			continue
+		}
+		if re, ok := rules[i].match(reply); ok {
+			return &rules[i], re
+		}
+	}
+	return nil, nil
+}
+
+func runTestPatterns(args []string) int {
+	fs := flag.NewFlagSet("test-patterns", flag.ExitOnError)
+	patternsFile := fs.String("patterns-file", "", "Rules file to test (required)")
+	tld := fs.String("tld", "", "TLD of the reply (default: from a DOMAIN.txt file name as written by -save-whois)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage:\n")
+		fmt.Fprintf(os.Stderr, "  %s test-patterns -patterns-file=rules.yaml <whois-reply-file>...\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Prints the rule each saved whois reply matches, or what the built-in patterns make of it.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+	positional := parseInterspersed(fs, args)
+
+	if *patternsFile == "" || len(positional) == 0 {
+		fs.Usage()
+		return 1
+	}
+	rules, err := readPatternRules(*patternsFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	for _, path := range positional {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		replyTLD := strings.TrimPrefix(strings.ToLower(*tld), ".")
+		if replyTLD == "" {
+			name := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), ".txt"), ".raw")
+			if !strings.Contains(name, ".") {
+				fmt.Fprintf(os.Stderr, "Error: cannot tell the TLD of %s, use -tld\n", path)
+				return 1
+			}
+			replyTLD = domainTLD(name)
+		}
+
+		reply := decodeWhois(replyTLD, string(data))
+		if rule, re := matchPatternRules(rules, replyTLD, reply); rule != nil {
+			fmt.Printf("%s: %s (rule at line %d, tld %s, pattern %s)\n", path, rule.Status, rule.Line, rule.TLD, re)
+		} else {
+			fmt.Printf("%s: no rule matched; built-in patterns say %s\n", path, classifyWhois(replyTLD, strings.ToLower(reply)))
+		}
+	}
+	return 0
+}
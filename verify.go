@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// VerifyConflict is an available verdict DNS contradicts: the whois reply
+// looked unregistered, yet the domain is delegated or resolves. It usually
+// means a reply pattern is wrong for the TLD, or the registry hides the
+// registration.
+type VerifyConflict struct {
+	Domain string `json:"domain"`
+	// Whois says where the available verdict came from, and DNS what was
+	// found instead.
+	Whois string `json:"whois"`
+	DNS   string `json:"dns"`
+}
+
+// verifyAvailable looks up every available domain in DNS, for
+// -verify-available=dns. A domain with name servers, or whose name exists,
+// is registered whatever whois said: it is downgraded to unknown with both
+// pieces of evidence. A failed lookup leaves the verdict alone.
+func verifyAvailable(results []DomainResult) []VerifyConflict {
+	found := make([]*VerifyConflict, len(results))
+	var wg sync.WaitGroup
+	for i := range results {
+		if results[i].Status != StatusAvailable {
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			result := &results[i]
+			start := time.Now()
+			evidence, err := dnsEvidence(result.Domain)
+			attempt := newAttempt("dns-verify", resolver.describe(), start, StatusAvailable, err)
+			if evidence == "" {
+				result.Attempts = append(result.Attempts, attempt)
+				return
+			}
+
+			conflict := VerifyConflict{Domain: result.Domain, Whois: whoisEvidence(*result), DNS: evidence}
+			result.Status = StatusUnknown
+			result.Error = fmt.Errorf("%w: %s said available, but %s", errVerifyConflict, conflict.Whois, evidence)
+			attempt.Outcome, attempt.Rule = string(StatusUnknown), evidence
+			result.Attempts = append(result.Attempts, attempt)
+			found[i] = &conflict
+		}(i)
+	}
+	wg.Wait()
+
+	var conflicts []VerifyConflict
+	for _, conflict := range found {
+		if conflict != nil {
+			conflicts = append(conflicts, *conflict)
+		}
+	}
+	return conflicts
+}
+
+// dnsEvidence returns what shows domain is registered: its delegation, or
+// failing that its name existing; "" when DNS knows nothing of it.
+func dnsEvidence(domain string) (string, error) {
+	delegated, err := hasNameServers(domain)
+	if err != nil {
+		return "", err
+	}
+	if delegated {
+		return "it is delegated to name servers", nil
+	}
+
+	release, err := connections.acquire(context.Background(), connDNS)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	exists, err := resolver.Exists(ctx, domain)
+	if err != nil || !exists {
+		return "", err
+	}
+	return "the name exists in DNS", nil
+}
+
+// whoisEvidence names the source of an available verdict.
+func whoisEvidence(result DomainResult) string {
+	switch {
+	case result.Cached:
+		return "the cached verdict"
+	case result.Server != "":
+		return result.Server
+	case result.Method != "":
+		return result.Method
+	}
+	return "the check"
+}
+
+func writeConflicts(w io.Writer, conflicts []VerifyConflict) {
+	fmt.Fprintf(w, "%s WHOIS/DNS CONFLICTS (%d), reported as unknown:\n", sym.Warning, len(conflicts))
+	for _, c := range conflicts {
+		fmt.Fprintf(w, "  %s: %s said available, but %s\n", c.Domain, c.Whois, c.DNS)
+	}
+	fmt.Fprintln(w, "  Whois replies misread as available; worth reporting with the reply saved by -save-whois")
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,797 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// version is set at build time with -ldflags "-X main.version=...".
+var version = "dev"
+
+// userAgent identifies the tool to RDAP and other HTTP services. -contact
+// appends an email address registries can use to reach bulk users.
+var userAgent = buildUserAgent("")
+
+func buildUserAgent(contact string) string {
+	v := version
+	if v == "dev" {
+		if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+			v = info.Main.Version
+		}
+	}
+	ua := fmt.Sprintf("domain-checker/%s (+https://github.com/botsman/domain-checker", v)
+	if contact != "" {
+		ua += "; " + contact
+	}
+	return ua + ")"
+}
+
+// whoisServer says where and how to query the registry of a TLD. Query is a
+// template in which {domain} is replaced by the domain being checked.
+type whoisServer struct {
+	Host  string
+	Query string
+}
+
+func (s whoisServer) query(domain string) string {
+	if s.Query == "" {
+		return domain
+	}
+	return strings.ReplaceAll(s.Query, "{domain}", domain)
+}
+
+// defaultWhoisServers are registries whose bare queries also match unrelated
+// objects. Verisign returns name server matches for a plain "example.com"
+// unless the query is restricted to domain records.
+func defaultWhoisServers() map[string][]whoisServer {
+	verisign := []whoisServer{{Host: "whois.verisign-grs.com", Query: "domain {domain}"}}
+	return map[string][]whoisServer{
+		"com": verisign,
+		"net": verisign,
+	}
+}
+
+// readWhoisServers parses a server mapping file. Each line holds a TLD, a
+// comma-separated list of whois servers tried in order, and an optional
+// query template, e.g.
+//
+//	de  whois.denic.de  -T dn,ace {domain}
+//
+// Lines starting with "maintenance" give the maintenance windows of a
+// server instead, replacing its defaults; "none" clears them:
+//
+//	maintenance  whois.denic.de  02:00-02:30 Europe/Berlin
+//	maintenance  whois.nic.example  0-14 3 * * 0 UTC
+//
+// Lines starting with "syntax" give the names the registry of a TLD
+// accepts, replacing its built-in rules:
+//
+//	syntax  co.uk  min=3 idn=no
+//	syntax  ca     min=2 idn=éèàç
+func readWhoisServers(path string) (map[string][]whoisServer, map[string][]maintenanceWindow, map[string]tldSyntax, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("reading whois servers file: %w", err)
+	}
+	defer file.Close()
+
+	servers := map[string][]whoisServer{}
+	windows := map[string][]maintenanceWindow{}
+	syntax := map[string]tldSyntax{}
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if strings.EqualFold(fields[0], "syntax") {
+			if len(fields) < 3 {
+				return nil, nil, nil, fmt.Errorf("whois servers file %s line %d: expected 'syntax tld rule=value...'", path, line)
+			}
+			rules, err := parseTLDSyntax(fields[2:])
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("whois servers file %s line %d: %w", path, line, err)
+			}
+			syntax[strings.ToLower(strings.TrimPrefix(fields[1], "."))] = rules
+			continue
+		}
+		if strings.EqualFold(fields[0], "maintenance") {
+			if len(fields) < 3 {
+				return nil, nil, nil, fmt.Errorf("whois servers file %s line %d: expected 'maintenance server window'", path, line)
+			}
+			host := strings.ToLower(fields[1])
+			if _, ok := windows[host]; !ok {
+				windows[host] = []maintenanceWindow{}
+			}
+			if len(fields) == 3 && strings.EqualFold(fields[2], "none") {
+				continue
+			}
+			w, err := parseMaintenanceWindow(strings.Join(fields[2:], " "))
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("whois servers file %s line %d: %w", path, line, err)
+			}
+			windows[host] = append(windows[host], w)
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, nil, nil, fmt.Errorf("whois servers file %s line %d: expected 'tld server[,fallback...] [query]'", path, line)
+		}
+		query := ""
+		if len(fields) > 2 {
+			query = strings.Join(fields[2:], " ")
+			if !strings.Contains(query, "{domain}") {
+				return nil, nil, nil, fmt.Errorf("whois servers file %s line %d: query template must contain {domain}", path, line)
+			}
+		}
+		tld := strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(fields[0], "."), ":"))
+		for _, host := range strings.Split(fields[1], ",") {
+			if host == "" {
+				continue
+			}
+			host, err := parseWhoisHost(host)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("whois servers file %s line %d: %w", path, line, err)
+			}
+			servers[tld] = append(servers[tld], whoisServer{Host: host, Query: query})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, nil, fmt.Errorf("reading whois servers file: %w", err)
+	}
+	return servers, windows, syntax, nil
+}
+
+// rawExcerptMaxLength bounds the excerpt of a reply kept with a failed or
+// unknown verdict, so huge replies do not bloat reports.
+const (
+	rawExcerptMaxLength = 500
+	rawExcerptMaxLines  = 10
+)
+
+// rawExcerpt returns the first lines of a reply, at most
+// rawExcerptMaxLength bytes of them, with control characters other than
+// newlines and tabs replaced so the excerpt is safe to print and store.
+func rawExcerpt(reply string) string {
+	var b strings.Builder
+	lines := strings.Split(strings.TrimSpace(strings.ReplaceAll(reply, "\r\n", "\n")), "\n")
+	for i, line := range lines {
+		if i == rawExcerptMaxLines {
+			break
+		}
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		for _, r := range strings.TrimRight(line, " \t\r") {
+			if unicode.IsControl(r) && r != '\t' {
+				r = '\uFFFD'
+			}
+			if b.Len()+utf8.RuneLen(r) > rawExcerptMaxLength {
+				return b.String() + "…"
+			}
+			b.WriteRune(r)
+		}
+	}
+	excerpt := b.String()
+	if len(lines) > rawExcerptMaxLines {
+		excerpt += "\n…"
+	}
+	return excerpt
+}
+
+// echoReplyMaxLength is the size below which a "taken" reply without any
+// registration data is not believed.
+const echoReplyMaxLength = 400
+
+// WhoisChecker checks domains over whois. The servers listed for a TLD are
+// tried in order, followed by the one IANA names for it; the next server is
+// only tried when the previous one failed in a way another server may not.
+type WhoisChecker struct {
+	Servers map[string][]whoisServer
+	// Suffixes are appended to queries sent to a server, keyed by host.
+	Suffixes map[string]string
+	Timeout  time.Duration
+	// ServerRate limits the queries per second sent to each server; zero
+	// means no limit.
+	ServerRate float64
+	// Classifier, when set, gets the first say on every reply.
+	Classifier *ExternalClassifier
+	// Rules from -patterns-file are tried before the built-in patterns.
+	Rules []patternRule
+	// Details follows the referral of thin registries to the registrar's
+	// whois server for the fields the registry does not have.
+	Details bool
+	// SaveDir, when set, receives every reply as DOMAIN.txt in UTF-8, and
+	// with SaveRaw also the bytes as received as DOMAIN.raw.
+	SaveDir string
+	SaveRaw bool
+	// Quota, when set, counts queries and stops them once it is spent.
+	Quota *whoisQuota
+	// Maintenance holds the maintenance windows of servers, keyed by
+	// host; queries falling into one are deferred without being sent.
+	Maintenance map[string][]maintenanceWindow
+	// Syntax holds the names each registry accepts, keyed by TLD; names
+	// breaking them are not checked.
+	Syntax map[string]tldSyntax
+	// Cooldowns holds the servers that banned queries for the day, which
+	// get none until the ban is over unless IgnoreCooldowns is set.
+	Cooldowns       *serverCooldowns
+	IgnoreCooldowns bool
+
+	mu         sync.Mutex
+	discovered map[string]string
+	nextSlot   map[string]time.Time
+}
+
+func (c *WhoisChecker) Check(domain string) DomainResult {
+	checked := DomainResult{Domain: domain, Method: "whois", CheckedAt: time.Now()}
+
+	result, server, attempts, err := c.lookup(domain)
+	checked.Duration = time.Since(checked.CheckedAt)
+	checked.Server = server
+	checked.Attempts = attempts
+	if errors.Is(err, errOutOfQuota) {
+		checked.Status = StatusUnchecked
+		checked.Error = err
+		return checked
+	}
+	if errors.Is(err, errDeferred) {
+		checked.Status = StatusDeferred
+		checked.Error = err
+		return checked
+	}
+	if err != nil {
+		checked.Status = StatusError
+		checked.Error = err
+		checked.RawExcerpt = rawExcerpt(decodeWhois(domainTLD(domain), result))
+		return checked
+	}
+
+	raw := result
+	result = decodeWhois(domainTLD(domain), raw)
+	if c.SaveDir != "" {
+		if err := c.save(domain, result, raw); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+	var rule string
+	checked.Status, rule = c.classify(domain, result)
+	// The last attempt is the one that got the reply.
+	last := &checked.Attempts[len(checked.Attempts)-1]
+	last.Outcome, last.Rule = string(checked.Status), rule
+	if checked.Status == StatusDeferred {
+		checked.Error = errDeferred
+		if indicator := deferredIndicator(domainTLD(domain), strings.ToLower(result)); indicator != "" {
+			checked.Error = fmt.Errorf("%w: %q", errDeferred, indicator)
+		}
+		return checked
+	}
+	if checked.Status == StatusUnknown {
+		checked.RawExcerpt = rawExcerpt(result)
+	}
+	if checked.Status == StatusTaken {
+		fields := parseWhoisFields(result)
+		// Some servers echo the query with a "Domain Name:" line even for
+		// names nobody registered.
+		if len(result) < echoReplyMaxLength && !fields.substantive() && redactionMarker(domainTLD(domain), strings.ToLower(result)) == "" {
+			checked.Status = StatusUnknown
+			checked.Error = errEchoReply
+			checked.RawExcerpt = rawExcerpt(result)
+			last.Outcome = attemptOutcome(checked.Status, checked.Error)
+			return checked
+		}
+		checked.EPPStatus = fields.EPPStatus
+		checked.Registrar = fields.Registrar
+		checked.CreatedAt = fields.CreatedAt
+		checked.ExpiresAt = fields.ExpiresAt
+		if c.Details && thinRegistries[domainTLD(domain)] {
+			c.addReferralDetails(&checked, result)
+		}
+	}
+	return checked
+}
+
+// addReferralDetails asks the registrar a thin registry refers to and fills
+// in the fields the registry left out. The verdict stays the registry's;
+// a failed referral only costs the details.
+func (c *WhoisChecker) addReferralDetails(checked *DomainResult, reply string) {
+	host := whoisValue(reply, "registrar whois server")
+	host = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://"), "/")
+	if host == "" || !c.Quota.take() {
+		return
+	}
+	c.wait(host)
+	checked.Referral = host
+	start := time.Now()
+	thick, err := queryWhois(host, checked.Domain, c.timeout())
+	if err != nil {
+		debugLog.Printf("%s: referral to %s: %v", checked.Domain, host, err)
+		checked.Attempts = append(checked.Attempts, newAttempt("whois-referral", host, start, StatusError, err))
+		return
+	}
+	attempt := newAttempt("whois-referral", host, start, checked.Status, nil)
+	attempt.Rule = "details only, the registry's verdict stands"
+	checked.Attempts = append(checked.Attempts, attempt)
+	fields := parseWhoisFields(decodeWhois(domainTLD(checked.Domain), thick))
+	if checked.Registrar == "" {
+		checked.Registrar = fields.Registrar
+	}
+	if checked.CreatedAt.IsZero() {
+		checked.CreatedAt = fields.CreatedAt
+	}
+	if checked.ExpiresAt.IsZero() {
+		checked.ExpiresAt = fields.ExpiresAt
+	}
+	if len(checked.EPPStatus) == 0 {
+		checked.EPPStatus = fields.EPPStatus
+	}
+}
+
+// save writes the decoded reply, and the raw one when it differs and
+// SaveRaw is set.
+func (c *WhoisChecker) save(domain, decoded, raw string) error {
+	path := filepath.Join(c.SaveDir, domain+".txt")
+	if err := os.WriteFile(path, []byte(decoded), 0o644); err != nil {
+		return fmt.Errorf("saving whois reply: %w", err)
+	}
+	if c.SaveRaw && raw != decoded {
+		if err := os.WriteFile(filepath.Join(c.SaveDir, domain+".raw"), []byte(raw), 0o644); err != nil {
+			return fmt.Errorf("saving whois reply: %w", err)
+		}
+	}
+	return nil
+}
+
+// classify asks the external classifier first, then the user rules, and
+// falls back to the built-in patterns. The rule says which of them decided.
+func (c *WhoisChecker) classify(domain, reply string) (Status, string) {
+	if c.Classifier != nil {
+		status, err := c.Classifier.Classify(domain, reply)
+		if err == nil {
+			return status, "-classifier"
+		}
+		debugLog.Printf("%s: %v; using built-in patterns", domain, err)
+	}
+	tld := domainTLD(domain)
+	if rule, re := matchPatternRules(c.Rules, tld, reply); rule != nil {
+		return rule.Status, fmt.Sprintf("-patterns-file rule at line %d, pattern %s", rule.Line, re)
+	}
+	lower := strings.ToLower(reply)
+	if thinRegistries[tld] {
+		if status, ok := classifyThin(domain, lower); ok {
+			return status, "thin registry reply"
+		}
+	}
+	status := classifyWhois(tld, lower)
+	if marker := redactionMarker(tld, lower); status == StatusTaken && marker != "" {
+		return status, fmt.Sprintf("built-in patterns, contacts redacted (%q)", marker)
+	}
+	return status, "built-in patterns"
+}
+
+// lookup returns the reply and the server that gave it, or the last server
+// tried when every one failed, with an attempt for every server asked. The
+// attempt that got the reply comes last, for Check to fill in the verdict.
+// A failed lookup still returns the last reply it refused, such as a rate
+// limit notice, for the excerpt.
+func (c *WhoisChecker) lookup(domain string) (string, string, []Attempt, error) {
+	tld := domainTLD(domain)
+	candidates := c.Servers[tld]
+
+	tried := map[string]bool{}
+	discovered := false
+	var attempts []Attempt
+	var lastErr error
+	var lastHost, lastReply string
+	for i := 0; ; i++ {
+		if i == len(candidates) {
+			if discovered {
+				break
+			}
+			// The mapping is exhausted; fall back to the server IANA names.
+			discovered = true
+			start := time.Now()
+			host, err := c.discover(tld)
+			if err != nil {
+				attempts = append(attempts, newAttempt("iana", ianaWhoisServer, start, StatusError, err))
+				if lastErr != nil {
+					return lastReply, lastHost, attempts, lastErr
+				}
+				return "", "", attempts, err
+			}
+			attempt := newAttempt("iana", ianaWhoisServer, start, "", nil)
+			attempt.Outcome = "server " + host
+			attempts = append(attempts, attempt)
+			candidates = append(candidates, whoisServer{Host: host})
+		}
+
+		server := candidates[i]
+		if tried[server.Host] {
+			continue
+		}
+		tried[server.Host] = true
+		if err := c.maintenance(server.Host, time.Now()); err != nil {
+			attempts = append(attempts, newAttempt("whois", server.Host, time.Now(), StatusDeferred, err))
+			lastErr, lastHost = err, server.Host
+			continue
+		}
+		if err := c.cooldown(server.Host, time.Now()); err != nil {
+			attempts = append(attempts, newAttempt("whois", server.Host, time.Now(), StatusDeferred, err))
+			lastErr, lastHost = err, server.Host
+			continue
+		}
+
+		query := server.query(domain)
+		if suffix := c.Suffixes[server.Host]; suffix != "" {
+			query += " " + suffix
+		}
+		if !c.Quota.take() {
+			return lastReply, lastHost, attempts, errOutOfQuota
+		}
+		c.wait(server.Host)
+		start := time.Now()
+		reply, err := queryWhois(server.Host, query, c.timeout())
+		if err == nil && isRateLimitResponse(strings.ToLower(reply)) {
+			err = fmt.Errorf("whois: %s: %w", server.Host, errRateLimited)
+		}
+		if cooldown, started, saveErr := c.Cooldowns.observe(server.Host, err); started {
+			fmt.Fprintf(os.Stderr, "Warning: %s rate limited %d queries in a row; no queries to it until %s\n",
+				server.Host, cooldownStrikes, displayTime(cooldown.Until, "2006-01-02 15:04"))
+			if saveErr != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", saveErr)
+			}
+		}
+		if err == nil {
+			return reply, server.Host, append(attempts, newAttempt("whois", server.Host, start, "", nil)), nil
+		}
+		attempts = append(attempts, newAttempt("whois", server.Host, start, StatusError, err))
+		lastErr, lastHost, lastReply = err, server.Host, reply
+		if !categorizeError(err).Retriable() {
+			break
+		}
+	}
+	return lastReply, lastHost, attempts, lastErr
+}
+
+// discover asks IANA once per TLD which server is authoritative for it.
+func (c *WhoisChecker) discover(tld string) (string, error) {
+	c.mu.Lock()
+	host, ok := c.discovered[tld]
+	c.mu.Unlock()
+	if ok {
+		return host, nil
+	}
+
+	c.wait(ianaWhoisServer)
+	host, err := whoisHost(tld, c.timeout())
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	if c.discovered == nil {
+		c.discovered = map[string]string{}
+	}
+	c.discovered[tld] = host
+	c.mu.Unlock()
+	return host, nil
+}
+
+// wait blocks until host may be queried again under ServerRate.
+func (c *WhoisChecker) wait(host string) {
+	if c.ServerRate <= 0 {
+		return
+	}
+	interval := time.Duration(float64(time.Second) / c.ServerRate)
+
+	c.mu.Lock()
+	if c.nextSlot == nil {
+		c.nextSlot = map[string]time.Time{}
+	}
+	now := time.Now()
+	slot := c.nextSlot[host]
+	if slot.Before(now) {
+		slot = now
+	}
+	c.nextSlot[host] = slot.Add(interval)
+	c.mu.Unlock()
+
+	time.Sleep(time.Until(slot))
+}
+
+func (c *WhoisChecker) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return 30 * time.Second
+}
+
+const ianaWhoisServer = "whois.iana.org"
+
+// whoisHost asks IANA which server is authoritative for a TLD.
+func whoisHost(tld string, timeout time.Duration) (string, error) {
+	response, err := queryWhois(ianaWhoisServer, tld, timeout)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(response, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(key), "whois") {
+			if host := strings.TrimSpace(value); host != "" {
+				return strings.ToLower(host), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("whois: .%s: %w", tld, errNoServer)
+}
+
+// whoisTLS verifies the certificates of tls:// and https:// servers; -insecure
+// and -ca-file change it.
+var whoisTLS = &tls.Config{}
+
+// queryWhois sends a single query and returns the reply. host is a port 43
+// server, or a tls:// address or http(s):// URL of a gateway in front of one.
+func queryWhois(host, query string, timeout time.Duration) (string, error) {
+	release, err := connections.acquire(context.Background(), connWhois)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	if strings.HasPrefix(host, "http://") || strings.HasPrefix(host, "https://") {
+		return queryWhoisHTTP(host, query, timeout)
+	}
+
+	addr, useTLS := strings.CutPrefix(host, "tls://")
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		port := "43"
+		if useTLS {
+			port = "443"
+		}
+		addr = net.JoinHostPort(addr, port)
+	}
+
+	var conn net.Conn
+	if useTLS {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", addr, whoisTLS)
+	} else {
+		conn, err = net.DialTimeout("tcp", addr, timeout)
+	}
+	if err != nil {
+		return "", fmt.Errorf("whois: connect to %s failed: %w", host, err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if _, err := io.WriteString(conn, query+"\r\n"); err != nil {
+		return "", fmt.Errorf("whois: send to %s failed: %w", host, err)
+	}
+	data, err := io.ReadAll(conn)
+	if err != nil {
+		return "", fmt.Errorf("whois: read from %s failed: %w", host, err)
+	}
+	return string(data), nil
+}
+
+// queryWhoisHTTP asks an HTTP gateway, with the query in place of {domain}
+// in the URL. The body of the response is the reply.
+func queryWhoisHTTP(endpoint, query string, timeout time.Duration) (string, error) {
+	client := &http.Client{Timeout: timeout, Transport: &http.Transport{TLSClientConfig: whoisTLS}}
+	req, err := http.NewRequest(http.MethodGet, strings.ReplaceAll(endpoint, "{domain}", url.QueryEscape(query)), nil)
+	if err != nil {
+		return "", fmt.Errorf("whois: %s: %w", endpoint, err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("whois: connect to %s failed: %w", endpoint, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("whois: read from %s failed: %w", endpoint, err)
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return "", fmt.Errorf("whois: %s: %w", endpoint, errRateLimited)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("whois: %s: %s", endpoint, resp.Status)
+	}
+	return string(data), nil
+}
+
+// parseWhoisHost checks a server of the mapping file: a host[:port], a
+// tls://host[:port] or an http(s):// URL containing {domain}.
+func parseWhoisHost(host string) (string, error) {
+	scheme, rest, ok := strings.Cut(host, "://")
+	if !ok {
+		return strings.ToLower(host), nil
+	}
+	switch strings.ToLower(scheme) {
+	case "tls":
+		return "tls://" + strings.ToLower(rest), nil
+	case "http", "https":
+		if !strings.Contains(rest, "{domain}") {
+			return "", fmt.Errorf("gateway URL %s must contain {domain}", host)
+		}
+		return strings.ToLower(scheme) + "://" + rest, nil
+	}
+	return "", fmt.Errorf("unsupported scheme in %s (use tls://, https:// or http://)", host)
+}
+
+type WhoisFlags struct {
+	ServersFile *string
+	QuerySuffix *string
+	Contact     *string
+	ServerRate  *float64
+
+	Classifier            *string
+	ClassifierTimeout     *time.Duration
+	ClassifierConcurrency *int
+
+	SaveWhois    *string
+	SaveWhoisRaw *bool
+
+	PatternsFile *string
+	Details      *bool
+
+	Insecure *bool
+	CAFile   *string
+
+	Backend     *string
+	FakeFixture *string
+	FakeLatency *time.Duration
+
+	IgnoreCooldowns *bool
+}
+
+func addWhoisFlags(fs *flag.FlagSet) *WhoisFlags {
+	return &WhoisFlags{
+		ServersFile: fs.String("whois-servers", "", "File mapping TLDs to whois servers and query templates ('tld server [query with {domain}]' per line)"),
+		QuerySuffix: fs.String("query-suffix", "", "Comma-separated server=suffix pairs appended to whois queries (e.g. 'whois.example.net=/contact ops@example.com')"),
+		Contact:     fs.String("contact", "", "Contact email included in the User-Agent of HTTP requests"),
+		ServerRate:  fs.Float64("server-rate", 0, "Maximum queries per second sent to each whois server, fallbacks included (0 for no limit)"),
+
+		Classifier: fs.String("classifier", "", "Command that classifies whois replies: it is run with the domain as argument and the reply on stdin, "+
+			"and prints available, taken, reserved or unknown; a non-zero exit, a timeout or other output falls back to the built-in patterns"),
+		ClassifierTimeout:     fs.Duration("classifier-timeout", 5*time.Second, "Maximum run time of one -classifier call"),
+		ClassifierConcurrency: fs.Int("classifier-concurrency", 4, "Maximum -classifier processes running at once"),
+
+		Insecure:     fs.Bool("insecure", false, "Do not verify the certificates of tls:// and https:// whois servers"),
+		CAFile:       fs.String("ca-file", "", "PEM file of CA certificates trusted for tls:// and https:// whois servers, e.g. an internal CA"),
+		Details:      fs.Bool("details", false, "For taken .com and .net domains, also ask the registrar's whois server the registry refers to, for the details the registry lacks"),
+		PatternsFile: fs.String("patterns-file", "", "YAML file of rules (tld, patterns, status) tried on whois replies before the built-in patterns"),
+
+		SaveWhois:    fs.String("save-whois", "", "Directory to save every whois reply to as DOMAIN.txt, converted to UTF-8"),
+		SaveWhoisRaw: fs.Bool("save-whois-raw", false, "With -save-whois, also keep replies that were not UTF-8 as received, as DOMAIN.raw"),
+
+		Backend:     fs.String("backend", "whois", "Backend that checks domains: whois, or fake for made-up deterministic verdicts without any network traffic, for demos and tests"),
+		FakeFixture: fs.String("fake-fixture", "", "File of 'domain status' lines giving -backend=fake the status of those domains"),
+		FakeLatency: fs.Duration("fake-latency", 0, "Average time one -backend=fake check takes"),
+
+		IgnoreCooldowns: fs.Bool("ignore-cooldowns", false, fmt.Sprintf("Query servers in cooldown anyway: a server that rate limits %d queries in a row otherwise gets none for %s, in this run and the next ones", cooldownStrikes, cooldownPeriod)),
+	}
+}
+
+// Fake returns the checker of -backend=fake, or nil for the whois backend.
+func (f *WhoisFlags) Fake() (*fakeChecker, error) {
+	switch *f.Backend {
+	case "whois":
+		if *f.FakeFixture != "" || *f.FakeLatency != 0 {
+			return nil, fmt.Errorf("-fake-fixture and -fake-latency require -backend=fake")
+		}
+		return nil, nil
+	case "fake":
+	default:
+		return nil, fmt.Errorf("unknown -backend %q (use whois or fake)", *f.Backend)
+	}
+	if *f.FakeLatency < 0 {
+		return nil, fmt.Errorf("-fake-latency cannot be negative")
+	}
+	fake := &fakeChecker{Latency: *f.FakeLatency}
+	if *f.FakeFixture != "" {
+		fixture, err := readFakeFixture(*f.FakeFixture)
+		if err != nil {
+			return nil, err
+		}
+		fake.Fixture = fixture
+	}
+	return fake, nil
+}
+
+// Checker builds the whois checker and applies -contact to the User-Agent.
+func (f *WhoisFlags) Checker() (*WhoisChecker, error) {
+	if *f.Contact != "" {
+		userAgent = buildUserAgent(*f.Contact)
+	}
+
+	if *f.Insecure {
+		whoisTLS.InsecureSkipVerify = true
+	}
+	if *f.CAFile != "" {
+		pem, err := os.ReadFile(*f.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("-ca-file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("-ca-file: no certificates found in %s", *f.CAFile)
+		}
+		whoisTLS.RootCAs = pool
+	}
+
+	checker := &WhoisChecker{Servers: defaultWhoisServers(), ServerRate: *f.ServerRate, Details: *f.Details, Maintenance: defaultMaintenance(), Syntax: defaultTLDSyntax()}
+	cooldowns, err := loadCooldowns(cooldownsPath())
+	if err != nil {
+		return nil, err
+	}
+	checker.Cooldowns, checker.IgnoreCooldowns = cooldowns, *f.IgnoreCooldowns
+	if *f.Classifier != "" {
+		if _, err := exec.LookPath(*f.Classifier); err != nil {
+			return nil, fmt.Errorf("-classifier: %w", err)
+		}
+		checker.Classifier = newExternalClassifier(*f.Classifier, *f.ClassifierTimeout, *f.ClassifierConcurrency)
+	}
+	if *f.PatternsFile != "" {
+		rules, err := readPatternRules(*f.PatternsFile)
+		if err != nil {
+			return nil, err
+		}
+		checker.Rules = rules
+	}
+	if *f.SaveWhois != "" {
+		if err := os.MkdirAll(*f.SaveWhois, 0o755); err != nil {
+			return nil, fmt.Errorf("-save-whois: %w", err)
+		}
+		checker.SaveDir = *f.SaveWhois
+		checker.SaveRaw = *f.SaveWhoisRaw
+	}
+	if *f.ServersFile != "" {
+		servers, windows, syntax, err := readWhoisServers(*f.ServersFile)
+		if err != nil {
+			return nil, err
+		}
+		for tld, rules := range syntax {
+			checker.Syntax[tld] = rules
+		}
+		for tld, list := range servers {
+			checker.Servers[tld] = list
+		}
+		for host, list := range windows {
+			checker.Maintenance[host] = list
+		}
+	}
+
+	for _, pair := range parseKeywords(*f.QuerySuffix) {
+		host, suffix, ok := strings.Cut(pair, "=")
+		if !ok || host == "" || suffix == "" {
+			return nil, fmt.Errorf("invalid -query-suffix %q (want server=suffix)", pair)
+		}
+		if checker.Suffixes == nil {
+			checker.Suffixes = map[string]string{}
+		}
+		checker.Suffixes[strings.ToLower(strings.TrimSpace(host))] = strings.TrimSpace(suffix)
+	}
+	return checker, nil
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// whoisStub serves whois on a local port, answering each query with the
+// reply reply returns for it. Queries are answered one at a time.
+func whoisStub(t *testing.T, reply func(query string) string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	var mu sync.Mutex
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				query, _ := bufio.NewReader(conn).ReadString('\n')
+				mu.Lock()
+				answer := reply(strings.TrimSpace(query))
+				mu.Unlock()
+				conn.Write([]byte(answer))
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// TestCheckFollowsThinReferral checks a .com domain against a stub
+// registry whose thin reply refers to a stub registrar: the verdict is the
+// registry's, the details the registrar's, and both servers are recorded.
+func TestCheckFollowsThinReferral(t *testing.T) {
+	var registrarQueries []string
+	registrar := whoisStub(t, func(query string) string {
+		registrarQueries = append(registrarQueries, query)
+		return "Domain Name: EXAMPLE.COM\nRegistrar: Thick Registrar, Inc.\nRegistrar Registration Expiration Date: 2031-05-06T00:00:00Z\n"
+	})
+	var registryQueries []string
+	registry := whoisStub(t, func(query string) string {
+		registryQueries = append(registryQueries, query)
+		return strings.Join([]string{
+			"   Domain Name: EXAMPLE.COM",
+			"   Registrar WHOIS Server: " + registrar,
+			"   Creation Date: 1995-08-14T04:00:00Z",
+			"   Domain Status: clientTransferProhibited https://icann.org/epp#clientTransferProhibited",
+			">>> Last update of whois database: 2026-10-16T09:14:21Z <<<",
+			"",
+		}, "\n")
+	})
+
+	for _, details := range []bool{false, true} {
+		registryQueries, registrarQueries = nil, nil
+		checker := &WhoisChecker{
+			Servers: map[string][]whoisServer{"com": {{Host: registry, Query: "domain {domain}"}}},
+			Details: details,
+			Timeout: 5 * time.Second,
+		}
+		result := checker.Check("example.com")
+		if result.Status != StatusTaken {
+			t.Fatalf("details=%v: status = %s (%v)", details, result.Status, result.Error)
+		}
+		if len(registryQueries) != 1 || registryQueries[0] != "domain example.com" {
+			t.Errorf("details=%v: registry queries = %q", details, registryQueries)
+		}
+		if result.Server != registry {
+			t.Errorf("details=%v: server = %q, want %q", details, result.Server, registry)
+		}
+		if !details {
+			if len(registrarQueries) != 0 || result.Referral != "" {
+				t.Errorf("referral followed without -details: %q", registrarQueries)
+			}
+			continue
+		}
+		if len(registrarQueries) != 1 || result.Referral != registrar {
+			t.Errorf("referral = %q, queries %q", result.Referral, registrarQueries)
+		}
+		if result.Registrar != "Thick Registrar, Inc." || result.ExpiresAt.Year() != 2031 || result.CreatedAt.Year() != 1995 {
+			t.Errorf("details = registrar %q, created %v, expires %v", result.Registrar, result.CreatedAt, result.ExpiresAt)
+		}
+	}
+}
+
+// TestCheckRecordsServer checks that the server whose reply was classified
+// is recorded, whether it comes from the mapping, a fallback, or IANA.
+func TestCheckRecordsServer(t *testing.T) {
+	taken := whoisStub(t, func(string) string {
+		return "Domain Name: EXAMPLE.ORG\nRegistrar: Example Registrar\nCreation Date: 2001-02-03T00:00:00Z\n"
+	})
+	// A port nothing listens on refuses connections at once.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dead := ln.Addr().String()
+	ln.Close()
+
+	for _, tt := range []struct {
+		name    string
+		servers map[string][]whoisServer
+		// discovered seeds the server IANA named for the TLD.
+		discovered map[string]string
+		want       string
+	}{
+		{"mapped", map[string][]whoisServer{"org": {{Host: taken}}}, nil, taken},
+		{"fallback", map[string][]whoisServer{"org": {{Host: dead}, {Host: taken}}}, nil, taken},
+		{"iana", map[string][]whoisServer{}, map[string]string{"org": taken}, taken},
+		{"iana after the mapping failed", map[string][]whoisServer{"org": {{Host: dead}}}, map[string]string{"org": taken}, taken},
+		{"failed", map[string][]whoisServer{"org": {{Host: dead}}}, map[string]string{"org": dead}, dead},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := &WhoisChecker{Servers: tt.servers, discovered: tt.discovered, Timeout: 5 * time.Second}
+			result := checker.Check("example.org")
+			if result.Server != tt.want {
+				t.Errorf("server = %q, want %q (status %s, %v)", result.Server, tt.want, result.Status, result.Error)
+			}
+			if tt.want == taken && result.Status != StatusTaken {
+				t.Errorf("status = %s (%v)", result.Status, result.Error)
+			}
+		})
+	}
+}
+
+// TestWhoisServersOverride checks that a -whois-servers line replaces the
+// built-in server of its TLD and leaves the others in place.
+func TestWhoisServersOverride(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "servers.txt")
+	if err := os.WriteFile(path, []byte("com  whois.example.net,whois2.example.net  ={domain}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	whoisFlags := addWhoisFlags(fs)
+	if err := fs.Parse([]string{"-whois-servers=" + path}); err != nil {
+		t.Fatal(err)
+	}
+	checker, err := whoisFlags.Checker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []whoisServer{{Host: "whois.example.net", Query: "={domain}"}, {Host: "whois2.example.net", Query: "={domain}"}}
+	if got := checker.Servers["com"]; !reflect.DeepEqual(got, want) {
+		t.Errorf(".com servers = %+v, want %+v", got, want)
+	}
+	if got := checker.Servers["net"]; len(got) == 0 || got[0].Host != "whois.verisign-grs.com" {
+		t.Errorf(".net servers = %+v, want the built-in default", got)
+	}
+}
+
+// TestServerNote checks how -verbose names the servers that answered.
+func TestServerNote(t *testing.T) {
+	results := []DomainResult{
+		{Domain: "example.com", Status: StatusTaken, Server: "whois.verisign-grs.com", Referral: "whois.example-registrar.com"},
+		{Domain: "example.org", Status: StatusTaken, Server: "whois.publicinterestregistry.org"},
+		{Domain: "free.org", Status: StatusAvailable, Server: "whois.publicinterestregistry.org"},
+	}
+	var b strings.Builder
+	printResults(&b, results, RenderOptions{Servers: true})
+	for _, want := range []string{
+		"example.com [via whois.verisign-grs.com " + sym.Arrow + " whois.example-registrar.com]",
+		"example.org [via whois.publicinterestregistry.org]",
+		"free.org [via whois.publicinterestregistry.org]",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, b.String())
+		}
+	}
+
+	b.Reset()
+	printResults(&b, results, RenderOptions{})
+	if strings.Contains(b.String(), "[via") {
+		t.Errorf("servers shown without -verbose:\n%s", b.String())
+	}
+}
+
+// TestCheckEchoReplies checks that short "Domain Name:" replies without
+// registrar, dates or name servers, as some ccTLD servers send back for any
+// query, are reported as unknown rather than taken.
+func TestCheckEchoReplies(t *testing.T) {
+	for _, tt := range []struct {
+		fixture, domain string
+		want            Status
+		err             error
+	}{
+		{"ws-echo.txt", "example-free.ws", StatusUnknown, errEchoReply},
+		{"ws-echo-nameservers.txt", "example.ws", StatusTaken, nil},
+		{"ws-taken.txt", "example.ws", StatusTaken, nil},
+	} {
+		t.Run(tt.fixture, func(t *testing.T) {
+			reply := readWhoisFixture(t, tt.fixture)
+			server := whoisStub(t, func(string) string { return reply })
+			checker := &WhoisChecker{Servers: map[string][]whoisServer{"ws": {{Host: server}}}, Timeout: 5 * time.Second}
+			result := checker.Check(tt.domain)
+			if result.Status != tt.want || !errors.Is(result.Error, tt.err) {
+				t.Errorf("Check = %s (%v), want %s (%v)", result.Status, result.Error, tt.want, tt.err)
+			}
+		})
+	}
+
+	// The reason is shown next to the domain.
+	var b strings.Builder
+	printResults(&b, []DomainResult{{Domain: "example-free.ws", Status: StatusUnknown, Error: errEchoReply}}, RenderOptions{})
+	if !strings.Contains(b.String(), "example-free.ws ("+errEchoReply.Error()+")") {
+		t.Errorf("output lacks the reason:\n%s", b.String())
+	}
+}
+
+// TestCheckQuotaExceeded checks that a reply saying the daily quota is spent
+// defers the domain rather than failing it as rate limited.
+func TestCheckQuotaExceeded(t *testing.T) {
+	reply := readWhoisFixture(t, "quota-exceeded.txt")
+	server := whoisStub(t, func(string) string { return reply })
+	checker := &WhoisChecker{Servers: map[string][]whoisServer{"xyz": {{Host: server}}}, Timeout: 5 * time.Second}
+	result := checker.Check("example.xyz")
+	if result.Status != StatusDeferred || errors.Is(result.Error, errRateLimited) {
+		t.Errorf("Check = %s (%v), want deferred", result.Status, result.Error)
+	}
+}
+
+// TestCheckRedactedReplies checks replies whose contacts were withheld for
+// privacy: they are taken with high confidence, the fields still given are
+// read, and the redacted ones are left empty rather than failing the check.
+func TestCheckRedactedReplies(t *testing.T) {
+	for _, tt := range []struct {
+		fixture, domain string
+		registrar       string
+		rule            string
+	}{
+		{"com-redacted.txt", "example-redacted.com", "Example Registrar, LLC", "thin registry reply"},
+		{"com-redacted-sparse.txt", "example-redacted.com", "", "thin registry reply"},
+		{"eu-redacted.txt", "example-redacted.eu", "", `contacts redacted ("not disclosed!")`},
+		{"eu-redacted-sparse.txt", "example-redacted.eu", "", `contacts redacted ("not disclosed!")`},
+		{"fr-redacted.txt", "example-redacted.fr", "EXAMPLE REGISTRAR", `contacts redacted ("ano nymous")`},
+		{"fr-redacted-sparse.txt", "example-redacted.fr", "", `contacts redacted ("ano nymous")`},
+	} {
+		t.Run(tt.fixture, func(t *testing.T) {
+			reply := readWhoisFixture(t, tt.fixture)
+			server := whoisStub(t, func(string) string { return reply })
+			tld := domainTLD(tt.domain)
+			checker := &WhoisChecker{Servers: map[string][]whoisServer{tld: {{Host: server}}}, Timeout: 5 * time.Second}
+			result := checker.Check(tt.domain)
+			if result.Status != StatusTaken || result.Error != nil || result.Confidence == ConfidenceLow {
+				t.Fatalf("Check = %s (%v), confidence %q; want taken with high confidence", result.Status, result.Error, result.Confidence)
+			}
+			if result.Registrar != tt.registrar {
+				t.Errorf("registrar = %q, want %q", result.Registrar, tt.registrar)
+			}
+			if rule := result.Attempts[len(result.Attempts)-1].Rule; !strings.Contains(rule, tt.rule) {
+				t.Errorf("rule = %q, want it to mention %q", rule, tt.rule)
+			}
+			if line := checkLine(result); !strings.Contains(line, "taken (whois, high confidence)") {
+				t.Errorf("check line = %q", line)
+			}
+		})
+	}
+}
+
+func TestParseRedactedFields(t *testing.T) {
+	fields := parseWhoisFields(readWhoisFixture(t, "com-redacted.txt"))
+	if !fields.Redacted || fields.Registrar != "Example Registrar, LLC" || fields.CreatedAt.Year() != 2015 || fields.ExpiresAt.Year() != 2026 || len(fields.NameServers) != 2 {
+		t.Errorf("fields = %+v", fields)
+	}
+	fields = parseWhoisFields(readWhoisFixture(t, "com-redacted-sparse.txt"))
+	if !fields.Redacted || fields.Registrar != "" || !fields.substantive() {
+		t.Errorf("fields of the sparse reply = %+v", fields)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// WhoisFields holds the structured data extracted from a whois response.
+type WhoisFields struct {
+	Registrar string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	EPPStatus []string
+	// NameServers is only used to tell replies with substance from bare
+	// echoes of the query.
+	NameServers []string
+	// Redacted is set when some values were withheld for privacy, which
+	// only the data of a registration is.
+	Redacted bool
+}
+
+// substantive reports whether the reply carried any registration data.
+func (f WhoisFields) substantive() bool {
+	return f.Registrar != "" || !f.CreatedAt.IsZero() || !f.ExpiresAt.IsZero() || len(f.NameServers) > 0 || f.Redacted
+}
+
+var (
+	registrarKeys = []string{"registrar", "registrar name", "sponsoring registrar"}
+	createdKeys   = []string{"creation date", "created", "created on", "registered on", "registration time", "domain registration date", "registered"}
+	expiresKeys   = []string{"registry expiry date", "registrar registration expiration date", "expiration date", "expiry date", "expires", "expires on", "expire date", "paid-till", "renewal date"}
+	statusKeys    = []string{"domain status", "status"}
+	nsKeys        = []string{"name server", "nameserver", "nameservers", "nserver", "name servers"}
+)
+
+var whoisDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"2006.01.02",
+	"2006/01/02",
+	"02-Jan-2006",
+	"02.01.2006",
+	"02/01/2006",
+	"January 2 2006",
+	"Mon Jan 2 15:04:05 MST 2006",
+}
+
+// parseWhoisFields extracts registrar, dates and EPP status codes from the
+// "key: value" lines of a raw whois response. Keys are matched
+// case-insensitively; the first occurrence of each field wins. Redacted
+// values are skipped, so a registrar withheld for privacy stays empty
+// rather than reading "REDACTED FOR PRIVACY".
+func parseWhoisFields(raw string) WhoisFields {
+	var fields WhoisFields
+	seenStatus := map[string]bool{}
+
+	for _, line := range strings.Split(raw, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if isRedacted(value) {
+			fields.Redacted = true
+			continue
+		}
+
+		switch {
+		case fields.Registrar == "" && containsKey(registrarKeys, key):
+			fields.Registrar = value
+		case fields.CreatedAt.IsZero() && containsKey(createdKeys, key):
+			fields.CreatedAt = parseWhoisDate(value)
+		case fields.ExpiresAt.IsZero() && containsKey(expiresKeys, key):
+			fields.ExpiresAt = parseWhoisDate(value)
+		case containsKey(nsKeys, key):
+			fields.NameServers = append(fields.NameServers, strings.ToLower(value))
+		case containsKey(statusKeys, key):
+			code := strings.ToLower(strings.Fields(value)[0])
+			if !seenStatus[code] {
+				seenStatus[code] = true
+				fields.EPPStatus = append(fields.EPPStatus, code)
+			}
+		}
+	}
+	return fields
+}
+
+// isRedacted reports whether a whois value was withheld for privacy rather
+// than given.
+func isRedacted(value string) bool {
+	lower := strings.ToLower(value)
+	return strings.HasPrefix(lower, "redacted") || strings.Contains(lower, "not disclosed") ||
+		containsAny(lower, genericPatterns.Redacted)
+}
+
+func containsKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+func parseWhoisDate(value string) time.Time {
+	// Drop trailing annotations such as "(YYYY-MM-DD)" or timezone names.
+	value = strings.TrimSpace(strings.SplitN(value, " (", 2)[0])
+	for _, layout := range whoisDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	if len(value) >= 10 {
+		if t, err := time.Parse("2006-01-02", value[:10]); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}